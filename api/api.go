@@ -0,0 +1,576 @@
+// Package api holds the JSON types of the /v1 routes. They are shared by the server
+// and the client package, so both sides agree on one contract.
+//
+// Missing values are null, dates are ISO 8601 (YYYY-MM-DD).
+package api
+
+// // // // // // //
+// responses      //
+// // // // // // //
+
+type Course struct {
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+}
+
+type Stats struct {
+	TotalCourses int64 `json:"totalCourses"`
+	TotalReviews int64 `json:"totalReviews"`
+}
+
+type CourseActivity struct {
+	CourseNumber string  `json:"courseNumber"`
+	CourseName   string  `json:"courseName"`
+	LatestDate   *string `json:"latestDate"`
+}
+
+type CourseReviewCount struct {
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+	ReviewCount  int64  `json:"reviewCount"`
+}
+
+type Review struct {
+	Review   string  `json:"review"`
+	Semester *string `json:"semester"`
+	// shown below the review, "[redacted by moderator]" after a moderator changed it
+	Annotation *string `json:"annotation"`
+}
+
+type Rating struct {
+	Recommended  *int32   `json:"recommended"`
+	Engaging     *int32   `json:"engaging"`
+	Difficulty   *int32   `json:"difficulty"`
+	Effort       *int32   `json:"effort"`
+	Resources    *int32   `json:"resources"`
+	HoursPerWeek *float64 `json:"hoursPerWeek"`
+}
+
+type RatingAvg struct {
+	Recommended *float64 `json:"recommended"`
+	Engaging    *float64 `json:"engaging"`
+	Difficulty  *float64 `json:"difficulty"`
+	Effort      *float64 `json:"effort"`
+	Resources   *float64 `json:"resources"`
+	// Dimensions holds the averages of every active rating dimension by key, the built-in
+	// ones above included.
+	Dimensions map[string]DimensionValue `json:"dimensions"`
+}
+
+// DimensionValue is the average of one rating dimension, on the scale of that dimension.
+type DimensionValue struct {
+	Average *float64 `json:"average"`
+	Count   int32    `json:"count"`
+}
+
+// RatingCounts holds how many ratings a course has in total and per dimension.
+type RatingCounts struct {
+	Total       int32 `json:"total"`
+	Recommended int32 `json:"recommended"`
+	Engaging    int32 `json:"engaging"`
+	Difficulty  int32 `json:"difficulty"`
+	Effort      int32 `json:"effort"`
+	Resources   int32 `json:"resources"`
+}
+
+type CourseRatingAvg struct {
+	CourseNumber string `json:"courseNumber"`
+	RatingAvg
+	Counts RatingCounts `json:"counts"`
+}
+
+// RatingAvgPage is one page of rating averages, Total counts all courses matching the filter.
+type RatingAvgPage struct {
+	Total int64             `json:"total"`
+	Items []CourseRatingAvg `json:"items"`
+}
+
+// Department groups the teaching units (course number prefixes) of a department.
+type Department struct {
+	Code        string   `json:"code"`
+	Name        *string  `json:"name"`
+	Prefixes    []string `json:"prefixes"`
+	CourseCount int64    `json:"courseCount"`
+}
+
+// DepartmentStats aggregates the ratings and verified reviews of all courses of a department.
+type DepartmentStats struct {
+	Code        string `json:"code"`
+	CourseCount int64  `json:"courseCount"`
+	ReviewCount int64  `json:"reviewCount"`
+	RatingAvg
+	Counts RatingCounts `json:"counts"`
+}
+
+type Lecturer struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// SemesterLecturers lists who taught a course in a semester.
+type SemesterLecturers struct {
+	Semester  string     `json:"semester"`
+	Lecturers []Lecturer `json:"lecturers"`
+}
+
+// SemesterRatingAvg holds the rating averages of the evaluations of one semester, together
+// with the lecturers of that semester if they are known.
+type SemesterRatingAvg struct {
+	Semester  *string    `json:"semester"`
+	Lecturers []Lecturer `json:"lecturers"`
+	RatingAvg
+	Counts RatingCounts `json:"counts"`
+}
+
+type LecturerCourse struct {
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+	Semester     string `json:"semester"`
+}
+
+// LecturerProfile holds the courses of a lecturer and the rating averages of the
+// evaluations from the semesters they taught them.
+type LecturerProfile struct {
+	ID      int32            `json:"id"`
+	Name    string           `json:"name"`
+	Courses []LecturerCourse `json:"courses"`
+	RatingAvg
+	Counts RatingCounts `json:"counts"`
+}
+
+// TrendValue is the average of one rating dimension in a semester. Shift is set if it
+// differs significantly from the previous semester this dimension was rated in.
+type TrendValue struct {
+	Average *float64 `json:"average"`
+	Count   int32    `json:"count"`
+	Shift   bool     `json:"shift"`
+}
+
+// TrendPoint holds the ratings of a course in one semester.
+type TrendPoint struct {
+	Semester  string     `json:"semester"`
+	Lecturers []Lecturer `json:"lecturers"`
+	// LecturerChanged is set if the lecturers differ from the previous semester with known lecturers.
+	LecturerChanged bool       `json:"lecturerChanged"`
+	Count           int32      `json:"count"`
+	Recommended     TrendValue `json:"recommended"`
+	Engaging        TrendValue `json:"engaging"`
+	Difficulty      TrendValue `json:"difficulty"`
+	Effort          TrendValue `json:"effort"`
+	Resources       TrendValue `json:"resources"`
+	// Dimensions holds every active rating dimension by key, the built-in ones above included.
+	Dimensions map[string]TrendValue `json:"dimensions"`
+	// Shift is set if any dimension shifted.
+	Shift bool `json:"shift"`
+}
+
+type ReviewSnippet struct {
+	Review     string  `json:"review"`
+	Semester   *string `json:"semester"`
+	Date       *string `json:"date"`
+	Annotation *string `json:"annotation"`
+}
+
+// CourseComparison holds everything needed to compare a course side by side with others.
+type CourseComparison struct {
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+	// ECTS of the latest offering the VVZ listed credits for.
+	ECTS      *float64 `json:"ects"`
+	Semesters []string `json:"semesters"`
+	RatingAvg
+	Counts RatingCounts `json:"counts"`
+	// Distributions holds per active dimension how many ratings gave each value of its
+	// scale, from the lowest one up.
+	Distributions map[string][]int64  `json:"distributions"`
+	LatestReviews []ReviewSnippet     `json:"latestReviews"`
+	Lecturers     []SemesterLecturers `json:"lecturers"`
+}
+
+// Reasons a course is recommended.
+const (
+	ReasonSimilar = "similar" // students who rated the user's courses alike liked it
+	ReasonPopular = "popular" // popular in the user's departments
+)
+
+// RecommendationSource is the rated course a recommendation is mostly based on.
+type RecommendationSource struct {
+	CourseNumber string  `json:"courseNumber"`
+	CourseName   string  `json:"courseName"`
+	Similarity   float64 `json:"similarity"`
+	YourScore    float64 `json:"yourScore"`
+}
+
+type Recommendation struct {
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+	// Score is the expected "recommended"/"engaging" score from 1 to 5.
+	Score       *float64              `json:"score"`
+	Reason      string                `json:"reason"`
+	Explanation string                `json:"explanation"`
+	BasedOn     *RecommendationSource `json:"basedOn"`
+	Department  *string               `json:"department"`
+}
+
+// SimilarCourse is a course whose reviews and name read alike, Similarity ranges from 0 to 1.
+type SimilarCourse struct {
+	CourseNumber string  `json:"courseNumber"`
+	CourseName   string  `json:"courseName"`
+	Similarity   float64 `json:"similarity"`
+}
+
+// RatingDimension is an aspect courses are rated on. Retired dimensions keep their values
+// but can't be rated anymore.
+type RatingDimension struct {
+	Key      string `json:"key"`
+	LabelDe  string `json:"labelDe"`
+	LabelEn  string `json:"labelEn"`
+	ScaleMin int32  `json:"scaleMin"`
+	ScaleMax int32  `json:"scaleMax"`
+	Active   bool   `json:"active"`
+	Position int32  `json:"position"`
+}
+
+// DimensionAvg is the average of one active rating dimension of a course.
+type DimensionAvg struct {
+	Key      string   `json:"key"`
+	LabelDe  string   `json:"labelDe"`
+	LabelEn  string   `json:"labelEn"`
+	ScaleMin int32    `json:"scaleMin"`
+	ScaleMax int32    `json:"scaleMax"`
+	Average  *float64 `json:"average"`
+	Count    int64    `json:"count"`
+	// Distribution holds how many ratings gave each value from ScaleMin to ScaleMax.
+	Distribution []int64 `json:"distribution"`
+	// Comments are the latest verified comments on this dimension.
+	Comments []DimensionComment `json:"comments"`
+}
+
+type DimensionComment struct {
+	Value    int32   `json:"value"`
+	Comment  string  `json:"comment"`
+	Semester *string `json:"semester"`
+	Date     *string `json:"date"`
+}
+
+// SummaryPhrase is a keyphrase of the reviews of a course. Sentiment is the mean sentiment
+// of the sentences it appears in, from -1 to 1.
+type SummaryPhrase struct {
+	Phrase string `json:"phrase"`
+	// Count is the number of reviews using the phrase.
+	Count     int32   `json:"count"`
+	Sentiment float64 `json:"sentiment"`
+}
+
+type SemesterSentiment struct {
+	Semester    string  `json:"semester"`
+	ReviewCount int32   `json:"reviewCount"`
+	Sentiment   float64 `json:"sentiment"`
+}
+
+// CourseSummary sums up the verified reviews of a course. Reviews are analyzed in German
+// and English shortly after they are verified.
+type CourseSummary struct {
+	CourseNumber    string           `json:"courseNumber"`
+	ReviewCount     int32            `json:"reviewCount"`
+	Sentiment       *float64         `json:"sentiment"`
+	Languages       map[string]int32 `json:"languages"`
+	TopPhrases      []SummaryPhrase  `json:"topPhrases"`
+	PositivePhrases []SummaryPhrase  `json:"positivePhrases"`
+	NegativePhrases []SummaryPhrase  `json:"negativePhrases"`
+	// Trend holds the mean sentiment per semester in chronological order.
+	Trend []SemesterSentiment `json:"trend"`
+}
+
+// CourseWorkload sums up the hours per week students spent on a course. Hours reported with
+// a rating are used first, hours mentioned in the verified review of an evaluation without
+// reported hours fill in.
+type CourseWorkload struct {
+	CourseNumber   string   `json:"courseNumber"`
+	ReportedCount  int32    `json:"reportedCount"`
+	MentionedCount int32    `json:"mentionedCount"`
+	Median         *float64 `json:"median"`
+	Q1             *float64 `json:"q1"`
+	Q3             *float64 `json:"q3"`
+	IQR            *float64 `json:"iqr"`
+	ECTS           *float64 `json:"ects"`
+	// HoursPerCredit is the median workload over a semester per ECTS credit, a credit
+	// stands for 30 hours.
+	HoursPerCredit *float64 `json:"hoursPerCredit"`
+	// CreditRatio is HoursPerCredit / 30, above 1 the course takes more time than its credits suggest.
+	CreditRatio *float64 `json:"creditRatio"`
+}
+
+type PublicEvaluation struct {
+	EvaluationID int32   `json:"evaluationId"`
+	CourseNumber string  `json:"courseNumber"`
+	CourseName   string  `json:"courseName"`
+	Semester     *string `json:"semester"`
+	Review       string  `json:"review"`
+	Date         *string `json:"date"`
+	Rating       Rating  `json:"rating"`
+}
+
+type UserEvaluation struct {
+	EvaluationID     int32   `json:"evaluationId"`
+	CourseNumber     string  `json:"courseNumber"`
+	CourseName       string  `json:"courseName"`
+	Semester         *string `json:"semester"`
+	Review           *string `json:"review"`
+	Status           *string `json:"status"`
+	RequestedChanges *string `json:"requestedChanges"`
+	Rating           Rating  `json:"rating"`
+	// RatingValues holds the value of every dimension rated, including retired ones.
+	RatingValues map[string]int32 `json:"ratingValues"`
+	// RatingComments holds the comments on rated dimensions with their moderation state.
+	RatingComments map[string]RatingComment `json:"ratingComments"`
+	// Redaction holds the original text and the reason if a moderator changed the review.
+	Redaction *ReviewRevision `json:"redaction"`
+}
+
+type RatingComment struct {
+	Comment          string  `json:"comment"`
+	Status           *string `json:"status"`
+	RequestedChanges *string `json:"requestedChanges"`
+}
+
+type Evaluation struct {
+	ID           int32   `json:"id"`
+	CourseNumber string  `json:"courseNumber"`
+	Semester     *string `json:"semester"`
+}
+
+type ReviewRecord struct {
+	EvaluationID     int32   `json:"evaluationId"`
+	Date             *string `json:"date"`
+	Status           *string `json:"status"`
+	Review           string  `json:"review"`
+	RequestedChanges *string `json:"requestedChanges"`
+	OldReview        *string `json:"oldReview"`
+	// a verified review of a running semester is only published after this day
+	EmbargoedUntil *string `json:"embargoedUntil"`
+	RedactedAt     *string `json:"redactedAt"`
+}
+
+// ReviewRevision is the text of a review before a moderator changed it.
+type ReviewRevision struct {
+	ID           int32   `json:"id"`
+	EvaluationID int32   `json:"evaluationId"`
+	Review       string  `json:"review"`
+	Reason       *string `json:"reason"`
+	CreatedAt    string  `json:"createdAt"`
+}
+
+type PendingReview struct {
+	EvaluationID     int32   `json:"evaluationId"`
+	CourseNumber     string  `json:"courseNumber"`
+	CourseName       string  `json:"courseName"`
+	UserID           string  `json:"userId"`
+	Review           string  `json:"review"`
+	OldReview        *string `json:"oldReview"`
+	RequestedChanges *string `json:"requestedChanges"`
+	// the day the review would be published if verified now, unset if right away
+	EmbargoUntil *string `json:"embargoUntil"`
+}
+
+// EmbargoedReview is verified, but hidden until the end of its semester.
+type EmbargoedReview struct {
+	EvaluationID   int32   `json:"evaluationId"`
+	CourseNumber   string  `json:"courseNumber"`
+	CourseName     string  `json:"courseName"`
+	Semester       *string `json:"semester"`
+	Review         string  `json:"review"`
+	EmbargoedUntil string  `json:"embargoedUntil"`
+}
+
+// SemesterEnd is the last day of a semester, reviews of it are embargoed until then.
+type SemesterEnd struct {
+	Semester string `json:"semester"`
+	EndsOn   string `json:"endsOn"`
+}
+
+type SemesterEndBody struct {
+	EndsOn string `json:"endsOn"` // YYYY-MM-DD
+}
+
+// PendingRatingComment is a comment on a rated dimension waiting for moderation.
+type PendingRatingComment struct {
+	EvaluationID int32  `json:"evaluationId"`
+	Dimension    string `json:"dimension"`
+	Value        int32  `json:"value"`
+	Comment      string `json:"comment"`
+	CourseNumber string `json:"courseNumber"`
+	CourseName   string `json:"courseName"`
+	UserID       string `json:"userId"`
+	// Shadowed comments belong to a rating given under a shadow ban, verified they only
+	// show once the ban is lifted.
+	Shadowed bool `json:"shadowed"`
+}
+
+type RatingCommentRecord struct {
+	EvaluationID     int32   `json:"evaluationId"`
+	Dimension        string  `json:"dimension"`
+	Value            int32   `json:"value"`
+	Comment          string  `json:"comment"`
+	Status           *string `json:"status"`
+	RequestedChanges *string `json:"requestedChanges"`
+	Date             *string `json:"date"`
+}
+
+type User struct {
+	UserID    string `json:"userId"`
+	Admin     bool   `json:"admin"`
+	Moderator bool   `json:"moderator"`
+}
+
+// Formats a data export can be downloaded in.
+const (
+	ExportJSON = "json"
+	ExportZip  = "zip" // one CSV file per table
+)
+
+// DataExport is an archive of all personal data of a user. It is built in the background,
+// once Status is "ready" the links download it until LinksExpireAt.
+type DataExport struct {
+	ID            string            `json:"id"`
+	Status        string            `json:"status"`
+	Error         *string           `json:"error"`
+	CreatedAt     string            `json:"createdAt"`
+	FinishedAt    *string           `json:"finishedAt"`
+	Links         map[string]string `json:"links"`
+	LinksExpireAt *string           `json:"linksExpireAt"`
+}
+
+// Ways to delete an account.
+const (
+	DeleteContributions = "delete" // reviews and ratings are deleted with the account
+	DetachContributions = "detach" // they stay, no longer tied to any account
+)
+
+type AccountDeletionBody struct {
+	Mode string `json:"mode"`
+}
+
+// AccountDeletion runs at ScheduledFor unless it is cancelled before. Afterwards only a
+// tombstone with the hash of the user id is kept.
+type AccountDeletion struct {
+	ID           int32   `json:"id"`
+	UserID       *string `json:"userId"`
+	UserHash     string  `json:"userHash"`
+	Mode         string  `json:"mode"`
+	Status       string  `json:"status"` // pending, cancelled or completed
+	RequestedAt  string  `json:"requestedAt"`
+	ScheduledFor string  `json:"scheduledFor"`
+	CancelledAt  *string `json:"cancelledAt"`
+	CompletedAt  *string `json:"completedAt"`
+	Evaluations  *int32  `json:"evaluations"`
+}
+
+// Kinds of bans.
+const (
+	BanFull   = "full"   // the user can't use any authenticated route
+	BanWrite  = "write"  // the user can read but not submit or change anything
+	BanShadow = "shadow" // submissions seem to work but are rejected silently
+)
+
+// UserBanBody bans a user until ExpiresAt, an RFC 3339 time, or for good without it.
+type UserBanBody struct {
+	Kind      string  `json:"kind"`
+	Reason    string  `json:"reason"`
+	ExpiresAt *string `json:"expiresAt"`
+}
+
+type UserBan struct {
+	UserID    string  `json:"userId"`
+	Kind      string  `json:"kind"`
+	Reason    string  `json:"reason"`
+	CreatedAt string  `json:"createdAt"`
+	ExpiresAt *string `json:"expiresAt"`
+	Active    bool    `json:"active"`
+}
+
+type StatEntry struct {
+	Time  string `json:"time"`
+	Value string `json:"value"`
+}
+
+type UsageStats struct {
+	Users []StatEntry `json:"users"`
+	Paths []StatEntry `json:"paths"`
+}
+
+type Success struct {
+	Success string `json:"success"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+type Created struct {
+	ID int32 `json:"id"`
+}
+
+// // // // // // //
+// requests       //
+// // // // // // //
+
+// Ratings is a rating as sent by clients, a dimension set to 0 is not rated.
+// HoursPerWeek is optional and must be between 0.5 and 60.
+type Ratings struct {
+	Recommended  int32    `json:"recommended"`
+	Engaging     int32    `json:"engaging"`
+	Difficulty   int32    `json:"difficulty"`
+	Effort       int32    `json:"effort"`
+	Resources    int32    `json:"resources"`
+	HoursPerWeek *float64 `json:"hoursPerWeek"`
+	// Values rates further dimensions by their key, see /v1/rating-dimensions. The built-in
+	// dimensions may be given here as well.
+	Values map[string]int32 `json:"values"`
+	// Comments explains the value of a rated dimension in at most 280 characters. They are
+	// shown once a moderator verified them.
+	Comments map[string]string `json:"comments"`
+}
+
+// RatingDimensionBody adds or relabels a rating dimension. The scale defaults to 1 - 5 and
+// can't change once the dimension exists, Position 0 keeps the current one or appends.
+type RatingDimensionBody struct {
+	LabelDe  string `json:"labelDe"`
+	LabelEn  string `json:"labelEn"`
+	ScaleMin int32  `json:"scaleMin"`
+	ScaleMax int32  `json:"scaleMax"`
+	Position int32  `json:"position"`
+}
+
+// EvaluationSubmission is a review and/or rating submitted without a login.
+type EvaluationSubmission struct {
+	CourseNumber string   `json:"courseNumber"`
+	Semester     string   `json:"semester"`
+	Review       string   `json:"review"`
+	AnonymousID  string   `json:"anonymousId"`
+	Rating       *Ratings `json:"rating"`
+}
+
+type SemesterBody struct {
+	Semester string `json:"semester"`
+}
+
+type ReviewBody struct {
+	Review string `json:"review"`
+}
+
+type RejectBody struct {
+	RequestedChanges string `json:"requestedChanges"`
+}
+
+// RedactBody replaces the text of a review, the reason is shown to its author.
+type RedactBody struct {
+	Review string `json:"review"`
+	Reason string `json:"reason"`
+}
+
+// DepartmentBody names a department and moves course number prefixes to it.
+type DepartmentBody struct {
+	Name     string   `json:"name"`
+	Prefixes []string `json:"prefixes"`
+}
@@ -0,0 +1,132 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/client"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// appDoer sends the requests of the client through fiber's app.Test instead of the network.
+type appDoer struct {
+	app   *fiber.App
+	calls atomic.Int32
+}
+
+func (d *appDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls.Add(1)
+	return d.app.Test(req, -1)
+}
+
+func newTestClient(app *fiber.App, opts ...client.Option) (*client.Client, *appDoer) {
+	doer := &appDoer{app: app}
+	opts = append([]client.Option{client.WithHTTPClient(doer), client.WithRetries(3, time.Millisecond)}, opts...)
+	return client.New("http://api.test", opts...), doer
+}
+
+// failing answers every request with status until it has failed times times.
+func failing(status, times int, retryAfter string) fiber.Handler {
+	var calls atomic.Int32
+	return func(c *fiber.Ctx) error {
+		if int(calls.Add(1)) > times {
+			return c.JSON(api.Stats{TotalCourses: 1, TotalReviews: 2})
+		}
+		if retryAfter != "" {
+			c.Set(fiber.HeaderRetryAfter, retryAfter)
+		}
+		return c.Status(status).JSON(fiber.Map{"error": "try again"})
+	}
+}
+
+func TestTokenIsSentAsBearer(t *testing.T) {
+	app := fiber.New()
+	app.Get("/v1/stats", func(c *fiber.Ctx) error {
+		return c.JSON(api.Stats{TotalReviews: int64(len(c.Get(fiber.HeaderAuthorization)))})
+	})
+
+	c, _ := newTestClient(app)
+	if stats, err := c.Stats(context.Background()); err != nil || stats.TotalReviews != 0 {
+		t.Fatalf("without token: got %+v, %v", stats, err)
+	}
+	c = c.WithToken("abc")
+	if stats, err := c.Stats(context.Background()); err != nil || stats.TotalReviews != int64(len("Bearer abc")) {
+		t.Fatalf("with token: got %+v, %v", stats, err)
+	}
+}
+
+func TestRetriesHonorRetryAfter(t *testing.T) {
+	for _, status := range []int{fiber.StatusTooManyRequests, fiber.StatusServiceUnavailable} {
+		app := fiber.New()
+		app.Get("/v1/stats", failing(status, 2, "0"))
+
+		// the backoff would outlast the deadline, only Retry-After lets the call succeed
+		c, doer := newTestClient(app, client.WithRetries(3, time.Hour))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		stats, err := c.Stats(ctx)
+		cancel()
+		if err != nil || stats.TotalReviews != 2 {
+			t.Fatalf("status %d: got %+v, %v", status, stats, err)
+		}
+		if calls := doer.calls.Load(); calls != 3 {
+			t.Errorf("status %d: got %d calls, want 3", status, calls)
+		}
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	app := fiber.New()
+	app.Get("/v1/stats", failing(fiber.StatusInternalServerError, 10, ""))
+
+	c, doer := newTestClient(app)
+	_, err := c.Stats(context.Background())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != fiber.StatusInternalServerError || apiErr.Message != "try again" {
+		t.Fatalf("got %v, want the 500 of the api", err)
+	}
+	if calls := doer.calls.Load(); calls != 4 {
+		t.Errorf("got %d calls, want 4", calls)
+	}
+}
+
+func TestPostIsNotRetried(t *testing.T) {
+	app := fiber.New()
+	app.Post("/v1/evaluations", failing(fiber.StatusServiceUnavailable, 10, "0"))
+
+	c, doer := newTestClient(app)
+	_, err := c.SubmitEvaluation(context.Background(), api.EvaluationSubmission{AnonymousID: "a", CourseNumber: "252-0027-00L", Semester: "HS23", Review: "Good"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != fiber.StatusServiceUnavailable {
+		t.Fatalf("got %v, want the 503 of the api", err)
+	}
+	if calls := doer.calls.Load(); calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
+
+func TestCancelDuringBackoff(t *testing.T) {
+	app := fiber.New()
+	app.Get("/v1/stats", failing(fiber.StatusServiceUnavailable, 10, ""))
+
+	c, doer := newTestClient(app, client.WithRetries(3, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.Stats(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %v, the backoff wasn't interrupted", elapsed)
+	}
+	if calls := doer.calls.Load(); calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
@@ -0,0 +1,374 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"coursereview/app/api"
+)
+
+func evaluationPath(id int32) string {
+	return "/v1/evaluations/" + strconv.Itoa(int(id))
+}
+
+func coursePath(number string) string {
+	return "/v1/courses/" + url.PathEscape(number)
+}
+
+// // // // // //
+// public      //
+// // // // // //
+
+func (c *Client) Stats(ctx context.Context) (api.Stats, error) {
+	return get[api.Stats](ctx, c, "/v1/stats")
+}
+
+// Evaluations lists all verified reviews with their ratings.
+func (c *Client) Evaluations(ctx context.Context) ([]api.PublicEvaluation, error) {
+	return get[[]api.PublicEvaluation](ctx, c, "/v1/evaluations")
+}
+
+func (c *Client) LatestReviews(ctx context.Context) ([]api.CourseActivity, error) {
+	return get[[]api.CourseActivity](ctx, c, "/v1/reviews/latest")
+}
+
+// RatingAveragesQuery filters and orders RatingAverages, zero values are left out.
+type RatingAveragesQuery struct {
+	Page     int // starts at 1
+	PageSize int // at most 200
+	// Sort is a rating dimension, "count" or "courseNumber", a "-" prefix sorts descending.
+	Sort       string
+	MinCount   int
+	Department string // course number prefix, e.g. "263"
+}
+
+func (q RatingAveragesQuery) encode() string {
+	values := url.Values{}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PageSize > 0 {
+		values.Set("pageSize", strconv.Itoa(q.PageSize))
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.MinCount > 0 {
+		values.Set("minCount", strconv.Itoa(q.MinCount))
+	}
+	if q.Department != "" {
+		values.Set("department", q.Department)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// RatingAverages lists per-dimension rating averages and counts of the courses matching the query.
+func (c *Client) RatingAverages(ctx context.Context, query RatingAveragesQuery) (api.RatingAvgPage, error) {
+	return get[api.RatingAvgPage](ctx, c, "/v1/ratings/averages"+query.encode())
+}
+
+func (c *Client) CurrentSemesters(ctx context.Context) ([]string, error) {
+	return get[[]string](ctx, c, "/v1/semesters/current")
+}
+
+func (c *Client) Courses(ctx context.Context) ([]api.Course, error) {
+	return get[[]api.Course](ctx, c, "/v1/courses")
+}
+
+func (c *Client) CourseReviewCounts(ctx context.Context) ([]api.CourseReviewCount, error) {
+	return get[[]api.CourseReviewCount](ctx, c, "/v1/courses/review-counts")
+}
+
+// EvaluatedCourses lists the courses with a verified review or a rating.
+func (c *Client) EvaluatedCourses(ctx context.Context) ([]api.CourseActivity, error) {
+	return get[[]api.CourseActivity](ctx, c, "/v1/courses/evaluated")
+}
+
+func (c *Client) Course(ctx context.Context, number string) (api.Course, error) {
+	return get[api.Course](ctx, c, coursePath(number))
+}
+
+func (c *Client) CourseReviews(ctx context.Context, number string) ([]api.Review, error) {
+	return get[[]api.Review](ctx, c, coursePath(number)+"/reviews")
+}
+
+func (c *Client) CourseRatings(ctx context.Context, number string) ([]api.Rating, error) {
+	return get[[]api.Rating](ctx, c, coursePath(number)+"/ratings")
+}
+
+func (c *Client) CourseRatingAverage(ctx context.Context, number string) (api.RatingAvg, error) {
+	return get[api.RatingAvg](ctx, c, coursePath(number)+"/ratings/average")
+}
+
+// CourseLecturers lists who taught the course by semester, latest first.
+func (c *Client) CourseLecturers(ctx context.Context, number string) ([]api.SemesterLecturers, error) {
+	return get[[]api.SemesterLecturers](ctx, c, coursePath(number)+"/lecturers")
+}
+
+// CourseRatingsBySemester splits the rating averages of a course by semester and its lecturers.
+func (c *Client) CourseRatingsBySemester(ctx context.Context, number string) ([]api.SemesterRatingAvg, error) {
+	return get[[]api.SemesterRatingAvg](ctx, c, coursePath(number)+"/ratings/semesters")
+}
+
+// SimilarCourses lists the courses whose reviews read most alike, most similar first.
+func (c *Client) SimilarCourses(ctx context.Context, number string) ([]api.SimilarCourse, error) {
+	return get[[]api.SimilarCourse](ctx, c, coursePath(number)+"/similar")
+}
+
+// RatingDimensions lists the dimensions courses can be rated on, in display order.
+func (c *Client) RatingDimensions(ctx context.Context) ([]api.RatingDimension, error) {
+	return get[[]api.RatingDimension](ctx, c, "/v1/rating-dimensions")
+}
+
+// CourseDimensionAverages returns the average, distribution and verified comments of every
+// active rating dimension of a course.
+func (c *Client) CourseDimensionAverages(ctx context.Context, number string) ([]api.DimensionAvg, error) {
+	return get[[]api.DimensionAvg](ctx, c, coursePath(number)+"/ratings/dimensions")
+}
+
+// CourseSummary returns the keyphrases and sentiment of the verified reviews of a course.
+func (c *Client) CourseSummary(ctx context.Context, number string) (api.CourseSummary, error) {
+	return get[api.CourseSummary](ctx, c, coursePath(number)+"/summary")
+}
+
+// CourseWorkload returns the hours per week spent on a course.
+func (c *Client) CourseWorkload(ctx context.Context, number string) (api.CourseWorkload, error) {
+	return get[api.CourseWorkload](ctx, c, coursePath(number)+"/workload")
+}
+
+// CourseTrend returns the rating averages of a course per semester, oldest first.
+func (c *Client) CourseTrend(ctx context.Context, number string) ([]api.TrendPoint, error) {
+	return get[[]api.TrendPoint](ctx, c, coursePath(number)+"/trends")
+}
+
+func (c *Client) Lecturer(ctx context.Context, id int32) (api.LecturerProfile, error) {
+	return get[api.LecturerProfile](ctx, c, "/v1/lecturers/"+strconv.Itoa(int(id)))
+}
+
+// CompareCourses returns the comparison data of up to 5 courses in the given order.
+func (c *Client) CompareCourses(ctx context.Context, numbers ...string) ([]api.CourseComparison, error) {
+	return get[[]api.CourseComparison](ctx, c, "/v1/compare?courses="+url.QueryEscape(strings.Join(numbers, ",")))
+}
+
+func (c *Client) Departments(ctx context.Context) ([]api.Department, error) {
+	return get[[]api.Department](ctx, c, "/v1/departments")
+}
+
+func (c *Client) DepartmentCourses(ctx context.Context, code string) ([]api.Course, error) {
+	return get[[]api.Course](ctx, c, "/v1/departments/"+url.PathEscape(code)+"/courses")
+}
+
+// DepartmentStats aggregates the ratings and verified reviews of all courses of a department.
+func (c *Client) DepartmentStats(ctx context.Context, code string) (api.DepartmentStats, error) {
+	return get[api.DepartmentStats](ctx, c, "/v1/departments/"+url.PathEscape(code)+"/stats")
+}
+
+// SubmitEvaluation submits a review and/or rating without a login and returns the evaluation id.
+func (c *Client) SubmitEvaluation(ctx context.Context, submission api.EvaluationSubmission) (int32, error) {
+	created, err := send[api.Created](ctx, c, http.MethodPost, "/v1/evaluations", submission)
+	return created.ID, err
+}
+
+// // // // // // // // //
+// authentication needed //
+// // // // // // // // //
+
+func (c *Client) MyEvaluations(ctx context.Context) ([]api.UserEvaluation, error) {
+	return get[[]api.UserEvaluation](ctx, c, "/v1/me/evaluations")
+}
+
+// Recommendations suggests courses the user hasn't evaluated yet and explains each suggestion.
+func (c *Client) Recommendations(ctx context.Context) ([]api.Recommendation, error) {
+	return get[[]api.Recommendation](ctx, c, "/v1/me/recommendations")
+}
+
+// RequestDataExport starts building an archive of all personal data of the user. Poll
+// DataExport until its status is "ready", then download it with DownloadDataExport.
+func (c *Client) RequestDataExport(ctx context.Context) (api.DataExport, error) {
+	return send[api.DataExport](ctx, c, http.MethodPost, "/v1/me/exports", nil)
+}
+
+func (c *Client) DataExport(ctx context.Context, id string) (api.DataExport, error) {
+	return get[api.DataExport](ctx, c, "/v1/me/exports/"+url.PathEscape(id))
+}
+
+// DownloadDataExport fetches the archive behind one of the signed links of a ready export.
+func (c *Client) DownloadDataExport(ctx context.Context, link string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, decode(resp, nil)
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// DeleteAccount schedules the deletion of the account, mode is api.DeleteContributions or
+// api.DetachContributions. It can be cancelled during the grace period.
+func (c *Client) DeleteAccount(ctx context.Context, mode string) (api.AccountDeletion, error) {
+	return send[api.AccountDeletion](ctx, c, http.MethodPost, "/v1/me/deletion", api.AccountDeletionBody{Mode: mode})
+}
+
+func (c *Client) AccountDeletion(ctx context.Context) (api.AccountDeletion, error) {
+	return get[api.AccountDeletion](ctx, c, "/v1/me/deletion")
+}
+
+func (c *Client) CancelAccountDeletion(ctx context.Context) (api.AccountDeletion, error) {
+	return send[api.AccountDeletion](ctx, c, http.MethodDelete, "/v1/me/deletion", nil)
+}
+
+func (c *Client) UpdateSemester(ctx context.Context, id int32, semester string) (api.Evaluation, error) {
+	return send[api.Evaluation](ctx, c, http.MethodPatch, evaluationPath(id), api.SemesterBody{Semester: semester})
+}
+
+// SetReview creates or replaces a review, which then waits for moderation again.
+func (c *Client) SetReview(ctx context.Context, id int32, review string) error {
+	return c.do(ctx, http.MethodPut, evaluationPath(id)+"/review", api.ReviewBody{Review: review}, nil)
+}
+
+func (c *Client) DeleteReview(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, evaluationPath(id)+"/review", nil, nil)
+}
+
+// RestoreReview undoes DeleteReview within the undo window of the server.
+func (c *Client) RestoreReview(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodPost, evaluationPath(id)+"/review/restore", nil, nil)
+}
+
+func (c *Client) SetRating(ctx context.Context, id int32, ratings api.Ratings) error {
+	return c.do(ctx, http.MethodPut, evaluationPath(id)+"/rating", ratings, nil)
+}
+
+func (c *Client) DeleteRating(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, evaluationPath(id)+"/rating", nil, nil)
+}
+
+// RestoreRating undoes DeleteRating within the undo window of the server.
+func (c *Client) RestoreRating(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodPost, evaluationPath(id)+"/rating/restore", nil, nil)
+}
+
+// // // // // // // //
+// mod / admin needed //
+// // // // // // // //
+
+func (c *Client) SetCurrentSemesters(ctx context.Context, semesters []string) ([]string, error) {
+	return send[[]string](ctx, c, http.MethodPut, "/v1/semesters/current", semesters)
+}
+
+func (c *Client) PendingReviews(ctx context.Context) ([]api.PendingReview, error) {
+	return get[[]api.PendingReview](ctx, c, "/v1/moderation/reviews")
+}
+
+func (c *Client) VerifyReview(ctx context.Context, id int32) (api.ReviewRecord, error) {
+	return send[api.ReviewRecord](ctx, c, http.MethodPost, "/v1/moderation/reviews/"+strconv.Itoa(int(id))+"/verify", nil)
+}
+
+// RedactReview replaces the text of a review and publishes it, the reason is shown to its author.
+func (c *Client) RedactReview(ctx context.Context, id int32, review, reason string) (api.ReviewRecord, error) {
+	return send[api.ReviewRecord](ctx, c, http.MethodPost, "/v1/moderation/reviews/"+strconv.Itoa(int(id))+"/redact", api.RedactBody{Review: review, Reason: reason})
+}
+
+func (c *Client) ReviewRevisions(ctx context.Context, id int32) ([]api.ReviewRevision, error) {
+	return get[[]api.ReviewRevision](ctx, c, "/v1/moderation/reviews/"+strconv.Itoa(int(id))+"/revisions")
+}
+
+func (c *Client) EmbargoedReviews(ctx context.Context) ([]api.EmbargoedReview, error) {
+	return get[[]api.EmbargoedReview](ctx, c, "/v1/moderation/reviews/embargoed")
+}
+
+func (c *Client) SemesterCalendar(ctx context.Context) ([]api.SemesterEnd, error) {
+	return get[[]api.SemesterEnd](ctx, c, "/v1/moderation/semester-calendar")
+}
+
+// SetSemesterEnd sets the last day of a semester, endsOn formatted as YYYY-MM-DD.
+func (c *Client) SetSemesterEnd(ctx context.Context, semester, endsOn string) (api.SemesterEnd, error) {
+	return send[api.SemesterEnd](ctx, c, http.MethodPut, "/v1/moderation/semester-calendar/"+url.PathEscape(semester), api.SemesterEndBody{EndsOn: endsOn})
+}
+
+func (c *Client) RejectReview(ctx context.Context, id int32, requestedChanges string) (api.ReviewRecord, error) {
+	return send[api.ReviewRecord](ctx, c, http.MethodPost, "/v1/moderation/reviews/"+strconv.Itoa(int(id))+"/reject", api.RejectBody{RequestedChanges: requestedChanges})
+}
+
+func (c *Client) PendingRatingComments(ctx context.Context) ([]api.PendingRatingComment, error) {
+	return get[[]api.PendingRatingComment](ctx, c, "/v1/moderation/rating-comments")
+}
+
+func ratingCommentPath(id int32, dimension string) string {
+	return "/v1/moderation/rating-comments/" + strconv.Itoa(int(id)) + "/" + url.PathEscape(dimension)
+}
+
+func (c *Client) VerifyRatingComment(ctx context.Context, id int32, dimension string) (api.RatingCommentRecord, error) {
+	return send[api.RatingCommentRecord](ctx, c, http.MethodPost, ratingCommentPath(id, dimension)+"/verify", nil)
+}
+
+func (c *Client) RejectRatingComment(ctx context.Context, id int32, dimension, requestedChanges string) (api.RatingCommentRecord, error) {
+	return send[api.RatingCommentRecord](ctx, c, http.MethodPost, ratingCommentPath(id, dimension)+"/reject", api.RejectBody{RequestedChanges: requestedChanges})
+}
+
+func (c *Client) UsageStats(ctx context.Context) (api.UsageStats, error) {
+	return get[api.UsageStats](ctx, c, "/v1/moderation/usage-stats")
+}
+
+// StartScrape starts scraping the VVZ for the semester in the background.
+func (c *Client) StartScrape(ctx context.Context, semester string) error {
+	return c.do(ctx, http.MethodPost, "/v1/moderation/scrapes", api.SemesterBody{Semester: semester}, nil)
+}
+
+func (c *Client) SetModerator(ctx context.Context, userID string) (api.User, error) {
+	return send[api.User](ctx, c, http.MethodPut, "/v1/admin/moderators/"+url.PathEscape(userID), nil)
+}
+
+func (c *Client) AddCourse(ctx context.Context, course api.Course) ([]api.Course, error) {
+	return send[[]api.Course](ctx, c, http.MethodPost, "/v1/admin/courses", course)
+}
+
+// SetDepartment creates or renames a department and moves the course number prefixes to it.
+func (c *Client) SetDepartment(ctx context.Context, code string, department api.DepartmentBody) (api.Department, error) {
+	return send[api.Department](ctx, c, http.MethodPut, "/v1/admin/departments/"+url.PathEscape(code), department)
+}
+
+func (c *Client) AccountDeletions(ctx context.Context) ([]api.AccountDeletion, error) {
+	return get[[]api.AccountDeletion](ctx, c, "/v1/admin/account-deletions")
+}
+
+func (c *Client) UserBans(ctx context.Context) ([]api.UserBan, error) {
+	return get[[]api.UserBan](ctx, c, "/v1/admin/bans")
+}
+
+// BanUser bans a user given by unique_id or pseudonym, replacing an earlier ban.
+func (c *Client) BanUser(ctx context.Context, userID string, ban api.UserBanBody) (api.UserBan, error) {
+	return send[api.UserBan](ctx, c, http.MethodPut, "/v1/admin/bans/"+url.PathEscape(userID), ban)
+}
+
+func (c *Client) UnbanUser(ctx context.Context, userID string) (api.UserBan, error) {
+	return send[api.UserBan](ctx, c, http.MethodDelete, "/v1/admin/bans/"+url.PathEscape(userID), nil)
+}
+
+// AllRatingDimensions lists every rating dimension including retired ones.
+func (c *Client) AllRatingDimensions(ctx context.Context) ([]api.RatingDimension, error) {
+	return get[[]api.RatingDimension](ctx, c, "/v1/admin/rating-dimensions")
+}
+
+// SetRatingDimension adds or relabels a rating dimension, bringing it back if it was retired.
+func (c *Client) SetRatingDimension(ctx context.Context, key string, dimension api.RatingDimensionBody) (api.RatingDimension, error) {
+	return send[api.RatingDimension](ctx, c, http.MethodPut, "/v1/admin/rating-dimensions/"+url.PathEscape(key), dimension)
+}
+
+// RetireRatingDimension stops a rating dimension from being rated, its values are kept.
+func (c *Client) RetireRatingDimension(ctx context.Context, key string) (api.RatingDimension, error) {
+	return send[api.RatingDimension](ctx, c, http.MethodDelete, "/v1/admin/rating-dimensions/"+url.PathEscape(key), nil)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidDeletionMode = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid mode, use delete or detach"}
+	ErrDeletionPending     = &ServiceError{Status: fiber.StatusConflict, Message: "Account deletion already requested"}
+	ErrDeletionNotFound    = &ServiceError{Status: fiber.StatusNotFound, Message: "No account deletion pending"}
+)
+
+const (
+	// time to change one's mind, the account keeps working until then
+	accountDeletionGrace    = 14 * 24 * time.Hour
+	accountDeletionInterval = time.Hour
+)
+
+func userHash(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAccountDeletionDTO(row sql.AccountDeletion) api.AccountDeletion {
+	deletion := api.AccountDeletion{
+		ID:           row.ID,
+		UserID:       textPtr(row.UserID),
+		UserHash:     row.UserHash,
+		Mode:         row.Mode,
+		Status:       "pending",
+		RequestedAt:  row.RequestedAt.Time.Format(time.RFC3339),
+		ScheduledFor: row.ScheduledFor.Time.Format(time.RFC3339),
+		CancelledAt:  timestamptzPtr(row.CancelledAt),
+		CompletedAt:  timestamptzPtr(row.CompletedAt),
+		Evaluations:  int4Ptr(row.Evaluations),
+	}
+	if row.CancelledAt.Valid {
+		deletion.Status = "cancelled"
+	} else if row.CompletedAt.Valid {
+		deletion.Status = "completed"
+	}
+	return deletion
+}
+
+// RequestAccountDeletion schedules the deletion of the account after the grace period.
+// Mode decides whether the contributions are deleted too or kept without the account.
+func (s *Service) RequestAccountDeletion(ctx context.Context, userID, mode string) (api.AccountDeletion, error) {
+	if mode != api.DeleteContributions && mode != api.DetachContributions {
+		return api.AccountDeletion{}, ErrInvalidDeletionMode
+	}
+	if _, err := s.db.GetPendingAccountDeletion(ctx, pgtype.Text{String: userID, Valid: true}); err == nil {
+		return api.AccountDeletion{}, ErrDeletionPending
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return api.AccountDeletion{}, err
+	}
+	deletion, err := s.db.RequestAccountDeletion(ctx, sql.RequestAccountDeletionParams{
+		UserID:       pgtype.Text{String: userID, Valid: true},
+		UserHash:     userHash(userID),
+		Mode:         mode,
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now().Add(accountDeletionGrace), Valid: true},
+	})
+	if err != nil {
+		return api.AccountDeletion{}, err
+	}
+	return toAccountDeletionDTO(deletion), nil
+}
+
+func (s *Service) PendingAccountDeletion(ctx context.Context, userID string) (api.AccountDeletion, error) {
+	deletion, err := s.db.GetPendingAccountDeletion(ctx, pgtype.Text{String: userID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return api.AccountDeletion{}, ErrDeletionNotFound
+	}
+	if err != nil {
+		return api.AccountDeletion{}, err
+	}
+	return toAccountDeletionDTO(deletion), nil
+}
+
+func (s *Service) CancelAccountDeletion(ctx context.Context, userID string) (api.AccountDeletion, error) {
+	deletion, err := s.db.CancelAccountDeletion(ctx, pgtype.Text{String: userID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return api.AccountDeletion{}, ErrDeletionNotFound
+	}
+	if err != nil {
+		return api.AccountDeletion{}, err
+	}
+	return toAccountDeletionDTO(deletion), nil
+}
+
+// AccountDeletions lists all requested deletions for admins, the completed ones as tombstones.
+func (s *Service) AccountDeletions(ctx context.Context) ([]api.AccountDeletion, error) {
+	rows, err := s.db.GetAccountDeletions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapAll(rows, toAccountDeletionDTO), nil
+}
+
+// RunAccountDeletions deletes the accounts whose grace period is over.
+func (s *Service) RunAccountDeletions(ctx context.Context) error {
+	due, err := s.db.GetDueAccountDeletions(ctx)
+	if err != nil {
+		return err
+	}
+	deleted := false
+	for _, deletion := range due {
+		if err := s.deleteAccount(ctx, deletion); err != nil {
+			log.Printf("Account deletion %d failed: %v", deletion.ID, err)
+			continue
+		}
+		deleted = deleted || deletion.Mode == api.DeleteContributions
+	}
+	if deleted {
+		s.publish(EventReviewChanged, EventRatingChanged)
+	}
+	return nil
+}
+
+// auditActions are the event log entries kept when their user is deleted, without the user.
+var auditActions = []string{actionRedactReview, actionBanUser, actionUnbanUser}
+
+// deleteAccount deletes the user in one transaction. Evaluations, reviews, ratings and
+// exports cascade with it and the event log entries of the user are deleted, apart from
+// audit entries. In detach mode the evaluations and event log are moved to a new
+// placeholder user nobody can log in as first.
+func (s *Service) deleteAccount(ctx context.Context, deletion sql.AccountDeletion) error {
+	userID := deletion.UserID.String
+	return s.inTx(ctx, func(db *sql.Queries) error {
+		evaluations, err := db.CountUserEvaluations(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if deletion.Mode == api.DetachContributions {
+			id := make([]byte, 16)
+			if _, err := rand.Read(id); err != nil {
+				return err
+			}
+			placeholder := "deleted-" + hex.EncodeToString(id)
+			if _, err := db.SetUser(ctx, placeholder); err != nil {
+				return err
+			}
+			if _, err := db.DetachUserEvaluations(ctx, sql.DetachUserEvaluationsParams{PlaceholderID: placeholder, UserID: userID}); err != nil {
+				return err
+			}
+			err = db.DetachUserEventLog(ctx, sql.DetachUserEventLogParams{
+				PlaceholderID: pgtype.Text{String: placeholder, Valid: true},
+				UserID:        pgtype.Text{String: userID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		err = db.DeleteUserEventLog(ctx, sql.DeleteUserEventLogParams{
+			UserID:       pgtype.Text{String: userID, Valid: true},
+			AuditActions: auditActions,
+		})
+		if err != nil {
+			return err
+		}
+		err = db.CompleteAccountDeletion(ctx, sql.CompleteAccountDeletionParams{
+			ID:          deletion.ID,
+			Evaluations: pgtype.Int4{Int32: int32(evaluations), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return db.DeleteUser(ctx, userID)
+	})
+}
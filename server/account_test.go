@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// These tests need a database, TEST_DB_URL points to one. Every test loads schema.sql into
+// a schema of its own and drops it afterwards, the database itself is left as it was.
+
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(suffix)
+	admin, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Error(err)
+		}
+		admin.Close()
+	})
+
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	ddl, err := os.ReadFile("../sql/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, string(ddl)); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func execSQL(t *testing.T, pool *pgxpool.Pool, query string, args ...any) {
+	t.Helper()
+	if _, err := pool.Exec(context.Background(), query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countRows(t *testing.T, pool *pgxpool.Pool, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := pool.QueryRow(context.Background(), query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// seedContributions gives the user an evaluation with a review, a rating with a value of a
+// further dimension, an event log entry and an export. It returns the evaluation id.
+func seedContributions(t *testing.T, pool *pgxpool.Pool, userID string) int32 {
+	t.Helper()
+	ctx := context.Background()
+	execSQL(t, pool, "INSERT INTO courses (course_number, course_name) VALUES ('252-0027-00L', 'Einführung in die Programmierung') ON CONFLICT DO NOTHING")
+	execSQL(t, pool, "INSERT INTO rating_dimensions (key, label_de, label_en) VALUES ('exam_fairness', 'Prüfungsfairness', 'Exam fairness') ON CONFLICT DO NOTHING")
+	execSQL(t, pool, "INSERT INTO actions (name) SELECT 'add_review' WHERE NOT EXISTS (SELECT 1 FROM actions WHERE name = 'add_review')")
+	execSQL(t, pool, "INSERT INTO users (user_id) VALUES ($1)", userID)
+
+	var id int32
+	err := pool.QueryRow(ctx, "INSERT INTO course_evaluation_map (user_id, course_number, semester) VALUES ($1, '252-0027-00L', '23HS') RETURNING id", userID).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	execSQL(t, pool, "INSERT INTO reviews (evaluation_id, review) VALUES ($1, 'Good exercises')", id)
+	execSQL(t, pool, "INSERT INTO ratings (evaluation_id, recommended) VALUES ($1, 4)", id)
+	execSQL(t, pool, "INSERT INTO rating_values (evaluation_id, dimension_key, value) VALUES ($1, 'exam_fairness', 3)", id)
+	execSQL(t, pool, "INSERT INTO event_log (evaluation_id, user_id, action_id) SELECT $1, $2, id FROM actions WHERE name = 'add_review'", id, userID)
+	execSQL(t, pool, "INSERT INTO data_exports (id, user_id) VALUES ($1, $2)", "export-"+userID, userID)
+	return id
+}
+
+func requestDeletion(t *testing.T, svc *Service, userID, mode string) sql.AccountDeletion {
+	t.Helper()
+	deletion, err := svc.db.RequestAccountDeletion(context.Background(), sql.RequestAccountDeletionParams{
+		UserID:       pgtype.Text{String: userID, Valid: true},
+		UserHash:     userHash(userID),
+		Mode:         mode,
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deletion
+}
+
+// assertTombstone checks that the completed deletion keeps the hash but not the user id.
+func assertTombstone(t *testing.T, pool *pgxpool.Pool, deletion sql.AccountDeletion, evaluations int) {
+	t.Helper()
+	var userID pgtype.Text
+	var hash string
+	var completed pgtype.Timestamptz
+	var counted pgtype.Int4
+	err := pool.QueryRow(context.Background(), "SELECT user_id, user_hash, completed_at, evaluations FROM account_deletions WHERE id = $1", deletion.ID).
+		Scan(&userID, &hash, &completed, &counted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID.Valid || hash != deletion.UserHash || !completed.Valid || counted.Int32 != int32(evaluations) {
+		t.Errorf("tombstone: user %v, hash %s, completed %v, evaluations %v", userID, hash, completed, counted)
+	}
+}
+
+func TestDeleteAccountDeletesContributions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	other := seedContributions(t, pool, "bob")
+	deletion := requestDeletion(t, svc, "alice", api.DeleteContributions)
+	// alice banned bob as admin, the audit entry outlives her account
+	execSQL(t, pool, "INSERT INTO actions (name) VALUES ('ban_user')")
+	execSQL(t, pool, "INSERT INTO event_log (user_id, action_id, info) SELECT 'alice', id, 'user=bob kind=full' FROM actions WHERE name = 'ban_user'")
+	// a moderator redacted her review, the original text is hers
+	execSQL(t, pool, "INSERT INTO review_revisions (evaluation_id, review) VALUES ($1, 'Ask TA Jane')", evaluation)
+
+	if err := svc.deleteAccount(context.Background(), deletion); err != nil {
+		t.Fatal(err)
+	}
+
+	for table, query := range map[string]string{
+		"course_evaluation_map": "SELECT COUNT(*) FROM course_evaluation_map WHERE id = $1",
+		"reviews":               "SELECT COUNT(*) FROM reviews WHERE evaluation_id = $1",
+		"ratings":               "SELECT COUNT(*) FROM ratings WHERE evaluation_id = $1",
+		"rating_values":         "SELECT COUNT(*) FROM rating_values WHERE evaluation_id = $1",
+		"review_revisions":      "SELECT COUNT(*) FROM review_revisions WHERE evaluation_id = $1",
+		"event_log":             "SELECT COUNT(*) FROM event_log WHERE evaluation_id = $1 OR user_id = 'alice'",
+	} {
+		if n := countRows(t, pool, query, evaluation); n != 0 {
+			t.Errorf("%s: %d rows of the deleted account left", table, n)
+		}
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM users WHERE user_id = 'alice'"); n != 0 {
+		t.Error("user was not deleted")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM data_exports WHERE user_id = 'alice'"); n != 0 {
+		t.Error("exports were kept")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM event_log WHERE user_id IS NULL AND info = 'user=bob kind=full'"); n != 1 {
+		t.Error("the ban audit entry was not kept without the user")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM reviews WHERE evaluation_id = $1", other); n != 1 {
+		t.Errorf("the review of another user was deleted too")
+	}
+	assertTombstone(t, pool, deletion, 1)
+}
+
+func TestDeleteAccountDetachesContributions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	deletion := requestDeletion(t, svc, "alice", api.DetachContributions)
+
+	if err := svc.deleteAccount(context.Background(), deletion); err != nil {
+		t.Fatal(err)
+	}
+
+	var owner string
+	if err := pool.QueryRow(context.Background(), "SELECT user_id FROM course_evaluation_map WHERE id = $1", evaluation).Scan(&owner); err != nil {
+		t.Fatal(err)
+	}
+	if len(owner) != len("deleted-")+32 || owner[:len("deleted-")] != "deleted-" {
+		t.Fatalf("evaluation belongs to %q, want a placeholder", owner)
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM event_log WHERE evaluation_id = $1 AND user_id = $2", evaluation, owner); n != 1 {
+		t.Errorf("event log entry not moved to the placeholder")
+	}
+	for table, query := range map[string]string{
+		"reviews":       "SELECT COUNT(*) FROM reviews WHERE evaluation_id = $1",
+		"ratings":       "SELECT COUNT(*) FROM ratings WHERE evaluation_id = $1",
+		"rating_values": "SELECT COUNT(*) FROM rating_values WHERE evaluation_id = $1",
+	} {
+		if n := countRows(t, pool, query, evaluation); n != 1 {
+			t.Errorf("%s: got %d rows, the contribution must be kept", table, n)
+		}
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM users WHERE user_id = 'alice'"); n != 0 {
+		t.Error("user was not deleted")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM data_exports WHERE user_id IN ('alice', $1)", owner); n != 0 {
+		t.Error("exports were kept, they are personal data and not contributions")
+	}
+	assertTombstone(t, pool, deletion, 1)
+}
+
+func TestDeleteAccountFailureChangesNothing(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	deletion := requestDeletion(t, svc, "alice", api.DetachContributions)
+
+	// the last step fails, after the contributions were detached and the deletion completed
+	execSQL(t, pool, `CREATE FUNCTION fail_user_deletion() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'injected failure';
+END;
+$$ LANGUAGE plpgsql`)
+	execSQL(t, pool, "CREATE TRIGGER fail_user_deletion BEFORE DELETE ON users FOR EACH ROW EXECUTE FUNCTION fail_user_deletion()")
+
+	if err := svc.deleteAccount(context.Background(), deletion); err == nil {
+		t.Fatal("deletion succeeded despite the failing step")
+	}
+
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM course_evaluation_map WHERE id = $1 AND user_id = 'alice'", evaluation); n != 1 {
+		t.Error("evaluation was detached")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM event_log WHERE user_id = 'alice'"); n != 1 {
+		t.Error("event log entry was detached")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM users WHERE user_id LIKE 'deleted-%'"); n != 0 {
+		t.Error("placeholder user was kept")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM account_deletions WHERE id = $1 AND user_id = 'alice' AND completed_at IS NULL", deletion.ID); n != 1 {
+		t.Error("deletion was completed")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM data_exports WHERE user_id = 'alice'"); n != 1 {
+		t.Error("export was deleted")
+	}
+}
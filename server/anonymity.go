@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// anonymityThreshold is the number of evaluations a course or semester needs before its
+// rating data is published, so a single student of a small seminar can't be singled out.
+// It is read from ANONYMITY_THRESHOLD, 1 turns the protection off.
+var anonymityThreshold = func() int32 {
+	threshold, err := strconv.Atoi(os.Getenv("ANONYMITY_THRESHOLD"))
+	if err != nil || threshold < 1 {
+		if os.Getenv("ANONYMITY_THRESHOLD") != "" {
+			log.Printf("Invalid ANONYMITY_THRESHOLD, using 5")
+		}
+		return 5
+	}
+	return int32(threshold)
+}()
+
+func publishable(count int32) bool {
+	return count >= anonymityThreshold
+}
+
+// coarseSemester returns the year of a semester, 2023 for 23HS.
+func coarseSemester(semester string) string {
+	parsed, err := ParseSemester(semester)
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(parsed.Year)
+}
+
+type cohort struct {
+	evaluations int32 // evaluations with a review or rating
+	ratings     int32
+}
+
+// cohorts holds the size of every course semester by course and semester, "" for
+// evaluations without a semester.
+type cohorts map[string]map[string]cohort
+
+// cohorts loads the cohorts of the given courses in one query, or of all courses without any.
+func (s *Service) cohorts(ctx context.Context, courses ...string) (cohorts, error) {
+	if courses == nil {
+		// a nil slice would be sent as NULL
+		courses = []string{}
+	}
+	rows, err := s.db.GetCohortSizes(ctx, courses)
+	if err != nil {
+		return nil, err
+	}
+	c := cohorts{}
+	for _, row := range rows {
+		if c[row.CourseNumber] == nil {
+			c[row.CourseNumber] = map[string]cohort{}
+		}
+		c[row.CourseNumber][row.Semester] = cohort{evaluations: row.Evaluations, ratings: row.Ratings}
+	}
+	return c, nil
+}
+
+// ratings counts the ratings of a course over all semesters.
+func (c cohorts) ratings(course string) int32 {
+	var count int32
+	for _, semester := range c[course] {
+		count += semester.ratings
+	}
+	return count
+}
+
+// semester returns the semester of an evaluation as it may be published, only the year
+// if too few students evaluated the course that semester.
+func (c cohorts) semester(course string, semester pgtype.Text) pgtype.Text {
+	if !semester.Valid || publishable(c[course][semester.String].evaluations) {
+		return semester
+	}
+	year := coarseSemester(semester.String)
+	return pgtype.Text{String: year, Valid: year != ""}
+}
+
+// anonymizeEvaluations coarsens the semesters of published evaluations and leaves out the
+// ratings of courses with too few of them.
+func (s *Service) anonymizeEvaluations(ctx context.Context, rows []sql.GetAllTheDataRow) ([]sql.GetAllTheDataRow, error) {
+	c, err := s.cohorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		row := &rows[i]
+		row.Semester = c.semester(row.CourseNumber, row.Semester)
+		if !publishable(c.ratings(row.CourseNumber)) {
+			row.Recommended, row.Engaging, row.Difficulty, row.Effort, row.Resources = pgtype.Int4{}, pgtype.Int4{}, pgtype.Int4{}, pgtype.Int4{}, pgtype.Int4{}
+			row.HoursPerWeek = pgtype.Float8{}
+		}
+	}
+	return rows, nil
+}
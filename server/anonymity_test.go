@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const testCourse = "252-0027-00L"
+
+func TestCohortsSemester(t *testing.T) {
+	if anonymityThreshold < 2 {
+		t.Skip("anonymity protection is off")
+	}
+	c := cohorts{testCourse: {"23HS": {evaluations: anonymityThreshold, ratings: 1}, "24FS": {evaluations: 1, ratings: 1}}}
+	tests := []struct {
+		semester pgtype.Text
+		want     string
+	}{
+		{pgtype.Text{String: "23HS", Valid: true}, `"23HS"`},
+		{pgtype.Text{String: "24FS", Valid: true}, `"2024"`},
+		{pgtype.Text{}, `null`},
+	}
+	for _, tt := range tests {
+		assertJSON(t, textPtr(c.semester(testCourse, tt.semester)), tt.want)
+	}
+	if got := c.ratings(testCourse); got != 2 {
+		t.Errorf("ratings = %d, want 2", got)
+	}
+}
+
+func dimensionRows() ([]sql.GetCourseDimensionAveragesRow, []sql.GetCourseDimensionDistributionRow, []sql.GetCourseDimensionCommentsRow) {
+	rows := []sql.GetCourseDimensionAveragesRow{{
+		Key: "exam_fairness", ScaleMin: 1, ScaleMax: 3,
+		Average: pgtype.Numeric{Int: big.NewInt(2), Valid: true}, Count: 1,
+	}}
+	distribution := []sql.GetCourseDimensionDistributionRow{{DimensionKey: "exam_fairness", Value: 2, Count: 1}}
+	comments := []sql.GetCourseDimensionCommentsRow{{
+		DimensionKey: "exam_fairness", Value: 2, Comment: pgtype.Text{String: "Fair", Valid: true},
+		Semester: pgtype.Text{String: "24FS", Valid: true},
+	}}
+	return rows, distribution, comments
+}
+
+func TestDimensionAveragesHideSingleRatings(t *testing.T) {
+	rows, distribution, comments := dimensionRows()
+	c := cohorts{testCourse: {"24FS": {evaluations: 1, ratings: 1}}}
+
+	averages := dimensionAverages(testCourse, c, rows, distribution, comments)
+	if averages[0].Average != nil || averages[0].Count != 0 || len(averages[0].Comments) != 0 {
+		t.Errorf("got %+v, a single rating must not be shown through its average or comment", averages[0])
+	}
+	assertJSON(t, averages[0].Distribution, `[0,0,0]`)
+}
+
+func TestDimensionAveragesCoarsenCommentSemesters(t *testing.T) {
+	if anonymityThreshold < 2 {
+		t.Skip("anonymity protection is off")
+	}
+	rows, distribution, comments := dimensionRows()
+	// enough ratings for the course, but too few evaluations in 24FS
+	c := cohorts{testCourse: {"23HS": {evaluations: anonymityThreshold, ratings: anonymityThreshold}, "24FS": {evaluations: 1, ratings: 1}}}
+
+	averages := dimensionAverages(testCourse, c, rows, distribution, comments)
+	assertJSON(t, averages[0].Distribution, `[0,1,0]`)
+	assertJSON(t, averages[0].Comments, `[{"value":2,"comment":"Fair","semester":"2024","date":null}]`)
+}
+
+func TestDepartmentStatsHideSingleRatings(t *testing.T) {
+	if anonymityThreshold < 2 {
+		t.Skip("anonymity protection is off")
+	}
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	seedContributions(t, pool, "alice")
+	execSQL(t, pool, "INSERT INTO departments (code) VALUES ('D-INFK')")
+	execSQL(t, pool, "INSERT INTO department_prefixes (prefix, department_code) VALUES ('252', 'D-INFK')")
+
+	stats, err := svc.DepartmentStats(context.Background(), "D-INFK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Dimensions) != 0 || stats.Counts.Total != 0 {
+		t.Errorf("got %+v, a single rating must not be shown through the department", stats)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidBanKind = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid kind, use full, write or shadow"}
+	ErrBanReason      = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "A reason is required"}
+	ErrInvalidExpiry  = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid expiry, use a future RFC 3339 time"}
+	ErrBanNotFound    = &ServiceError{Status: fiber.StatusNotFound, Message: "User not banned"}
+)
+
+// Bans and lifted bans are written to the event log, which is the audit log.
+const (
+	actionBanUser   = "ban_user"
+	actionUnbanUser = "unban_user"
+)
+
+func toUserBanDTO(row sql.UserBan) api.UserBan {
+	return api.UserBan{
+		UserID:    row.UserID,
+		Kind:      row.Kind,
+		Reason:    row.Reason,
+		CreatedAt: row.CreatedAt.Time.Format(time.RFC3339),
+		ExpiresAt: timestamptzPtr(row.ExpiresAt),
+		Active:    !row.ExpiresAt.Valid || row.ExpiresAt.Time.After(time.Now()),
+	}
+}
+
+// errBanned tells a banned user why and until when.
+func errBanned(ban sql.UserBan) error {
+	message := "Banned: " + ban.Reason
+	if ban.ExpiresAt.Valid {
+		message = fmt.Sprintf("Banned until %s: %s", ban.ExpiresAt.Time.Format(time.RFC3339), ban.Reason)
+	}
+	return &ServiceError{Status: fiber.StatusForbidden, Message: message}
+}
+
+// CheckBan rejects users with a full ban, and those with a write ban if the request
+// changes anything. Shadow bans pass, their submissions are rejected when stored.
+func (s *Service) CheckBan(ctx context.Context, userID string, write bool) error {
+	ban, err := s.db.GetUserBan(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ban.Kind == api.BanFull || (ban.Kind == api.BanWrite && write) {
+		return errBanned(ban)
+	}
+	return nil
+}
+
+// shadowBanned reports whether the author of an evaluation is shadow banned.
+func (s *Service) shadowBanned(ctx context.Context, evalID int32) (bool, error) {
+	kind, err := s.db.GetEvaluationBan(ctx, evalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return kind == api.BanShadow, err
+}
+
+func (s *Service) UserBans(ctx context.Context) ([]api.UserBan, error) {
+	rows, err := s.db.GetUserBans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapAll(rows, toUserBanDTO), nil
+}
+
+// BanUser bans a user, given by their unique_id or pseudonym, replacing an earlier ban.
+// Without an expiry the ban lasts until it is lifted.
+func (s *Service) BanUser(ctx context.Context, adminID, userID string, body api.UserBanBody) (api.UserBan, error) {
+	reason := strings.TrimSpace(body.Reason)
+	if body.Kind != api.BanFull && body.Kind != api.BanWrite && body.Kind != api.BanShadow {
+		return api.UserBan{}, ErrInvalidBanKind
+	}
+	if reason == "" {
+		return api.UserBan{}, ErrBanReason
+	}
+	var expiresAt pgtype.Timestamptz
+	if body.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *body.ExpiresAt)
+		if err != nil || !t.After(time.Now()) {
+			return api.UserBan{}, ErrInvalidExpiry
+		}
+		expiresAt = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	userID, err := storedUserID(userID)
+	if err != nil {
+		return api.UserBan{}, err
+	}
+	if err := s.EnsureUser(ctx, userID); err != nil {
+		return api.UserBan{}, err
+	}
+
+	info := fmt.Sprintf("user=%s kind=%s", userID, body.Kind)
+	if expiresAt.Valid {
+		info += " expires=" + expiresAt.Time.Format(time.RFC3339)
+	}
+	var ban sql.UserBan
+	err = s.inTx(ctx, func(db *sql.Queries) error {
+		var err error
+		ban, err = db.SetUserBan(ctx, sql.SetUserBanParams{UserID: userID, Kind: body.Kind, Reason: reason, ExpiresAt: expiresAt})
+		if err != nil {
+			return err
+		}
+		return db.LogEvent(ctx, sql.LogEventParams{
+			UserID: pgtype.Text{String: adminID, Valid: true},
+			Action: actionBanUser,
+			Info:   pgtype.Text{String: info + " reason=" + reason, Valid: true},
+		})
+	})
+	if err != nil {
+		return api.UserBan{}, err
+	}
+	return toUserBanDTO(ban), nil
+}
+
+// UnbanUser lifts the ban of a user, expired or not. Lifting a shadow ban counts the
+// ratings given under it and puts the silently rejected reviews back into the queue.
+func (s *Service) UnbanUser(ctx context.Context, adminID, userID string) (api.UserBan, error) {
+	userID, err := storedUserID(userID)
+	if err != nil {
+		return api.UserBan{}, err
+	}
+	var ban sql.UserBan
+	var ratings, reviews int64
+	err = s.inTx(ctx, func(db *sql.Queries) error {
+		var err error
+		ban, err = db.DeleteUserBan(ctx, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrBanNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if ban.Kind == api.BanShadow {
+			if ratings, err = db.UnshadowUserRatings(ctx, userID); err != nil {
+				return err
+			}
+			if reviews, err = db.UnshadowUserReviews(ctx, userID); err != nil {
+				return err
+			}
+		}
+		return db.LogEvent(ctx, sql.LogEventParams{
+			UserID: pgtype.Text{String: adminID, Valid: true},
+			Action: actionUnbanUser,
+			Info:   pgtype.Text{String: fmt.Sprintf("user=%s kind=%s", userID, ban.Kind), Valid: true},
+		})
+	})
+	if err != nil {
+		return api.UserBan{}, err
+	}
+	if ratings > 0 {
+		s.publish(EventRatingChanged)
+	}
+	if reviews > 0 {
+		s.publish(EventReviewChanged)
+		SendDiscordMessage("Review to review: https://coursereview.ch/admin", "", 16712959)
+	}
+	return toUserBanDTO(ban), nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"coursereview/app/generated/sql"
+)
+
+func TestBanExempt(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/v1/me/exports", true},
+		{"/v1/me/exports/abc", true},
+		{"/v1/me/deletion", true},
+		{"/auth/export", true},
+		{"/auth/export/abc", true},
+		{"/auth/deleteAccount", true},
+		{"/v1/me/evaluations", false},
+		{"/auth/getUserData", false},
+		{"/auth/insertReview", false},
+	}
+	for _, tt := range tests {
+		if got := banExempt(tt.path); got != tt.want {
+			t.Errorf("banExempt(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShadowBannedRatingIsStoredHidden(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "mallory")
+	execSQL(t, pool, "DELETE FROM ratings WHERE evaluation_id = $1", evaluation)
+	execSQL(t, pool, "INSERT INTO user_bans (user_id, kind, reason) VALUES ('mallory', 'shadow', 'rating spam')")
+
+	created, err := svc.SetRating(context.Background(), evaluation, Ratings{Values: map[string]int32{"exam_fairness": 1}})
+	if err != nil || !created {
+		t.Fatalf("got %v, %v, want the rating to seem created", created, err)
+	}
+
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM ratings WHERE evaluation_id = $1 AND shadowed", evaluation); n != 1 {
+		t.Error("rating was not stored as shadowed")
+	}
+	if n := countRows(t, pool, "SELECT rating_count FROM course_stats WHERE course_number = '252-0027-00L'"); n != 0 {
+		t.Errorf("course stats count %d ratings, want the shadowed one left out", n)
+	}
+	values, err := svc.courseDimensionValues(context.Background(), []string{"252-0027-00L"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values.of("252-0027-00L")) != 0 {
+		t.Errorf("dimension averages include the shadowed rating: %v", values.of("252-0027-00L"))
+	}
+}
+
+func TestUnbanRestoresShadowedContributions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	ctx := context.Background()
+	const user = "mallory-noAuth"
+	evaluation := seedContributions(t, pool, user)
+	execSQL(t, pool, "DELETE FROM ratings WHERE evaluation_id = $1", evaluation)
+	execSQL(t, pool, "INSERT INTO user_bans (user_id, kind, reason) VALUES ($1, 'shadow', 'rating spam')", user)
+
+	if _, err := svc.SetRating(ctx, evaluation, Ratings{Values: map[string]int32{"exam_fairness": 1}, Comments: map[string]string{"exam_fairness": "Unfair"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SetReview(ctx, evaluation, "Terrible"); err != nil {
+		t.Fatal(err)
+	}
+	comments, err := svc.PendingRatingComments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || !comments[0].Shadowed {
+		t.Errorf("got %+v, want the shadowed comment in the queue", comments)
+	}
+	data, err := svc.UserData(ctx, user)
+	if err != nil || len(data) != 1 || data[0].Published.Status != sql.StatusPending {
+		t.Errorf("got %+v, %v, want the shadowed review to look pending to its author", data, err)
+	}
+
+	if _, err := svc.UnbanUser(ctx, "admin", user); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM reviews WHERE evaluation_id = $1 AND published = 'pending' AND NOT shadowed", evaluation); n != 1 {
+		t.Error("the silently rejected review is not back in the queue")
+	}
+	if n := countRows(t, pool, "SELECT rating_count FROM course_stats WHERE course_number = $1", testCourse); n != 1 {
+		t.Errorf("course stats count %d ratings, want the one given under the ban", n)
+	}
+}
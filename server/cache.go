@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// cacheMaxEntries bounds the memory used by query string variations
+	cacheMaxEntries = 2000
+	// cacheMaxAge is a safety net for changes that bypass the service, like manual db edits
+	cacheMaxAge = 10 * time.Minute
+)
+
+type cacheEntry struct {
+	body        []byte
+	etag        string
+	contentType string
+	events      []Event
+	stored      time.Time
+}
+
+// responseCache keeps the answers of public read routes in memory until an event they
+// depend on is published.
+type responseCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	// generation counts invalidations, an answer computed across one isn't stored
+	generation uint64
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: map[string]cacheEntry{}}
+}
+
+// Invalidate drops every entry depending on the event.
+func (rc *responseCache) Invalidate(event Event) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	for key, entry := range rc.entries {
+		for _, e := range entry.events {
+			if e == event {
+				delete(rc.entries, key)
+				break
+			}
+		}
+	}
+}
+
+func (rc *responseCache) get(key string) (cacheEntry, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	entry, ok := rc.entries[key]
+	if !ok || time.Since(entry.stored) > cacheMaxAge {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (rc *responseCache) currentGeneration() uint64 {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.generation
+}
+
+// set stores the entry unless an invalidation happened since generation, the answer
+// might have been computed from data that changed meanwhile.
+func (rc *responseCache) set(key string, entry cacheEntry, generation uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.generation != generation {
+		return
+	}
+	if len(rc.entries) >= cacheMaxEntries {
+		for k := range rc.entries {
+			delete(rc.entries, k)
+			if len(rc.entries) < cacheMaxEntries/2 {
+				break
+			}
+		}
+	}
+	rc.entries[key] = entry
+}
+
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cached serves GET requests from the cache, answering 304 if the client already has the
+// current version. maxAge is what clients may use without asking again.
+func (rc *responseCache) cached(maxAge time.Duration, events ...Event) fiber.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+		key := urlWithoutToken(c)
+
+		entry, hit := rc.get(key)
+		if !hit {
+			generation := rc.currentGeneration()
+			if err := c.Next(); err != nil {
+				return err
+			}
+			if c.Response().StatusCode() != fiber.StatusOK {
+				return nil
+			}
+			body := append([]byte(nil), c.Response().Body()...)
+			entry = cacheEntry{
+				body:        body,
+				etag:        strongETag(body),
+				contentType: string(c.Response().Header.ContentType()),
+				events:      events,
+				stored:      time.Now(),
+			}
+			rc.set(key, entry, generation)
+		}
+
+		c.Set(fiber.HeaderETag, entry.etag)
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		if etagMatches(c.Get(fiber.HeaderIfNoneMatch), entry.etag) {
+			c.Response().ResetBody()
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		if hit {
+			c.Set(fiber.HeaderContentType, entry.contentType)
+			return c.Status(fiber.StatusOK).Send(entry.body)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCacheHitKeepsDeprecationHeaders(t *testing.T) {
+	rc := newResponseCache()
+	app := fiber.New()
+	app.Get("/stats", deprecated("/v1/stats"), rc.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"reviews": 1})
+	})
+
+	for _, attempt := range []string{"miss", "hit"} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/stats", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Deprecation") != "true" || resp.Header.Get("Link") == "" {
+			t.Errorf("%s: status %d, headers %v", attempt, resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+func TestCacheDropsAnswerInvalidatedWhileComputed(t *testing.T) {
+	rc := newResponseCache()
+	app := fiber.New()
+	computed := 0
+	app.Get("/stats", rc.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		computed++
+		if computed == 1 {
+			// a review is written while the first answer is read from the db
+			rc.Invalidate(EventReviewChanged)
+		}
+		return c.JSON(fiber.Map{"computed": computed})
+	})
+
+	for range 3 {
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/stats", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if computed != 2 {
+		t.Errorf("computed %d times, want 2: the stale first answer must not be cached", computed)
+	}
+}
+
+func TestCacheKeyLeavesOutToken(t *testing.T) {
+	rc := newResponseCache()
+	app := fiber.New()
+	app.Get("/stats", rc.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"reviews": 1})
+	})
+
+	for _, target := range []string{"/stats?page=2&token=header.claims.signature", "/stats?token=other.claims.signature&page=2"} {
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.entries) != 1 {
+		t.Fatalf("got %d entries, want one for both tokens", len(rc.entries))
+	}
+	for key := range rc.entries {
+		if key != "/stats?page=2" {
+			t.Errorf("got key %q, want /stats?page=2", key)
+		}
+	}
+}
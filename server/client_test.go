@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/client"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// The client lives in its own package and can't import package main, so it is tested
+// against the real routes from here. The service has no database, every query panics and
+// recover turns that into a 500, which is all the tests below need.
+
+type appDoer struct {
+	app   *fiber.App
+	calls atomic.Int32
+}
+
+func (d *appDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls.Add(1)
+	return d.app.Test(req, -1)
+}
+
+func newRoutedClient(t *testing.T, opts ...client.Option) (*client.Client, *appDoer) {
+	t.Helper()
+	app := fiber.New()
+	app.Use(recover.New())
+	svc := NewService(nil, nil)
+	cache := newResponseCache()
+	registerV1Routes(app, svc, cache)
+	registerLegacyRoutes(app, svc, cache)
+
+	doer := &appDoer{app: app}
+	opts = append([]client.Option{client.WithHTTPClient(doer), client.WithRetries(2, time.Millisecond)}, opts...)
+	return client.New("http://api.test", opts...), doer
+}
+
+func apiStatus(err error) int {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	return 0
+}
+
+func TestClientSendsTokenToBearerAuth(t *testing.T) {
+	c, _ := newRoutedClient(t)
+
+	_, err := c.MyEvaluations(context.Background())
+	if apiStatus(err) != fiber.StatusUnauthorized || err.(*client.Error).Message != "Missing bearer token" {
+		t.Fatalf("without token: got %v", err)
+	}
+	// the token reaches the middleware, which rejects it as malformed instead of missing
+	_, err = c.WithToken("not-a-jwt").MyEvaluations(context.Background())
+	if apiStatus(err) != fiber.StatusUnauthorized || err.(*client.Error).Message != "invalid JWT token" {
+		t.Fatalf("with token: got %v", err)
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	c, doer := newRoutedClient(t)
+
+	_, err := c.Stats(context.Background())
+	if apiStatus(err) != fiber.StatusInternalServerError {
+		t.Fatalf("got %v, want 500", err)
+	}
+	if calls := doer.calls.Load(); calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+}
+
+func TestClientDoesNotRetrySubmissions(t *testing.T) {
+	c, doer := newRoutedClient(t)
+
+	_, err := c.SubmitEvaluation(context.Background(), api.EvaluationSubmission{CourseNumber: "252-0027-00L", Semester: "HS23", Review: "Good"})
+	if apiStatus(err) != fiber.StatusUnprocessableEntity {
+		t.Fatalf("without anonymous id: got %v, want 422", err)
+	}
+	doer.calls.Store(0)
+	_, err = c.SubmitEvaluation(context.Background(), api.EvaluationSubmission{AnonymousID: "a", CourseNumber: "252-0027-00L", Semester: "HS23", Review: "Good"})
+	if apiStatus(err) != fiber.StatusInternalServerError {
+		t.Fatalf("got %v, want 500", err)
+	}
+	if calls := doer.calls.Load(); calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
+
+func TestClientStopsBackoffOnCancel(t *testing.T) {
+	c, doer := newRoutedClient(t, client.WithRetries(2, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := c.Stats(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if calls := doer.calls.Load(); calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
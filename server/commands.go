@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Reconcile rebuilds course_stats from the base tables and reports every course whose
+// stored aggregates drifted. The triggers keep the table up to date, so drift points to
+// manual edits or a bug in them. Run it with `go run . reconcile`.
+func Reconcile() error {
+	ctx := context.Background()
+	pool, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	db := sql.New(tx)
+
+	drift, err := db.GetCourseStatsDrift(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range drift {
+		fmt.Printf("%s: %s\n", d.CourseNumber, strings.Join(driftedColumns(d), ", "))
+	}
+
+	if err := db.DeleteCourseStats(ctx); err != nil {
+		return err
+	}
+	if err := db.RebuildCourseStats(ctx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt course_stats, %d courses drifted\n", len(drift))
+	return nil
+}
+
+// driftedColumns lists every course_stats column of the row whose stored value differs
+// from the computed one, as "column stored -> computed".
+func driftedColumns(d sql.GetCourseStatsDriftRow) []string {
+	columns := []struct {
+		name             string
+		stored, computed any
+	}{
+		{"rating_count", d.StoredRatingCount, d.ComputedRatingCount},
+		{"recommended_sum", d.StoredRecommendedSum, d.ComputedRecommendedSum},
+		{"recommended_count", d.StoredRecommendedCount, d.ComputedRecommendedCount},
+		{"engaging_sum", d.StoredEngagingSum, d.ComputedEngagingSum},
+		{"engaging_count", d.StoredEngagingCount, d.ComputedEngagingCount},
+		{"difficulty_sum", d.StoredDifficultySum, d.ComputedDifficultySum},
+		{"difficulty_count", d.StoredDifficultyCount, d.ComputedDifficultyCount},
+		{"effort_sum", d.StoredEffortSum, d.ComputedEffortSum},
+		{"effort_count", d.StoredEffortCount, d.ComputedEffortCount},
+		{"resources_sum", d.StoredResourcesSum, d.ComputedResourcesSum},
+		{"resources_count", d.StoredResourcesCount, d.ComputedResourcesCount},
+		{"review_count", d.StoredReviewCount, d.ComputedReviewCount},
+		{"verified_review_count", d.StoredVerifiedReviewCount, d.ComputedVerifiedReviewCount},
+		{"latest_activity", driftDate(d.StoredLatestActivity), driftDate(d.ComputedLatestActivity)},
+	}
+	var drifted []string
+	for _, c := range columns {
+		stored, computed := fmt.Sprint(c.stored), fmt.Sprint(c.computed)
+		if stored != computed {
+			drifted = append(drifted, fmt.Sprintf("%s %s -> %s", c.name, stored, computed))
+		}
+	}
+	if len(drifted) == 0 {
+		// the row was missing, all its values were zero
+		drifted = append(drifted, "missing row")
+	}
+	return drifted
+}
+
+func driftDate(d pgtype.Date) string {
+	if s := datePtr(d); s != nil {
+		return *s
+	}
+	return "none"
+}
+
+// refreshSimilarities runs the similarity job once, `go run . similarities`.
+func refreshSimilarities() error {
+	pool, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	svc := NewService(pool, log.Default())
+	if err := svc.RefreshSimilarities(context.Background()); err != nil {
+		return err
+	}
+	if err := svc.RefreshTextSimilarities(context.Background()); err != nil {
+		return err
+	}
+	fmt.Println("Refreshed course similarities")
+	return nil
+}
+
+func runCommand(name string) {
+	switch name {
+	case "reconcile":
+		if err := Reconcile(); err != nil {
+			fmt.Fprintf(os.Stderr, "Reconcile failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "similarities":
+		if err := refreshSimilarities(); err != nil {
+			fmt.Fprintf(os.Stderr, "Refreshing course similarities failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "pseudonymize":
+		if err := Pseudonymize(); err != nil {
+			fmt.Fprintf(os.Stderr, "Pseudonymizing users failed: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", name)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDriftedColumns(t *testing.T) {
+	day := pgtype.Date{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	tests := []struct {
+		name string
+		row  sql.GetCourseStatsDriftRow
+		want []string
+	}{
+		{
+			"sum only",
+			sql.GetCourseStatsDriftRow{StoredRecommendedSum: 12, ComputedRecommendedSum: 9, StoredRecommendedCount: 3, ComputedRecommendedCount: 3},
+			[]string{"recommended_sum 12 -> 9"},
+		},
+		{
+			"counts and activity",
+			sql.GetCourseStatsDriftRow{StoredRatingCount: 1, ComputedRatingCount: 2, StoredEffortCount: 1, ComputedEffortCount: 2, ComputedLatestActivity: day},
+			[]string{"rating_count 1 -> 2", "effort_count 1 -> 2", "latest_activity none -> 2024-03-01"},
+		},
+		{
+			"missing empty row",
+			sql.GetCourseStatsDriftRow{},
+			[]string{"missing row"},
+		},
+	}
+	for _, tt := range tests {
+		if got := driftedColumns(tt.row); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
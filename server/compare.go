@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	compareMaxCourses    = 5
+	compareSnippetLength = 280
+	compareSnippetCount  = 3
+)
+
+var ErrTooManyCourses = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Too many courses, compare at most 5"}
+
+// CompareCourses loads the comparison data of all courses with one query and returns
+// them in the requested order.
+func (s *Service) CompareCourses(ctx context.Context, courseNumbers []string) ([]api.CourseComparison, error) {
+	var numbers []string
+	for _, number := range courseNumbers {
+		number = strings.TrimSpace(number)
+		if number != "" && !slices.Contains(numbers, number) {
+			numbers = append(numbers, number)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil, ErrMissingCourse
+	}
+	if len(numbers) > compareMaxCourses {
+		return nil, ErrTooManyCourses
+	}
+
+	rows, err := s.db.CompareCourses(ctx, sql.CompareCoursesParams{
+		CourseNumbers: numbers,
+		SnippetLength: compareSnippetLength,
+		SnippetCount:  compareSnippetCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byNumber := map[string]sql.CompareCoursesRow{}
+	for _, row := range rows {
+		byNumber[row.CourseNumber] = row
+	}
+	values, err := s.courseDimensionValues(ctx, numbers)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.cohorts(ctx, numbers...)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisons := make([]api.CourseComparison, 0, len(numbers))
+	for _, number := range numbers {
+		row, ok := byNumber[number]
+		if !ok {
+			return nil, &ServiceError{Status: fiber.StatusNotFound, Message: "Course not found: " + number}
+		}
+		if !publishable(row.RatingCount) {
+			row = hideComparisonRatings(row)
+		}
+		comparison, err := toCourseComparisonDTO(row)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Dimensions = values.of(number)
+		for i, review := range comparison.LatestReviews {
+			if review.Semester != nil {
+				comparison.LatestReviews[i].Semester = textPtr(c.semester(number, pgtype.Text{String: *review.Semester, Valid: true}))
+			}
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons, nil
+}
+
+// hideComparisonRatings leaves out the ratings of a course with too few of them.
+func hideComparisonRatings(row sql.CompareCoursesRow) sql.CompareCoursesRow {
+	return sql.CompareCoursesRow{
+		CourseNumber:  row.CourseNumber,
+		CourseName:    row.CourseName,
+		Ects:          row.Ects,
+		Semesters:     row.Semesters,
+		Distributions: []byte("{}"),
+		LatestReviews: row.LatestReviews,
+		Lecturers:     row.Lecturers,
+	}
+}
+
+func toCourseComparisonDTO(row sql.CompareCoursesRow) (api.CourseComparison, error) {
+	comparison := api.CourseComparison{
+		CourseNumber: row.CourseNumber,
+		CourseName:   row.CourseName,
+		ECTS:         numericPtr(row.Ects),
+		Semesters:    row.Semesters,
+		RatingAvg: toRatingAvgDTO(sql.GetRatingsAvgRow{
+			Recommended: row.Recommended,
+			Engaging:    row.Engaging,
+			Difficulty:  row.Difficulty,
+			Effort:      row.Effort,
+			Resources:   row.Resources,
+		}),
+		Counts: api.RatingCounts{
+			Total:       row.RatingCount,
+			Recommended: row.RecommendedCount,
+			Engaging:    row.EngagingCount,
+			Difficulty:  row.DifficultyCount,
+			Effort:      row.EffortCount,
+			Resources:   row.ResourcesCount,
+		},
+	}
+	slices.SortFunc(comparison.Semesters, compareSemesters)
+	if err := json.Unmarshal(row.Distributions, &comparison.Distributions); err != nil {
+		return comparison, err
+	}
+	if err := json.Unmarshal(row.LatestReviews, &comparison.LatestReviews); err != nil {
+		return comparison, err
+	}
+	if err := json.Unmarshal(row.Lecturers, &comparison.Lecturers); err != nil {
+		return comparison, err
+	}
+	return comparison, nil
+}
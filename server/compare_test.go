@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCompareSemesters(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"23FS", "23HS", -1},
+		{"23HS", "23FS", 1},
+		{"23HS", "24FS", -1},
+		{"2023W", "23HS", 0},
+		{"2024S", "23HS", 1},
+		{"23HS", "unknown", -1},
+		{"unknown", "23HS", 1},
+		{"a", "b", -1},
+		{"b", "b", 0},
+	}
+	for _, tt := range tests {
+		if got := compareSemesters(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSemesters(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestToCourseComparisonDTO(t *testing.T) {
+	row := sql.CompareCoursesRow{
+		CourseNumber:     "252-0027-00L",
+		CourseName:       "Einführung in die Programmierung",
+		Ects:             pgtype.Numeric{Int: big.NewInt(7), Valid: true},
+		Semesters:        []string{"24FS", "unknown", "22HS", "23HS"},
+		RatingCount:      4,
+		Recommended:      pgtype.Numeric{Int: big.NewInt(45), Exp: -1, Valid: true},
+		RecommendedCount: 4,
+		Distributions:    []byte(`{"exam_fairness": [0, 1, 0, 2, 1]}`),
+		LatestReviews:    []byte(`[{"review": "Good exercises", "semester": "23HS", "date": "2024-01-10", "annotation": null}]`),
+		Lecturers:        []byte(`[{"semester": "23HS", "lecturers": [{"id": 1, "name": "Jane Doe"}]}]`),
+	}
+	comparison, err := toCourseComparisonDTO(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"22HS", "23HS", "24FS", "unknown"}; !slices.Equal(comparison.Semesters, want) {
+		t.Errorf("semesters %v, want %v", comparison.Semesters, want)
+	}
+	if comparison.ECTS == nil || *comparison.ECTS != 7 {
+		t.Errorf("ects %v, want 7", comparison.ECTS)
+	}
+	if comparison.Recommended == nil || *comparison.Recommended != 4.5 || comparison.Counts.Recommended != 4 {
+		t.Errorf("recommended %v of %d ratings, want 4.5 of 4", comparison.Recommended, comparison.Counts.Recommended)
+	}
+	if want := []int64{0, 1, 0, 2, 1}; !slices.Equal(comparison.Distributions["exam_fairness"], want) {
+		t.Errorf("distribution %v, want %v", comparison.Distributions["exam_fairness"], want)
+	}
+	if len(comparison.LatestReviews) != 1 || comparison.LatestReviews[0].Review != "Good exercises" || comparison.LatestReviews[0].Annotation != nil {
+		t.Errorf("latest reviews %+v", comparison.LatestReviews)
+	}
+	if len(comparison.Lecturers) != 1 || comparison.Lecturers[0].Lecturers[0].Name != "Jane Doe" {
+		t.Errorf("lecturers %+v", comparison.Lecturers)
+	}
+
+	row.LatestReviews = []byte(`{`)
+	if _, err := toCourseComparisonDTO(row); err == nil {
+		t.Error("broken JSON was accepted")
+	}
+}
+
+func TestHideComparisonRatings(t *testing.T) {
+	row := hideComparisonRatings(sql.CompareCoursesRow{
+		CourseNumber:     "252-0027-00L",
+		Semesters:        []string{"23HS"},
+		RatingCount:      1,
+		Recommended:      pgtype.Numeric{Int: big.NewInt(5), Valid: true},
+		RecommendedCount: 1,
+		Distributions:    []byte(`{"exam_fairness": [0, 0, 1, 0, 0]}`),
+		LatestReviews:    []byte(`[]`),
+		Lecturers:        []byte(`[]`),
+	})
+	comparison, err := toCourseComparisonDTO(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comparison.Recommended != nil || comparison.Counts.Total != 0 || comparison.Counts.Recommended != 0 {
+		t.Errorf("ratings kept: %v of %+v", comparison.Recommended, comparison.Counts)
+	}
+	if len(comparison.Distributions) != 0 {
+		t.Errorf("distributions kept: %v", comparison.Distributions)
+	}
+	if comparison.CourseNumber != "252-0027-00L" || len(comparison.Semesters) != 1 {
+		t.Errorf("course data lost: %+v", comparison)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	courseNumberPattern = regexp.MustCompile(`^(\d{3})-(\d{4})-([A-Z0-9]{3})$`)
+	coursePrefixPattern = regexp.MustCompile(`^\d{3}$`)
+)
+
+var ErrInvalidCourseNumber = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid course number, expected a format like 263-3010-00L"}
+
+// CourseNumber is a VVZ course number like 263-3010-00L. The prefix identifies the
+// teaching unit and with it the department, the suffix the variant of the course.
+type CourseNumber struct {
+	Prefix string // 263
+	Number string // 3010
+	Suffix string // 00L
+}
+
+func ParseCourseNumber(s string) (CourseNumber, error) {
+	match := courseNumberPattern.FindStringSubmatch(s)
+	if match == nil {
+		return CourseNumber{}, ErrInvalidCourseNumber
+	}
+	return CourseNumber{Prefix: match[1], Number: match[2], Suffix: match[3]}, nil
+}
+
+func (n CourseNumber) String() string {
+	return n.Prefix + "-" + n.Number + "-" + n.Suffix
+}
+
+// validCoursePrefix reports whether s could be the prefix of a course number.
+func validCoursePrefix(s string) bool {
+	return coursePrefixPattern.MatchString(s)
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestParseCourseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want CourseNumber
+		ok   bool
+	}{
+		{"263-3010-00L", CourseNumber{Prefix: "263", Number: "3010", Suffix: "00L"}, true},
+		{"252-0027-00L", CourseNumber{Prefix: "252", Number: "0027", Suffix: "00L"}, true},
+		{"401-0131-00V", CourseNumber{Prefix: "401", Number: "0131", Suffix: "00V"}, true},
+		{"851-0101-86S", CourseNumber{Prefix: "851", Number: "0101", Suffix: "86S"}, true},
+		{"263-3010-00l", CourseNumber{}, false},
+		{"263-301-00L", CourseNumber{}, false},
+		{"2633-010-00L", CourseNumber{}, false},
+		{"263-3010-00L ", CourseNumber{}, false},
+		{"263 3010 00L", CourseNumber{}, false},
+		{"263-3010", CourseNumber{}, false},
+		{"", CourseNumber{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseCourseNumber(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseCourseNumber(%q) = %+v, %v", tt.in, got, err)
+			continue
+		}
+		if tt.ok && got.String() != tt.in {
+			t.Errorf("ParseCourseNumber(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestValidCoursePrefix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"263", true},
+		{"052", true},
+		{"26", false},
+		{"2630", false},
+		{"26a", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validCoursePrefix(tt.in); got != tt.want {
+			t.Errorf("validCoursePrefix(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
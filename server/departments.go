@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrDepartmentNotFound = &ServiceError{Status: fiber.StatusNotFound, Message: "Department not found"}
+
+func (s *Service) Departments(ctx context.Context) ([]sql.GetDepartmentsRow, error) {
+	return s.db.GetDepartments(ctx)
+}
+
+func (s *Service) Department(ctx context.Context, code string) (sql.GetDepartmentRow, error) {
+	department, err := s.db.GetDepartment(ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return department, ErrDepartmentNotFound
+	}
+	return department, err
+}
+
+func (s *Service) DepartmentCourses(ctx context.Context, code string) ([]sql.Course, error) {
+	if _, err := s.Department(ctx, code); err != nil {
+		return nil, err
+	}
+	return s.db.GetDepartmentCourses(ctx, code)
+}
+
+// DepartmentRatingsAvg aggregates the ratings and verified reviews of all courses of the
+// department. The ratings stay unset until the department has anonymityThreshold of them.
+func (s *Service) DepartmentRatingsAvg(ctx context.Context, code string) (sql.GetDepartmentRatingsAvgRow, error) {
+	if _, err := s.Department(ctx, code); err != nil {
+		return sql.GetDepartmentRatingsAvgRow{}, err
+	}
+	row, err := s.db.GetDepartmentRatingsAvg(ctx, code)
+	if err == nil && !publishable(row.RatingCount) {
+		row = sql.GetDepartmentRatingsAvgRow{CourseCount: row.CourseCount, ReviewCount: row.ReviewCount}
+	}
+	return row, err
+}
+
+// SetDepartment creates or renames a department and moves the given course number prefixes
+// to it. Departments left without prefixes are removed.
+func (s *Service) SetDepartment(ctx context.Context, code, name string, prefixes []string) (sql.GetDepartmentRow, error) {
+	if code == "" {
+		return sql.GetDepartmentRow{}, &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Department code missing"}
+	}
+	for _, prefix := range prefixes {
+		if !validCoursePrefix(prefix) {
+			return sql.GetDepartmentRow{}, ErrInvalidDepartment
+		}
+	}
+
+	err := s.db.SetDepartment(ctx, sql.SetDepartmentParams{Code: code, Name: pgtype.Text{String: name, Valid: name != ""}})
+	if err != nil {
+		return sql.GetDepartmentRow{}, err
+	}
+	if len(prefixes) > 0 {
+		if err := s.db.SetDepartmentPrefixes(ctx, sql.SetDepartmentPrefixesParams{Prefixes: prefixes, Code: code}); err != nil {
+			return sql.GetDepartmentRow{}, err
+		}
+		if err := s.db.DeleteEmptyDepartments(ctx, code); err != nil {
+			return sql.GetDepartmentRow{}, err
+		}
+	}
+	s.publish(EventDepartmentChanged)
+	return s.Department(ctx, code)
+}
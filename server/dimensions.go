@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrDimensionNotFound = &ServiceError{Status: fiber.StatusNotFound, Message: "Rating dimension not found"}
+	ErrInvalidDimension  = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid rating dimension, use lowercase letters, digits and underscores"}
+	ErrDimensionLabels   = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Labels in German and English are required"}
+	ErrInvalidScale      = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Scale must lie within 1 and 10, its lowest value below its highest"}
+	ErrCommentTooLong    = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Comments can have at most 280 characters"}
+	ErrCommentNoValue    = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "A comment needs a value for its rating dimension"}
+	ErrCommentNotFound   = &ServiceError{Status: fiber.StatusNotFound, Message: "Comment not found"}
+)
+
+const (
+	ratingCommentMaxLength = 280
+	// verified comments shown per dimension on the course page
+	ratingCommentsPerDimension = 20
+)
+
+var regexDimensionKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// builtInDimensions are mirrored in the columns of ratings. Only the author's own data
+// reads them, the aggregates project them from rating_values in counted_ratings.
+var builtInDimensions = []string{"recommended", "engaging", "difficulty", "effort", "resources"}
+
+// values merges the built-in fields into Values, leaving out everything set to 0.
+func (r Ratings) values() map[string]int32 {
+	values := map[string]int32{}
+	for key, value := range r.Values {
+		if value != 0 {
+			values[key] = value
+		}
+	}
+	for i, value := range []int32{r.Recommended, r.Engaging, r.Difficulty, r.Effort, r.Resources} {
+		if value != 0 {
+			values[builtInDimensions[i]] = value
+		}
+	}
+	return values
+}
+
+// comments trims the comments and drops empty ones. Every comment needs a value for its dimension.
+func (r Ratings) comments(values map[string]int32) (map[string]string, error) {
+	comments := map[string]string{}
+	for key, comment := range r.Comments {
+		comment = strings.TrimSpace(comment)
+		if comment == "" {
+			continue
+		}
+		if _, ok := values[key]; !ok {
+			return nil, ErrCommentNoValue
+		}
+		if utf8.RuneCountInString(comment) > ratingCommentMaxLength {
+			return nil, ErrCommentTooLong
+		}
+		comments[key] = comment
+	}
+	return comments, nil
+}
+
+// checkRatingValues rejects values of unknown or retired dimensions and values outside
+// the scale of their dimension.
+func (s *Service) checkRatingValues(ctx context.Context, values map[string]int32) error {
+	if len(values) == 0 {
+		return nil
+	}
+	dimensions, err := s.db.GetRatingDimensions(ctx, false)
+	if err != nil {
+		return err
+	}
+	active := map[string]sql.RatingDimension{}
+	for _, dimension := range dimensions {
+		active[dimension.Key] = dimension
+	}
+	for key, value := range values {
+		dimension, ok := active[key]
+		if !ok {
+			return &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Unknown or retired rating dimension: " + key}
+		}
+		if value < dimension.ScaleMin || value > dimension.ScaleMax {
+			return ErrRatingsOutOfRange
+		}
+	}
+	return nil
+}
+
+// setRatingValues replaces the values and comments of a rating with the given ones. A new
+// or changed comment waits for moderation again.
+func setRatingValues(ctx context.Context, db *sql.Queries, evalID int32, values map[string]int32, comments map[string]string) error {
+	params := sql.SetRatingValuesParams{EvaluationID: evalID, DimensionKeys: []string{}, Values: []int32{}, Comments: []string{}}
+	for key := range values {
+		params.DimensionKeys = append(params.DimensionKeys, key)
+	}
+	sort.Strings(params.DimensionKeys)
+	for _, key := range params.DimensionKeys {
+		params.Values = append(params.Values, values[key])
+		params.Comments = append(params.Comments, comments[key])
+	}
+	return db.SetRatingValues(ctx, params)
+}
+
+// UserEvaluations returns the evaluations of a user with the values of every dimension they rated.
+func (s *Service) UserEvaluations(ctx context.Context, userID string) ([]api.UserEvaluation, error) {
+	data, err := s.UserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.GetUserRatingValues(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	values := map[int32]map[string]int32{}
+	comments := map[int32]map[string]api.RatingComment{}
+	for _, row := range rows {
+		if values[row.EvaluationID] == nil {
+			values[row.EvaluationID] = map[string]int32{}
+		}
+		values[row.EvaluationID][row.DimensionKey] = row.Value
+		if row.Comment.Valid {
+			if comments[row.EvaluationID] == nil {
+				comments[row.EvaluationID] = map[string]api.RatingComment{}
+			}
+			comments[row.EvaluationID][row.DimensionKey] = api.RatingComment{
+				Comment:          row.Comment.String,
+				Status:           statusPtr(row.CommentStatus),
+				RequestedChanges: textPtr(row.CommentRequestedChanges),
+			}
+		}
+	}
+
+	redactions, err := s.db.GetUserReviewRedactions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	redacted := map[int32]api.ReviewRevision{}
+	for _, row := range redactions {
+		redacted[row.EvaluationID] = toReviewRevisionDTO(row)
+	}
+
+	evaluations := mapAll(data, toUserEvaluationDTO)
+	for i := range evaluations {
+		evaluations[i].RatingValues = values[evaluations[i].EvaluationID]
+		evaluations[i].RatingComments = comments[evaluations[i].EvaluationID]
+		if redaction, ok := redacted[evaluations[i].EvaluationID]; ok {
+			evaluations[i].Redaction = &redaction
+		}
+	}
+	return evaluations, nil
+}
+
+// RatingDimensions lists the dimensions in display order, retired ones only if asked for.
+func (s *Service) RatingDimensions(ctx context.Context, includeRetired bool) ([]sql.RatingDimension, error) {
+	return s.db.GetRatingDimensions(ctx, includeRetired)
+}
+
+// SetRatingDimension adds a dimension or relabels an existing one, which also brings a
+// retired dimension back. The scale of an existing dimension stays, its values would be off.
+func (s *Service) SetRatingDimension(ctx context.Context, params sql.SetRatingDimensionParams) (sql.RatingDimension, error) {
+	params.LabelDe, params.LabelEn = strings.TrimSpace(params.LabelDe), strings.TrimSpace(params.LabelEn)
+	if !regexDimensionKey.MatchString(params.Key) {
+		return sql.RatingDimension{}, ErrInvalidDimension
+	}
+	if params.LabelDe == "" || params.LabelEn == "" {
+		return sql.RatingDimension{}, ErrDimensionLabels
+	}
+	if params.ScaleMin == 0 && params.ScaleMax == 0 {
+		params.ScaleMin, params.ScaleMax = 1, 5
+	}
+	// 0 means "not rated", a scale can't include it
+	if params.ScaleMin < 1 || params.ScaleMax > 10 || params.ScaleMin >= params.ScaleMax {
+		return sql.RatingDimension{}, ErrInvalidScale
+	}
+	dimension, err := s.db.SetRatingDimension(ctx, params)
+	if err != nil {
+		return dimension, err
+	}
+	s.publish(EventDimensionChanged)
+	return dimension, nil
+}
+
+// RetireRatingDimension stops a dimension from being rated and shown, its values are kept.
+func (s *Service) RetireRatingDimension(ctx context.Context, key string) (sql.RatingDimension, error) {
+	dimension, err := s.db.RetireRatingDimension(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dimension, ErrDimensionNotFound
+	}
+	if err != nil {
+		return dimension, err
+	}
+	s.publish(EventDimensionChanged)
+	return dimension, nil
+}
+
+// CourseDimensionAverages returns the average, distribution and latest verified comments
+// of every active dimension of a course. All of them stay empty until the course has
+// anonymityThreshold ratings, a comment shows the value of a single rating.
+func (s *Service) CourseDimensionAverages(ctx context.Context, course string) ([]api.DimensionAvg, error) {
+	rows, err := s.db.GetCourseDimensionAverages(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	distribution, err := s.db.GetCourseDimensionDistribution(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.db.GetCourseDimensionComments(ctx, sql.GetCourseDimensionCommentsParams{CourseNumber: course, PerDimension: ratingCommentsPerDimension})
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.cohorts(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	return dimensionAverages(course, c, rows, distribution, comments), nil
+}
+
+// dimensionAverages puts the rows of CourseDimensionAverages together and anonymizes them
+// like the reviews: nothing below the threshold and coarsened semesters.
+func dimensionAverages(course string, c cohorts, rows []sql.GetCourseDimensionAveragesRow, distribution []sql.GetCourseDimensionDistributionRow, comments []sql.GetCourseDimensionCommentsRow) []api.DimensionAvg {
+	hidden := !publishable(c.ratings(course))
+
+	averages := mapAll(rows, toDimensionAvgDTO)
+	byKey := map[string]*api.DimensionAvg{}
+	for i := range averages {
+		average := &averages[i]
+		if hidden {
+			average.Average, average.Count = nil, 0
+		}
+		average.Distribution = make([]int64, average.ScaleMax-average.ScaleMin+1)
+		average.Comments = []api.DimensionComment{}
+		byKey[average.Key] = average
+	}
+	if hidden {
+		return averages
+	}
+	for _, row := range distribution {
+		// retired dimensions are not listed, values of a former scale are left out
+		if average, ok := byKey[row.DimensionKey]; ok && row.Value >= average.ScaleMin && row.Value <= average.ScaleMax {
+			average.Distribution[row.Value-average.ScaleMin] = row.Count
+		}
+	}
+	for _, row := range comments {
+		if average, ok := byKey[row.DimensionKey]; ok {
+			row.Semester = c.semester(course, row.Semester)
+			average.Comments = append(average.Comments, toDimensionCommentDTO(row))
+		}
+	}
+	return averages
+}
+
+// // // // // // //
+// moderation     //
+// // // // // // //
+
+func (s *Service) PendingRatingComments(ctx context.Context) ([]sql.GetPendingRatingCommentsRow, error) {
+	return s.db.GetPendingRatingComments(ctx)
+}
+
+func (s *Service) VerifyRatingComment(ctx context.Context, evalID int32, dimension string) (sql.RatingValue, error) {
+	comment, err := s.db.VerifyRatingComment(ctx, sql.VerifyRatingCommentParams{EvaluationID: evalID, DimensionKey: dimension})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return comment, ErrCommentNotFound
+	}
+	if err != nil {
+		return comment, err
+	}
+	s.publish(EventRatingChanged)
+	return comment, nil
+}
+
+func (s *Service) RejectRatingComment(ctx context.Context, evalID int32, dimension, requestedChanges string) (sql.RatingValue, error) {
+	comment, err := s.db.RejectRatingComment(ctx, sql.RejectRatingCommentParams{
+		EvaluationID:     evalID,
+		DimensionKey:     dimension,
+		RequestedChanges: pgtype.Text{String: requestedChanges, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return comment, ErrCommentNotFound
+	}
+	if err != nil {
+		return comment, err
+	}
+	s.publish(EventRatingChanged)
+	return comment, nil
+}
+
+// builtInColumn returns the ratings column value of a built-in dimension, NULL if unset.
+func builtInColumn(values map[string]int32, key string) pgtype.Int4 {
+	value, ok := values[key]
+	return pgtype.Int4{Int32: value, Valid: ok}
+}
+
+// // // // // // // // // //
+// aggregates by dimension  //
+// // // // // // // // // //
+
+// dimensionValues holds the averages of the active dimensions by scope, like a course
+// number or a semester, and dimension key.
+type dimensionValues map[string]map[string]api.DimensionValue
+
+func (d dimensionValues) add(scope, key string, average pgtype.Numeric, count int32) {
+	if d[scope] == nil {
+		d[scope] = map[string]api.DimensionValue{}
+	}
+	d[scope][key] = api.DimensionValue{Average: numericPtr(average), Count: count}
+}
+
+// of returns the averages of a scope, an empty map if it has none.
+func (d dimensionValues) of(scope string) map[string]api.DimensionValue {
+	if values, ok := d[scope]; ok {
+		return values
+	}
+	return map[string]api.DimensionValue{}
+}
+
+// activeDimension reports whether key is a dimension that can be rated.
+func (s *Service) activeDimension(ctx context.Context, key string) (bool, error) {
+	dimensions, err := s.db.GetRatingDimensions(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	for _, dimension := range dimensions {
+		if dimension.Key == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// courseDimensionValues loads the averages of the active dimensions of the courses, courses
+// with fewer than anonymityThreshold ratings are left out.
+func (s *Service) courseDimensionValues(ctx context.Context, courses []string) (dimensionValues, error) {
+	rows, err := s.db.GetDimensionAveragesByCourse(ctx, courses)
+	if err != nil {
+		return nil, err
+	}
+	values := dimensionValues{}
+	for _, row := range rows {
+		if publishable(row.RatingCount) {
+			values.add(row.CourseNumber, row.DimensionKey, row.Average, row.Count)
+		}
+	}
+	return values, nil
+}
+
+// RatingAveragesPage is AllRatingsAvg with the averages of every active dimension.
+func (s *Service) RatingAveragesPage(ctx context.Context, filter RatingsAvgFilter) (api.RatingAvgPage, error) {
+	rows, total, err := s.AllRatingsAvg(ctx, filter)
+	if err != nil {
+		return api.RatingAvgPage{}, err
+	}
+	values, err := s.courseDimensionValues(ctx, mapAll(rows, func(row sql.GetAllRatingsAvgRow) string { return row.CourseNumber }))
+	if err != nil {
+		return api.RatingAvgPage{}, err
+	}
+	items := mapAll(rows, toCourseRatingAvgDTO)
+	for i := range items {
+		items[i].Dimensions = values.of(items[i].CourseNumber)
+	}
+	return api.RatingAvgPage{Total: total, Items: items}, nil
+}
+
+// CourseRatingAverage is RatingsAvg with the averages of every active dimension.
+func (s *Service) CourseRatingAverage(ctx context.Context, course string) (api.RatingAvg, error) {
+	row, err := s.RatingsAvg(ctx, course)
+	if err != nil {
+		return api.RatingAvg{}, err
+	}
+	values, err := s.courseDimensionValues(ctx, []string{course})
+	if err != nil {
+		return api.RatingAvg{}, err
+	}
+	average := toRatingAvgDTO(row)
+	average.Dimensions = values.of(course)
+	return average, nil
+}
+
+// SemesterRatingAverages is RatingsBySemester with the averages of every active dimension.
+func (s *Service) SemesterRatingAverages(ctx context.Context, course string) ([]api.SemesterRatingAvg, error) {
+	rows, err := s.RatingsBySemester(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	dimensionRows, err := s.db.GetSemesterDimensionAverages(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	values := dimensionValues{}
+	for _, row := range dimensionRows {
+		values.add(row.Semester, row.DimensionKey, row.Average, row.Count)
+	}
+	averages := mapAll(rows, toSemesterRatingAvgDTO)
+	for i := range averages {
+		// only the published semesters are looked up, evaluations without one under ""
+		averages[i].Dimensions = values.of(rows[i].Semester.String)
+	}
+	return averages, nil
+}
+
+// LecturerProfile is Lecturer with the averages of every active dimension.
+func (s *Service) LecturerProfile(ctx context.Context, id int32) (api.LecturerProfile, error) {
+	lecturer, courses, ratings, err := s.Lecturer(ctx, id)
+	if err != nil {
+		return api.LecturerProfile{}, err
+	}
+	profile := toLecturerProfileDTO(lecturer, courses, ratings)
+	if !publishable(ratings.RatingCount) {
+		return profile, nil
+	}
+	rows, err := s.db.GetLecturerDimensionAverages(ctx, id)
+	if err != nil {
+		return api.LecturerProfile{}, err
+	}
+	for _, row := range rows {
+		profile.Dimensions[row.DimensionKey] = api.DimensionValue{Average: numericPtr(row.Average), Count: row.Count}
+	}
+	return profile, nil
+}
+
+// DepartmentStats is DepartmentRatingsAvg with the averages of every active dimension. A
+// dimension rated fewer than anonymityThreshold times is left out.
+func (s *Service) DepartmentStats(ctx context.Context, code string) (api.DepartmentStats, error) {
+	row, err := s.DepartmentRatingsAvg(ctx, code)
+	if err != nil {
+		return api.DepartmentStats{}, err
+	}
+	stats := toDepartmentStatsDTO(code, row)
+	if !publishable(row.RatingCount) {
+		return stats, nil
+	}
+	rows, err := s.db.GetDepartmentDimensionAverages(ctx, code)
+	if err != nil {
+		return api.DepartmentStats{}, err
+	}
+	for _, row := range rows {
+		if publishable(row.Count) {
+			stats.Dimensions[row.DimensionKey] = api.DimensionValue{Average: numericPtr(row.Average), Count: row.Count}
+		}
+	}
+	return stats, nil
+}
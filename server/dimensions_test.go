@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRatingsValues(t *testing.T) {
+	values := Ratings{Recommended: 4, Difficulty: 0, Values: map[string]int32{"exam_fairness": 2, "pace": 0}}.values()
+	if len(values) != 2 || values["recommended"] != 4 || values["exam_fairness"] != 2 {
+		t.Errorf("got %v, want recommended and exam_fairness, unset ones left out", values)
+	}
+}
+
+func TestRatingsValidLeavesScaleToDimensions(t *testing.T) {
+	// 7 fits a dimension with a scale up to 10, checkRatingValues knows the scale
+	if !(Ratings{Values: map[string]int32{"exam_fairness": 7}}).valid() {
+		t.Error("a value above 5 of a further dimension was rejected")
+	}
+	if (Ratings{Effort: -1}).valid() || (Ratings{Values: map[string]int32{"exam_fairness": -1}}).valid() {
+		t.Error("a negative value was accepted")
+	}
+}
+
+func TestSetRatingDimensionRejectsZeroScale(t *testing.T) {
+	svc := NewService(nil, nil)
+	_, err := svc.SetRatingDimension(context.Background(), sql.SetRatingDimensionParams{
+		Key: "exam_fairness", LabelDe: "Prüfungsfairness", LabelEn: "Exam fairness", ScaleMin: 0, ScaleMax: 4,
+	})
+	if !errors.Is(err, ErrInvalidScale) {
+		t.Errorf("got %v, want ErrInvalidScale: 0 can't be stored as it means not rated", err)
+	}
+}
+
+func TestDimensionValues(t *testing.T) {
+	values := dimensionValues{}
+	values.add("252-0027-00L", "exam_fairness", pgtype.Numeric{Int: big.NewInt(35), Exp: -1, Valid: true}, 6)
+
+	assertJSON(t, values.of("252-0027-00L"), `{"exam_fairness":{"average":3.5,"count":6}}`)
+	assertJSON(t, values.of("252-0028-00L"), `{}`)
+}
+
+func TestAggregatesFollowActiveDimensions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	execSQL(t, pool, "INSERT INTO rating_dimensions (key, label_de, label_en) VALUES ('recommended', 'Empfehlenswert', 'Recommended')")
+	execSQL(t, pool, "INSERT INTO rating_dimensions (key, label_de, label_en, scale_max) VALUES ('pace', 'Tempo', 'Pace', 7)")
+	execSQL(t, pool, "INSERT INTO rating_values (evaluation_id, dimension_key, value) VALUES ($1, 'recommended', 4), ($1, 'pace', 6)", evaluation)
+
+	if n := countRows(t, pool, "SELECT recommended_count FROM course_stats WHERE course_number = $1", testCourse); n != 1 {
+		t.Fatalf("course stats count %d recommended values, want 1", n)
+	}
+	execSQL(t, pool, "UPDATE rating_dimensions SET active = FALSE WHERE key = 'recommended'")
+	if n := countRows(t, pool, "SELECT recommended_count FROM course_stats WHERE course_number = $1", testCourse); n != 0 {
+		t.Errorf("course stats count %d values of the retired dimension", n)
+	}
+	if n := countRows(t, pool, "SELECT COUNT(recommended) FROM counted_ratings WHERE evaluation_id = $1", evaluation); n != 0 {
+		t.Error("the legacy column still shows the retired dimension")
+	}
+
+	rows, err := svc.db.CompareCourses(context.Background(), sql.CompareCoursesParams{CourseNumbers: []string{testCourse}, SnippetLength: 10, SnippetCount: 1})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("got %v, %v", rows, err)
+	}
+	var distributions map[string][]int64
+	if err := json.Unmarshal(rows[0].Distributions, &distributions); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]int64{"exam_fairness": {0, 0, 1, 0, 0}, "pace": {0, 0, 0, 0, 0, 1, 0}}
+	if !reflect.DeepEqual(distributions, want) {
+		t.Errorf("got distributions %v, want %v", distributions, want)
+	}
+}
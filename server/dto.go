@@ -0,0 +1,408 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The /v1 routes answer with the types of the api package instead of the sqlc rows,
+// so the JSON contract doesn't change with `sqlc generate`.
+
+// // // // // // //
+// field helpers  //
+// // // // // // //
+
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func int4Ptr(i pgtype.Int4) *int32 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int32
+}
+
+// numericPtr leaves out NaN and infinite values, JSON has no representation for them.
+func numericPtr(n pgtype.Numeric) *float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid || math.IsNaN(f.Float64) || math.IsInf(f.Float64, 0) {
+		return nil
+	}
+	return &f.Float64
+}
+
+func float8Ptr(f pgtype.Float8) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+func datePtr(d pgtype.Date) *string {
+	if !d.Valid || d.InfinityModifier != pgtype.Finite {
+		return nil
+	}
+	s := d.Time.Format(time.DateOnly)
+	return &s
+}
+
+func timestamptzPtr(t pgtype.Timestamptz) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.Format(time.RFC3339)
+	return &s
+}
+
+// anyDatePtr formats the untyped date columns sqlc generates for expressions like MAX(date).
+func anyDatePtr(v any) *string {
+	switch d := v.(type) {
+	case time.Time:
+		s := d.Format(time.DateOnly)
+		return &s
+	case pgtype.Date:
+		return datePtr(d)
+	case string:
+		return &d
+	}
+	return nil
+}
+
+func statusPtr(s sql.NullStatus) *string {
+	if !s.Valid {
+		return nil
+	}
+	status := string(s.Status)
+	return &status
+}
+
+func mapAll[T, R any](rows []T, mapper func(T) R) []R {
+	out := make([]R, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, mapper(row))
+	}
+	return out
+}
+
+// // // // // //
+// mappers     //
+// // // // // //
+
+func toCourseDTO(row sql.Course) api.Course {
+	return api.Course{CourseNumber: row.CourseNumber, CourseName: row.CourseName}
+}
+
+func toStatsDTO(row sql.GetStatsRow) api.Stats {
+	return api.Stats{TotalCourses: row.TotalCourses, TotalReviews: row.TotalReviews}
+}
+
+func toLatestReviewDTO(row sql.GetReviewedCoursesRow) api.CourseActivity {
+	return api.CourseActivity{CourseNumber: row.CourseNumber, CourseName: row.CourseName, LatestDate: anyDatePtr(row.Date)}
+}
+
+func toEvaluatedCourseDTO(row sql.GetAllCoursesWithReviewsOrRatingsRow) api.CourseActivity {
+	return api.CourseActivity{CourseNumber: row.CourseNumber, CourseName: row.CourseName, LatestDate: datePtr(row.LatestDate)}
+}
+
+func toCourseReviewCountDTO(row sql.GetCoursesWithReviewAmountRow) api.CourseReviewCount {
+	return api.CourseReviewCount{CourseNumber: row.CourseNumber, CourseName: row.CourseName, ReviewCount: row.Count}
+}
+
+func toReviewDTO(row sql.GetReviewsRow) api.Review {
+	review := api.Review{Review: row.Review, Semester: textPtr(row.Semester)}
+	if row.Annotation != "" {
+		review.Annotation = &row.Annotation
+	}
+	return review
+}
+
+func toRatingDTO(row sql.GetCourseRatingsRow) api.Rating {
+	return api.Rating{
+		Recommended:  int4Ptr(row.Recommended),
+		Engaging:     int4Ptr(row.Engaging),
+		Difficulty:   int4Ptr(row.Difficulty),
+		Effort:       int4Ptr(row.Effort),
+		Resources:    int4Ptr(row.Resources),
+		HoursPerWeek: float8Ptr(row.HoursPerWeek),
+	}
+}
+
+func toRatingAvgDTO(row sql.GetRatingsAvgRow) api.RatingAvg {
+	return api.RatingAvg{
+		Recommended: numericPtr(row.Recommended),
+		Engaging:    numericPtr(row.Engaging),
+		Difficulty:  numericPtr(row.Difficulty),
+		Effort:      numericPtr(row.Effort),
+		Resources:   numericPtr(row.Resources),
+		Dimensions:  map[string]api.DimensionValue{},
+	}
+}
+
+func toCourseRatingAvgDTO(row sql.GetAllRatingsAvgRow) api.CourseRatingAvg {
+	return api.CourseRatingAvg{
+		CourseNumber: row.CourseNumber,
+		RatingAvg: toRatingAvgDTO(sql.GetRatingsAvgRow{
+			Recommended: row.Recommended,
+			Engaging:    row.Engaging,
+			Difficulty:  row.Difficulty,
+			Effort:      row.Effort,
+			Resources:   row.Resources,
+		}),
+		Counts: api.RatingCounts{
+			Total:       row.RatingCount,
+			Recommended: row.RecommendedCount,
+			Engaging:    row.EngagingCount,
+			Difficulty:  row.DifficultyCount,
+			Effort:      row.EffortCount,
+			Resources:   row.ResourcesCount,
+		},
+	}
+}
+
+func toDepartmentDTO(row sql.GetDepartmentsRow) api.Department {
+	return api.Department{Code: row.Code, Name: textPtr(row.Name), Prefixes: row.Prefixes, CourseCount: row.CourseCount}
+}
+
+func toDepartmentStatsDTO(code string, row sql.GetDepartmentRatingsAvgRow) api.DepartmentStats {
+	return api.DepartmentStats{
+		Code:        code,
+		CourseCount: row.CourseCount,
+		ReviewCount: row.ReviewCount,
+		RatingAvg: toRatingAvgDTO(sql.GetRatingsAvgRow{
+			Recommended: row.Recommended,
+			Engaging:    row.Engaging,
+			Difficulty:  row.Difficulty,
+			Effort:      row.Effort,
+			Resources:   row.Resources,
+		}),
+		Counts: api.RatingCounts{
+			Total:       row.RatingCount,
+			Recommended: row.RecommendedCount,
+			Engaging:    row.EngagingCount,
+			Difficulty:  row.DifficultyCount,
+			Effort:      row.EffortCount,
+			Resources:   row.ResourcesCount,
+		},
+	}
+}
+
+// toSemesterLecturersDTO groups the rows, which are ordered by semester.
+func toSemesterLecturersDTO(rows []sql.GetCourseLecturersRow) []api.SemesterLecturers {
+	out := []api.SemesterLecturers{}
+	for _, row := range rows {
+		if len(out) == 0 || out[len(out)-1].Semester != row.Semester {
+			out = append(out, api.SemesterLecturers{Semester: row.Semester, Lecturers: []api.Lecturer{}})
+		}
+		last := &out[len(out)-1]
+		last.Lecturers = append(last.Lecturers, api.Lecturer{ID: row.ID, Name: row.Name})
+	}
+	return out
+}
+
+func toSemesterRatingAvgDTO(row sql.GetCourseRatingsBySemesterRow) api.SemesterRatingAvg {
+	lecturers := make([]api.Lecturer, 0, len(row.LecturerIds))
+	for i, id := range row.LecturerIds {
+		lecturers = append(lecturers, api.Lecturer{ID: id, Name: row.LecturerNames[i]})
+	}
+	return api.SemesterRatingAvg{
+		Semester:  textPtr(row.Semester),
+		Lecturers: lecturers,
+		RatingAvg: toRatingAvgDTO(sql.GetRatingsAvgRow{
+			Recommended: row.Recommended,
+			Engaging:    row.Engaging,
+			Difficulty:  row.Difficulty,
+			Effort:      row.Effort,
+			Resources:   row.Resources,
+		}),
+		Counts: api.RatingCounts{
+			Total:       row.RatingCount,
+			Recommended: row.RecommendedCount,
+			Engaging:    row.EngagingCount,
+			Difficulty:  row.DifficultyCount,
+			Effort:      row.EffortCount,
+			Resources:   row.ResourcesCount,
+		},
+	}
+}
+
+func toLecturerProfileDTO(lecturer sql.Lecturer, courses []sql.GetLecturerCoursesRow, ratings sql.GetLecturerRatingsAvgRow) api.LecturerProfile {
+	return api.LecturerProfile{
+		ID:   lecturer.ID,
+		Name: lecturer.Name,
+		Courses: mapAll(courses, func(row sql.GetLecturerCoursesRow) api.LecturerCourse {
+			return api.LecturerCourse{CourseNumber: row.CourseNumber, CourseName: row.CourseName, Semester: row.Semester}
+		}),
+		RatingAvg: toRatingAvgDTO(sql.GetRatingsAvgRow{
+			Recommended: ratings.Recommended,
+			Engaging:    ratings.Engaging,
+			Difficulty:  ratings.Difficulty,
+			Effort:      ratings.Effort,
+			Resources:   ratings.Resources,
+		}),
+		Counts: api.RatingCounts{
+			Total:       ratings.RatingCount,
+			Recommended: ratings.RecommendedCount,
+			Engaging:    ratings.EngagingCount,
+			Difficulty:  ratings.DifficultyCount,
+			Effort:      ratings.EffortCount,
+			Resources:   ratings.ResourcesCount,
+		},
+	}
+}
+
+func toPublicEvaluationDTO(row sql.GetAllTheDataRow) api.PublicEvaluation {
+	return api.PublicEvaluation{
+		EvaluationID: row.EvaluationID,
+		CourseNumber: row.CourseNumber,
+		CourseName:   row.CourseName,
+		Semester:     textPtr(row.Semester),
+		Review:       row.Review,
+		Date:         datePtr(row.Date),
+		Rating: toRatingDTO(sql.GetCourseRatingsRow{
+			Recommended:  row.Recommended,
+			Engaging:     row.Engaging,
+			Difficulty:   row.Difficulty,
+			Effort:       row.Effort,
+			Resources:    row.Resources,
+			HoursPerWeek: row.HoursPerWeek,
+		}),
+	}
+}
+
+func toUserEvaluationDTO(row sql.GetUserDataRow) api.UserEvaluation {
+	return api.UserEvaluation{
+		EvaluationID:     row.Evaluationid,
+		CourseNumber:     row.CourseNumber,
+		CourseName:       row.CourseName,
+		Semester:         textPtr(row.Semester),
+		Review:           textPtr(row.Review),
+		Status:           statusPtr(row.Published),
+		RequestedChanges: textPtr(row.RequestedChanges),
+		Rating: toRatingDTO(sql.GetCourseRatingsRow{
+			Recommended:  row.Recommended,
+			Engaging:     row.Engaging,
+			Difficulty:   row.Difficulty,
+			Effort:       row.Effort,
+			Resources:    row.Resources,
+			HoursPerWeek: row.HoursPerWeek,
+		}),
+	}
+}
+
+func toEvaluationDTO(row sql.CourseEvaluationMap) api.Evaluation {
+	return api.Evaluation{ID: row.ID, CourseNumber: row.CourseNumber, Semester: textPtr(row.Semester)}
+}
+
+func toReviewRecordDTO(row sql.Review) api.ReviewRecord {
+	return api.ReviewRecord{
+		EvaluationID:     row.EvaluationID,
+		Date:             datePtr(row.Date),
+		Status:           statusPtr(row.Published),
+		Review:           row.Review,
+		RequestedChanges: textPtr(row.RequestedChanges),
+		OldReview:        textPtr(row.OldReview),
+		EmbargoedUntil:   datePtr(row.EmbargoedUntil),
+		RedactedAt:       timestamptzPtr(row.RedactedAt),
+	}
+}
+
+func toReviewRevisionDTO(row sql.ReviewRevision) api.ReviewRevision {
+	return api.ReviewRevision{
+		ID:           row.ID,
+		EvaluationID: row.EvaluationID,
+		Review:       row.Review,
+		Reason:       textPtr(row.Reason),
+		CreatedAt:    row.CreatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+func toPendingReviewDTO(row sql.GetUnverifiedReviewsRow) api.PendingReview {
+	return api.PendingReview{
+		EvaluationID:     row.ID,
+		CourseNumber:     row.CourseNumber,
+		CourseName:       row.CourseName,
+		UserID:           row.UserID,
+		Review:           row.Review,
+		OldReview:        textPtr(row.OldReview),
+		RequestedChanges: textPtr(row.RequestedChanges),
+		EmbargoUntil:     datePtr(row.EmbargoUntil),
+	}
+}
+
+func toEmbargoedReviewDTO(row sql.GetEmbargoedReviewsRow) api.EmbargoedReview {
+	return api.EmbargoedReview{
+		EvaluationID:   row.ID,
+		CourseNumber:   row.CourseNumber,
+		CourseName:     row.CourseName,
+		Semester:       textPtr(row.Semester),
+		Review:         row.Review,
+		EmbargoedUntil: row.EmbargoedUntil.Time.Format(time.DateOnly),
+	}
+}
+
+func toSemesterEndDTO(row sql.SemesterCalendar) api.SemesterEnd {
+	return api.SemesterEnd{Semester: row.Semester, EndsOn: row.EndsOn.Time.Format(time.DateOnly)}
+}
+
+func toUserDTO(row sql.User) api.User {
+	return api.User{UserID: row.UserID, Admin: row.Admin.Bool, Moderator: row.Moderator.Bool}
+}
+
+func toRatingDimensionDTO(row sql.RatingDimension) api.RatingDimension {
+	return api.RatingDimension(row)
+}
+
+func toDimensionAvgDTO(row sql.GetCourseDimensionAveragesRow) api.DimensionAvg {
+	return api.DimensionAvg{
+		Key:      row.Key,
+		LabelDe:  row.LabelDe,
+		LabelEn:  row.LabelEn,
+		ScaleMin: row.ScaleMin,
+		ScaleMax: row.ScaleMax,
+		Average:  numericPtr(row.Average),
+		Count:    row.Count,
+	}
+}
+
+func toDimensionCommentDTO(row sql.GetCourseDimensionCommentsRow) api.DimensionComment {
+	return api.DimensionComment{
+		Value:    row.Value,
+		Comment:  row.Comment.String,
+		Semester: textPtr(row.Semester),
+		Date:     datePtr(row.CommentDate),
+	}
+}
+
+func toPendingRatingCommentDTO(row sql.GetPendingRatingCommentsRow) api.PendingRatingComment {
+	return api.PendingRatingComment{
+		EvaluationID: row.EvaluationID,
+		Dimension:    row.DimensionKey,
+		Value:        row.Value,
+		Comment:      row.Comment.String,
+		CourseNumber: row.CourseNumber,
+		CourseName:   row.CourseName,
+		UserID:       row.UserID,
+		Shadowed:     row.Shadowed,
+	}
+}
+
+func toRatingCommentRecordDTO(row sql.RatingValue) api.RatingCommentRecord {
+	return api.RatingCommentRecord{
+		EvaluationID:     row.EvaluationID,
+		Dimension:        row.DimensionKey,
+		Value:            row.Value,
+		Comment:          row.Comment.String,
+		Status:           statusPtr(row.CommentStatus),
+		RequestedChanges: textPtr(row.CommentRequestedChanges),
+		Date:             datePtr(row.CommentDate),
+	}
+}
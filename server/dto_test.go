@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The mappers fix the JSON contract of /v1, these tests notice when `sqlc generate`
+// changes a row type in a way that would leak into the answers.
+
+var (
+	testDay  = time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC)
+	testTime = time.Date(2024, 2, 14, 9, 30, 0, 0, time.UTC)
+)
+
+func assertJSON(t *testing.T, value any, want string) {
+	t.Helper()
+	got, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestFieldHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"text", textPtr(pgtype.Text{String: "HS", Valid: true}), `"HS"`},
+		{"empty text", textPtr(pgtype.Text{String: "", Valid: true}), `""`},
+		{"null text", textPtr(pgtype.Text{}), `null`},
+		{"int4", int4Ptr(pgtype.Int4{Int32: 4, Valid: true}), `4`},
+		{"null int4", int4Ptr(pgtype.Int4{}), `null`},
+		{"numeric", numericPtr(pgtype.Numeric{Int: big.NewInt(425), Exp: -2, Valid: true}), `4.25`},
+		{"null numeric", numericPtr(pgtype.Numeric{}), `null`},
+		{"nan numeric", numericPtr(pgtype.Numeric{NaN: true, Valid: true}), `null`},
+		{"float8", float8Ptr(pgtype.Float8{Float64: 7.5, Valid: true}), `7.5`},
+		{"null float8", float8Ptr(pgtype.Float8{}), `null`},
+		{"date", datePtr(pgtype.Date{Time: testDay, Valid: true}), `"2024-02-14"`},
+		{"null date", datePtr(pgtype.Date{}), `null`},
+		{"infinite date", datePtr(pgtype.Date{InfinityModifier: pgtype.Infinity, Valid: true}), `null`},
+		{"timestamptz", timestamptzPtr(pgtype.Timestamptz{Time: testTime, Valid: true}), `"2024-02-14T09:30:00Z"`},
+		{"null timestamptz", timestamptzPtr(pgtype.Timestamptz{}), `null`},
+		{"any date from time", anyDatePtr(testDay), `"2024-02-14"`},
+		{"any date from pgtype", anyDatePtr(pgtype.Date{Time: testDay, Valid: true}), `"2024-02-14"`},
+		{"any date null", anyDatePtr(nil), `null`},
+		{"status", statusPtr(sql.NullStatus{Status: sql.StatusVerified, Valid: true}), `"verified"`},
+		{"null status", statusPtr(sql.NullStatus{}), `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSON(t, tt.value, tt.want)
+		})
+	}
+}
+
+func TestMapAllNeverNull(t *testing.T) {
+	assertJSON(t, mapAll([]sql.Course(nil), toCourseDTO), `[]`)
+}
+
+func TestReviewDTO(t *testing.T) {
+	assertJSON(t, toReviewDTO(sql.GetReviewsRow{Review: "Great", Semester: pgtype.Text{String: "HS23", Valid: true}}),
+		`{"review":"Great","semester":"HS23","annotation":null}`)
+	assertJSON(t, toReviewDTO(sql.GetReviewsRow{Review: "Great", Annotation: "[redacted by moderator]"}),
+		`{"review":"Great","semester":null,"annotation":"[redacted by moderator]"}`)
+}
+
+func TestReviewRecordDTO(t *testing.T) {
+	assertJSON(t, toReviewRecordDTO(sql.Review{
+		EvaluationID:     7,
+		Date:             pgtype.Date{Time: testDay, Valid: true},
+		Published:        sql.NullStatus{Status: sql.StatusRejected, Valid: true},
+		Review:           "Too short",
+		RequestedChanges: pgtype.Text{String: "Please elaborate", Valid: true},
+		EmbargoedUntil:   pgtype.Date{Time: testDay, Valid: true},
+		RedactedAt:       pgtype.Timestamptz{Time: testTime, Valid: true},
+		DeletedAt:        pgtype.Timestamptz{Time: testTime, Valid: true},
+	}), `{"evaluationId":7,"date":"2024-02-14","status":"rejected","review":"Too short","requestedChanges":"Please elaborate","oldReview":null,"embargoedUntil":"2024-02-14","redactedAt":"2024-02-14T09:30:00Z"}`)
+
+	assertJSON(t, toReviewRecordDTO(sql.Review{EvaluationID: 7, Review: "Fine"}),
+		`{"evaluationId":7,"date":null,"status":null,"review":"Fine","requestedChanges":null,"oldReview":null,"embargoedUntil":null,"redactedAt":null}`)
+}
+
+func TestReviewRevisionDTO(t *testing.T) {
+	assertJSON(t, toReviewRevisionDTO(sql.ReviewRevision{
+		ID:           3,
+		EvaluationID: 7,
+		Review:       "Ask TA Jane",
+		Reason:       pgtype.Text{String: "names a TA", Valid: true},
+		CreatedAt:    pgtype.Timestamptz{Time: testTime, Valid: true},
+	}), `{"id":3,"evaluationId":7,"review":"Ask TA Jane","reason":"names a TA","createdAt":"2024-02-14T09:30:00Z"}`)
+
+	assertJSON(t, toReviewRevisionDTO(sql.ReviewRevision{ID: 3, EvaluationID: 7, Review: "Ask TA Jane", CreatedAt: pgtype.Timestamptz{Time: testTime, Valid: true}}),
+		`{"id":3,"evaluationId":7,"review":"Ask TA Jane","reason":null,"createdAt":"2024-02-14T09:30:00Z"}`)
+}
+
+func TestEmbargoedReviewDTO(t *testing.T) {
+	assertJSON(t, toEmbargoedReviewDTO(sql.GetEmbargoedReviewsRow{
+		ID:             7,
+		CourseNumber:   "252-0027-00L",
+		CourseName:     "Einführung in die Programmierung",
+		Review:         "Good",
+		EmbargoedUntil: pgtype.Date{Time: testDay, Valid: true},
+	}), `{"evaluationId":7,"courseNumber":"252-0027-00L","courseName":"Einführung in die Programmierung","semester":null,"review":"Good","embargoedUntil":"2024-02-14"}`)
+}
+
+func TestRatingDTOs(t *testing.T) {
+	assertJSON(t, toRatingDTO(sql.GetCourseRatingsRow{
+		Recommended:  pgtype.Int4{Int32: 5, Valid: true},
+		Difficulty:   pgtype.Int4{Int32: 1, Valid: true},
+		HoursPerWeek: pgtype.Float8{Float64: 6.5, Valid: true},
+	}), `{"recommended":5,"engaging":null,"difficulty":1,"effort":null,"resources":null,"hoursPerWeek":6.5}`)
+
+	assertJSON(t, toRatingAvgDTO(sql.GetRatingsAvgRow{
+		Recommended: pgtype.Numeric{Int: big.NewInt(45), Exp: -1, Valid: true},
+		Effort:      pgtype.Numeric{Int: big.NewInt(3), Valid: true},
+	}), `{"recommended":4.5,"engaging":null,"difficulty":null,"effort":3,"resources":null,"dimensions":{}}`)
+}
+
+func TestPublicEvaluationDTO(t *testing.T) {
+	assertJSON(t, toPublicEvaluationDTO(sql.GetAllTheDataRow{
+		EvaluationID: 7,
+		CourseNumber: "252-0027-00L",
+		CourseName:   "Einführung in die Programmierung",
+		Semester:     pgtype.Text{String: "HS23", Valid: true},
+		Review:       "Good",
+		Date:         pgtype.Date{Time: testDay, Valid: true},
+		Recommended:  pgtype.Int4{Int32: 4, Valid: true},
+	}), `{"evaluationId":7,"courseNumber":"252-0027-00L","courseName":"Einführung in die Programmierung","semester":"HS23","review":"Good","date":"2024-02-14","rating":{"recommended":4,"engaging":null,"difficulty":null,"effort":null,"resources":null,"hoursPerWeek":null}}`)
+}
+
+func TestSemesterEndDTO(t *testing.T) {
+	assertJSON(t, toSemesterEndDTO(sql.SemesterCalendar{Semester: "23HS", EndsOn: pgtype.Date{Time: testDay, Valid: true}}),
+		`{"semester":"23HS","endsOn":"2024-02-14"}`)
+}
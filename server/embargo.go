@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrInvalidEndDate = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid end date, use YYYY-MM-DD"}
+
+const embargoReleaseInterval = time.Hour
+
+// ReleaseEmbargoedReviews publishes the verified reviews whose semester has ended.
+func (s *Service) ReleaseEmbargoedReviews(ctx context.Context) error {
+	released, err := s.db.ReleaseEmbargoedReviews(ctx)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		s.publish(EventReviewChanged)
+	}
+	return nil
+}
+
+func (s *Service) EmbargoedReviews(ctx context.Context) ([]api.EmbargoedReview, error) {
+	rows, err := s.db.GetEmbargoedReviews(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapAll(rows, toEmbargoedReviewDTO), nil
+}
+
+func (s *Service) SemesterCalendar(ctx context.Context) ([]api.SemesterEnd, error) {
+	rows, err := s.db.GetSemesterCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapAll(rows, toSemesterEndDTO), nil
+}
+
+// parseSemesterEnd returns the semester in our format and the day it ends on.
+func parseSemesterEnd(semester, endsOn string) (string, pgtype.Date, error) {
+	parsed, err := ParseSemester(semester)
+	if err != nil {
+		return "", pgtype.Date{}, err
+	}
+	day, err := time.Parse(time.DateOnly, endsOn)
+	if err != nil {
+		return "", pgtype.Date{}, ErrInvalidEndDate
+	}
+	return parsed.String(), pgtype.Date{Time: day, Valid: true}, nil
+}
+
+// SetSemesterEnd sets the last day of a semester. Reviews of it that are already embargoed
+// move to the new date, those that are due by now are published right away.
+func (s *Service) SetSemesterEnd(ctx context.Context, semester, endsOn string) (api.SemesterEnd, error) {
+	semester, date, err := parseSemesterEnd(semester, endsOn)
+	if err != nil {
+		return api.SemesterEnd{}, err
+	}
+
+	var row sql.SemesterCalendar
+	err = s.inTx(ctx, func(db *sql.Queries) error {
+		var err error
+		row, err = db.SetSemesterEnd(ctx, sql.SetSemesterEndParams{Semester: semester, EndsOn: date})
+		if err != nil {
+			return err
+		}
+		if err := db.MoveSemesterEmbargo(ctx, sql.MoveSemesterEmbargoParams{EndsOn: date, Semester: pgtype.Text{String: semester, Valid: true}}); err != nil {
+			return err
+		}
+		_, err = db.ReleaseEmbargoedReviews(ctx)
+		return err
+	})
+	if err != nil {
+		return api.SemesterEnd{}, err
+	}
+	s.publish(EventReviewChanged)
+	return toSemesterEndDTO(row), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseSemesterEnd(t *testing.T) {
+	tests := []struct {
+		semester, endsOn string
+		wantSemester     string
+		wantDate         string
+		wantErr          error
+	}{
+		{"23HS", "2023-12-22", "23HS", "2023-12-22", nil},
+		{"2024S", "2024-05-31", "24FS", "2024-05-31", nil},
+		{"2023W", "2024-02-16", "23HS", "2024-02-16", nil},
+		{"23WS", "2023-12-22", "", "", ErrInvalidSemester},
+		{"23HS", "22.12.2023", "", "", ErrInvalidEndDate},
+		{"23HS", "2023-02-30", "", "", ErrInvalidEndDate},
+		{"23HS", "2023-12-22T00:00:00Z", "", "", ErrInvalidEndDate},
+		{"23HS", "", "", "", ErrInvalidEndDate},
+	}
+	for _, tt := range tests {
+		semester, date, err := parseSemesterEnd(tt.semester, tt.endsOn)
+		if err != tt.wantErr {
+			t.Errorf("parseSemesterEnd(%q, %q): error %v, want %v", tt.semester, tt.endsOn, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if semester != tt.wantSemester || !date.Valid || date.Time.Format(time.DateOnly) != tt.wantDate {
+			t.Errorf("parseSemesterEnd(%q, %q) = %q, %v, want %q, %s", tt.semester, tt.endsOn, semester, date.Time, tt.wantSemester, tt.wantDate)
+		}
+	}
+}
+
+func TestEmbargoDTOs(t *testing.T) {
+	until := pgtype.Date{Time: time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC), Valid: true}
+	assertJSON(t, toEmbargoedReviewDTO(sql.GetEmbargoedReviewsRow{
+		ID:             7,
+		CourseNumber:   "252-0027-00L",
+		CourseName:     "Einführung in die Programmierung",
+		Semester:       pgtype.Text{String: "23HS", Valid: true},
+		Review:         "Good exercises",
+		EmbargoedUntil: until,
+	}), `{"evaluationId":7,"courseNumber":"252-0027-00L","courseName":"Einführung in die Programmierung","semester":"23HS","review":"Good exercises","embargoedUntil":"2024-02-16"}`)
+	assertJSON(t, toSemesterEndDTO(sql.SemesterCalendar{Semester: "23HS", EndsOn: until}), `{"semester":"23HS","endsOn":"2024-02-16"}`)
+}
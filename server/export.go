@@ -0,0 +1,482 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrExportNotFound    = &ServiceError{Status: fiber.StatusNotFound, Message: "Export not found"}
+	ErrInvalidExportLink = &ServiceError{Status: fiber.StatusForbidden, Message: "Download link is invalid or expired"}
+)
+
+const (
+	// archives are deleted after this, a new export can be requested any time
+	exportRetention = 24 * time.Hour
+	exportLinkTTL   = 15 * time.Minute
+	// pending exports attempted longer ago than this were lost in a restart and are built again
+	exportStallAfter      = 10 * time.Minute
+	exportCleanupInterval = time.Hour
+)
+
+// exportSigningKey signs download links. Without EXPORT_SIGNING_KEY a random key is used,
+// links handed out before a restart stop working then.
+var exportSigningKey = func() []byte {
+	if key := os.Getenv("EXPORT_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+func signExport(id, format string, expires int64) string {
+	mac := hmac.New(sha256.New, exportSigningKey)
+	mac.Write([]byte(id + "|" + format + "|" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func exportLink(id, format string, expires int64) string {
+	query := url.Values{}
+	query.Set("format", format)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signExport(id, format, expires))
+	return "/v1/exports/" + url.PathEscape(id) + "/download?" + query.Encode()
+}
+
+func toDataExportDTO(row sql.GetDataExportRow) api.DataExport {
+	export := api.DataExport{
+		ID:         row.ID,
+		Status:     row.Status,
+		Error:      textPtr(row.Error),
+		CreatedAt:  row.CreatedAt.Time.Format(time.RFC3339),
+		FinishedAt: timestamptzPtr(row.FinishedAt),
+	}
+	if row.Status == "ready" {
+		expires := time.Now().Add(exportLinkTTL)
+		export.Links = map[string]string{
+			api.ExportJSON: exportLink(row.ID, api.ExportJSON, expires.Unix()),
+			api.ExportZip:  exportLink(row.ID, api.ExportZip, expires.Unix()),
+		}
+		linksExpireAt := expires.Format(time.RFC3339)
+		export.LinksExpireAt = &linksExpireAt
+	}
+	return export
+}
+
+// RequestDataExport starts building an archive of all personal data of the user, or
+// returns the one still being built.
+func (s *Service) RequestDataExport(ctx context.Context, userID string) (api.DataExport, error) {
+	pending, err := s.db.GetPendingDataExport(ctx, userID)
+	if err == nil {
+		return toDataExportDTO(sql.GetDataExportRow(pending)), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return api.DataExport{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return api.DataExport{}, err
+	}
+	export, err := s.db.CreateDataExport(ctx, sql.CreateDataExportParams{ID: hex.EncodeToString(id), UserID: userID})
+	if err != nil {
+		return api.DataExport{}, err
+	}
+	go s.buildDataExport(export.ID, userID)
+	return toDataExportDTO(sql.GetDataExportRow(export)), nil
+}
+
+// DataExport returns the state of an export of the user, with download links once it is ready.
+func (s *Service) DataExport(ctx context.Context, userID, id string) (api.DataExport, error) {
+	export, err := s.db.GetDataExport(ctx, sql.GetDataExportParams{ID: id, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return api.DataExport{}, ErrExportNotFound
+	}
+	if err != nil {
+		return api.DataExport{}, err
+	}
+	return toDataExportDTO(export), nil
+}
+
+// DataExportArchive checks a signed download link and returns the archive it points to.
+func (s *Service) DataExportArchive(ctx context.Context, id, format, expires, signature string) ([]byte, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt || (format != api.ExportJSON && format != api.ExportZip) {
+		return nil, ErrInvalidExportLink
+	}
+	if !hmac.Equal([]byte(signature), []byte(signExport(id, format, expiresAt))) {
+		return nil, ErrInvalidExportLink
+	}
+	archive, err := s.db.GetDataExportArchive(ctx, sql.GetDataExportArchiveParams{
+		Zip:          format == api.ExportZip,
+		ID:           id,
+		CreatedAfter: pgtype.Timestamptz{Time: time.Now().Add(-exportRetention), Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	return archive, err
+}
+
+// CleanupDataExports deletes old archives and builds exports again that a restart interrupted.
+func (s *Service) CleanupDataExports(ctx context.Context) error {
+	now := time.Now()
+	if err := s.db.DeleteDataExportsBefore(ctx, pgtype.Timestamptz{Time: now.Add(-exportRetention), Valid: true}); err != nil {
+		return err
+	}
+	// claiming stamps a new attempt, another instance running the cleanup leaves the export alone
+	for {
+		export, err := s.db.ClaimStalledDataExport(ctx, pgtype.Timestamptz{Time: now.Add(-exportStallAfter), Valid: true})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.buildDataExport(export.ID, export.UserID)
+	}
+}
+
+func (s *Service) buildDataExport(id, userID string) {
+	ctx := context.Background()
+	archive, err := s.collectPersonalData(ctx, userID)
+	var jsonArchive, zipArchive []byte
+	if err == nil {
+		jsonArchive, err = json.MarshalIndent(archive, "", "  ")
+	}
+	if err == nil {
+		zipArchive, err = archive.zip()
+	}
+	if err != nil {
+		log.Printf("Export %s failed: %v", id, err)
+		if err := s.db.FailDataExport(ctx, sql.FailDataExportParams{ID: id, Error: pgtype.Text{String: err.Error(), Valid: true}}); err != nil {
+			log.Printf("Export %s failed: %v", id, err)
+		}
+		return
+	}
+	if err := s.db.FinishDataExport(ctx, sql.FinishDataExportParams{ID: id, JsonArchive: jsonArchive, ZipArchive: zipArchive}); err != nil {
+		log.Printf("Export %s failed: %v", id, err)
+	}
+}
+
+// // // // // // //
+// archive        //
+// // // // // // //
+
+type exportUser struct {
+	UserID    string `json:"userId"`
+	Admin     bool   `json:"admin"`
+	Moderator bool   `json:"moderator"`
+}
+
+type exportEvaluation struct {
+	ID           int32   `json:"id"`
+	CourseNumber string  `json:"courseNumber"`
+	Semester     *string `json:"semester"`
+}
+
+type exportReview struct {
+	EvaluationID     int32   `json:"evaluationId"`
+	Date             *string `json:"date"`
+	Status           *string `json:"status"`
+	Review           string  `json:"review"`
+	RequestedChanges *string `json:"requestedChanges"`
+	OldReview        *string `json:"oldReview"`
+	Shadowed         bool    `json:"shadowed"`
+}
+
+type exportRevision struct {
+	EvaluationID int32   `json:"evaluationId"`
+	Review       string  `json:"review"`
+	Reason       *string `json:"reason"`
+	CreatedAt    string  `json:"createdAt"`
+}
+
+type exportRating struct {
+	EvaluationID int32    `json:"evaluationId"`
+	Date         *string  `json:"date"`
+	HoursPerWeek *float64 `json:"hoursPerWeek"`
+	Shadowed     bool     `json:"shadowed"`
+}
+
+type exportRatingValue struct {
+	EvaluationID            int32   `json:"evaluationId"`
+	Dimension               string  `json:"dimension"`
+	Value                   int32   `json:"value"`
+	Comment                 *string `json:"comment"`
+	CommentStatus           *string `json:"commentStatus"`
+	CommentRequestedChanges *string `json:"commentRequestedChanges"`
+	CommentDate             *string `json:"commentDate"`
+}
+
+type exportBan struct {
+	Kind      string  `json:"kind"`
+	Reason    string  `json:"reason"`
+	CreatedAt string  `json:"createdAt"`
+	ExpiresAt *string `json:"expiresAt"`
+}
+
+type exportAccountDeletion struct {
+	ID           int32   `json:"id"`
+	Mode         string  `json:"mode"`
+	RequestedAt  string  `json:"requestedAt"`
+	ScheduledFor string  `json:"scheduledFor"`
+	CancelledAt  *string `json:"cancelledAt"`
+	CompletedAt  *string `json:"completedAt"`
+	Evaluations  *int32  `json:"evaluations"`
+}
+
+type exportEvent struct {
+	ID           int32   `json:"id"`
+	EvaluationID *int32  `json:"evaluationId"`
+	Action       *string `json:"action"`
+	Info         *string `json:"info"`
+	Date         *string `json:"date"`
+}
+
+// personalData is everything stored about a user. Usage holds the lines of the usage log
+// that mention the user.
+type personalData struct {
+	ExportedAt   string                  `json:"exportedAt"`
+	User         exportUser              `json:"user"`
+	Evaluations  []exportEvaluation      `json:"evaluations"`
+	Reviews      []exportReview          `json:"reviews"`
+	Revisions    []exportRevision        `json:"reviewRevisions"`
+	Ratings      []exportRating          `json:"ratings"`
+	RatingValues []exportRatingValue     `json:"ratingValues"`
+	Bans         []exportBan             `json:"bans"`
+	Deletions    []exportAccountDeletion `json:"accountDeletions"`
+	EventLog     []exportEvent           `json:"eventLog"`
+	Usage        []api.StatEntry         `json:"usage"`
+}
+
+func (s *Service) collectPersonalData(ctx context.Context, userID string) (personalData, error) {
+	data := personalData{ExportedAt: time.Now().Format(time.RFC3339)}
+
+	user, err := s.db.GetUser(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.User = exportUser{UserID: user.UserID, Admin: user.Admin.Bool, Moderator: user.Moderator.Bool}
+
+	evaluations, err := s.db.ExportUserEvaluations(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Evaluations = mapAll(evaluations, func(row sql.ExportUserEvaluationsRow) exportEvaluation {
+		return exportEvaluation{ID: row.ID, CourseNumber: row.CourseNumber, Semester: textPtr(row.Semester)}
+	})
+
+	reviews, err := s.db.ExportUserReviews(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Reviews = mapAll(reviews, func(row sql.ExportUserReviewsRow) exportReview {
+		return exportReview{
+			EvaluationID:     row.EvaluationID,
+			Date:             datePtr(row.Date),
+			Status:           statusPtr(row.Published),
+			Review:           row.Review,
+			RequestedChanges: textPtr(row.RequestedChanges),
+			OldReview:        textPtr(row.OldReview),
+			Shadowed:         row.Shadowed,
+		}
+	})
+
+	revisions, err := s.db.ExportUserReviewRevisions(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Revisions = mapAll(revisions, func(row sql.ReviewRevision) exportRevision {
+		return exportRevision{EvaluationID: row.EvaluationID, Review: row.Review, Reason: textPtr(row.Reason), CreatedAt: row.CreatedAt.Time.Format(time.RFC3339)}
+	})
+
+	ratings, err := s.db.ExportUserRatings(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Ratings = mapAll(ratings, func(row sql.ExportUserRatingsRow) exportRating {
+		return exportRating{EvaluationID: row.EvaluationID, Date: datePtr(row.Date), HoursPerWeek: float8Ptr(row.HoursPerWeek), Shadowed: row.Shadowed}
+	})
+
+	values, err := s.db.ExportUserRatingValues(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.RatingValues = mapAll(values, func(row sql.RatingValue) exportRatingValue {
+		return exportRatingValue{
+			EvaluationID:            row.EvaluationID,
+			Dimension:               row.DimensionKey,
+			Value:                   row.Value,
+			Comment:                 textPtr(row.Comment),
+			CommentStatus:           statusPtr(row.CommentStatus),
+			CommentRequestedChanges: textPtr(row.CommentRequestedChanges),
+			CommentDate:             datePtr(row.CommentDate),
+		}
+	})
+
+	bans, err := s.db.ExportUserBans(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Bans = mapAll(bans, func(row sql.ExportUserBansRow) exportBan {
+		return exportBan{Kind: row.Kind, Reason: row.Reason, CreatedAt: row.CreatedAt.Time.Format(time.RFC3339), ExpiresAt: timestamptzPtr(row.ExpiresAt)}
+	})
+
+	deletions, err := s.db.ExportUserAccountDeletions(ctx, sql.ExportUserAccountDeletionsParams{
+		UserID:   pgtype.Text{String: userID, Valid: true},
+		UserHash: userHash(userID),
+	})
+	if err != nil {
+		return data, err
+	}
+	data.Deletions = mapAll(deletions, func(row sql.ExportUserAccountDeletionsRow) exportAccountDeletion {
+		return exportAccountDeletion{
+			ID:           row.ID,
+			Mode:         row.Mode,
+			RequestedAt:  row.RequestedAt.Time.Format(time.RFC3339),
+			ScheduledFor: row.ScheduledFor.Time.Format(time.RFC3339),
+			CancelledAt:  timestamptzPtr(row.CancelledAt),
+			CompletedAt:  timestamptzPtr(row.CompletedAt),
+			Evaluations:  int4Ptr(row.Evaluations),
+		}
+	})
+
+	events, err := s.db.ExportUserEventLog(ctx, pgtype.Text{String: userID, Valid: true})
+	if err != nil {
+		return data, err
+	}
+	data.EventLog = mapAll(events, func(row sql.ExportUserEventLogRow) exportEvent {
+		return exportEvent{
+			ID:           row.ID,
+			EvaluationID: int4Ptr(row.EvaluationID),
+			Action:       textPtr(row.Action),
+			Info:         textPtr(row.Info),
+			Date:         datePtr(row.Date),
+		}
+	})
+
+	data.Usage, err = userUsageEntries(userID)
+	return data, err
+}
+
+// userUsageEntries returns the logins and the creation of the user from the usage log.
+func userUsageEntries(userID string) ([]api.StatEntry, error) {
+	entries := []api.StatEntry{}
+	file, err := os.Open(statsLogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), " ")
+		if len(parts) < 3 {
+			continue
+		}
+		if key, value, _ := strings.Cut(parts[2], "="); value == userID && (key == "user_id" || key == "new_user") {
+			entries = append(entries, api.StatEntry{Time: parts[0] + " " + parts[1], Value: key})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func optional[T any](v *T, format func(T) string) string {
+	if v == nil {
+		return ""
+	}
+	return format(*v)
+}
+
+func itself(s string) string { return s }
+
+func formatInt32(i int32) string { return strconv.Itoa(int(i)) }
+
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+// zip writes every table of the archive as CSV file into a zip archive.
+func (data personalData) zip() ([]byte, error) {
+	tables := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"user.csv", []string{"user_id", "admin", "moderator"}, [][]string{{data.User.UserID, strconv.FormatBool(data.User.Admin), strconv.FormatBool(data.User.Moderator)}}},
+		{"evaluations.csv", []string{"id", "course_number", "semester"}, mapAll(data.Evaluations, func(e exportEvaluation) []string {
+			return []string{formatInt32(e.ID), e.CourseNumber, optional(e.Semester, itself)}
+		})},
+		{"reviews.csv", []string{"evaluation_id", "date", "status", "review", "requested_changes", "old_review", "shadowed"}, mapAll(data.Reviews, func(r exportReview) []string {
+			return []string{formatInt32(r.EvaluationID), optional(r.Date, itself), optional(r.Status, itself), r.Review, optional(r.RequestedChanges, itself), optional(r.OldReview, itself), strconv.FormatBool(r.Shadowed)}
+		})},
+		{"review_revisions.csv", []string{"evaluation_id", "review", "reason", "created_at"}, mapAll(data.Revisions, func(r exportRevision) []string {
+			return []string{formatInt32(r.EvaluationID), r.Review, optional(r.Reason, itself), r.CreatedAt}
+		})},
+		{"ratings.csv", []string{"evaluation_id", "date", "hours_per_week", "shadowed"}, mapAll(data.Ratings, func(r exportRating) []string {
+			return []string{formatInt32(r.EvaluationID), optional(r.Date, itself), optional(r.HoursPerWeek, formatFloat), strconv.FormatBool(r.Shadowed)}
+		})},
+		{"rating_values.csv", []string{"evaluation_id", "dimension", "value", "comment", "comment_status", "comment_requested_changes", "comment_date"}, mapAll(data.RatingValues, func(v exportRatingValue) []string {
+			return []string{formatInt32(v.EvaluationID), v.Dimension, formatInt32(v.Value), optional(v.Comment, itself), optional(v.CommentStatus, itself), optional(v.CommentRequestedChanges, itself), optional(v.CommentDate, itself)}
+		})},
+		{"bans.csv", []string{"kind", "reason", "created_at", "expires_at"}, mapAll(data.Bans, func(b exportBan) []string {
+			return []string{b.Kind, b.Reason, b.CreatedAt, optional(b.ExpiresAt, itself)}
+		})},
+		{"account_deletions.csv", []string{"id", "mode", "requested_at", "scheduled_for", "cancelled_at", "completed_at", "evaluations"}, mapAll(data.Deletions, func(d exportAccountDeletion) []string {
+			return []string{formatInt32(d.ID), d.Mode, d.RequestedAt, d.ScheduledFor, optional(d.CancelledAt, itself), optional(d.CompletedAt, itself), optional(d.Evaluations, formatInt32)}
+		})},
+		{"event_log.csv", []string{"id", "evaluation_id", "action", "info", "date"}, mapAll(data.EventLog, func(e exportEvent) []string {
+			return []string{formatInt32(e.ID), optional(e.EvaluationID, formatInt32), optional(e.Action, itself), optional(e.Info, itself), optional(e.Date, itself)}
+		})},
+		{"usage.csv", []string{"time", "event"}, mapAll(data.Usage, func(e api.StatEntry) []string {
+			return []string{e.Time, e.Value}
+		})},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, table := range tables {
+		file, err := archive.Create(table.name)
+		if err != nil {
+			return nil, err
+		}
+		writer := csv.NewWriter(file)
+		if err := writer.Write(table.header); err != nil {
+			return nil, err
+		}
+		if err := writer.WriteAll(table.rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
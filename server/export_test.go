@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"coursereview/app/api"
+)
+
+func TestPersonalDataHasBansAndDeletions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	execSQL(t, pool, "UPDATE ratings SET shadowed = TRUE WHERE evaluation_id = $1", evaluation)
+	execSQL(t, pool, "INSERT INTO user_bans (user_id, kind, reason) VALUES ('alice', 'shadow', 'rating spam')")
+	requestDeletion(t, svc, "alice", api.DetachContributions)
+
+	data, err := svc.collectPersonalData(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Bans) != 1 || data.Bans[0].Kind != api.BanShadow || data.Bans[0].Reason != "rating spam" {
+		t.Errorf("got bans %+v, want the shadow ban", data.Bans)
+	}
+	if len(data.Deletions) != 1 || data.Deletions[0].Mode != api.DetachContributions {
+		t.Errorf("got deletions %+v, want the requested one", data.Deletions)
+	}
+	if len(data.Ratings) != 1 || !data.Ratings[0].Shadowed {
+		t.Errorf("got ratings %+v, want the rating marked as shadowed", data.Ratings)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runEvery runs the job right away and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	runOn(ctx, interval, nil, name, job)
+}
+
+// runOn is runEvery that also runs the job whenever wake receives.
+func runOn(ctx context.Context, interval time.Duration, wake <-chan struct{}, name string, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("Job %s failed: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// wakeOn returns a channel receiving after the service published one of the events. Events
+// published while a job is running are coalesced into a single run afterwards.
+func wakeOn(svc *Service, events ...Event) <-chan struct{} {
+	wake := make(chan struct{}, 1)
+	svc.Subscribe(func(event Event) {
+		for _, e := range events {
+			if e == event {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+				return
+			}
+		}
+	})
+	return wake
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrLecturerNotFound = &ServiceError{Status: fiber.StatusNotFound, Message: "Lecturer not found"}
+
+// CourseLecturers lists who taught the course, latest semester first.
+func (s *Service) CourseLecturers(ctx context.Context, course string) ([]sql.GetCourseLecturersRow, error) {
+	return s.db.GetCourseLecturers(ctx, course)
+}
+
+// RatingsBySemester splits the rating averages of a course by the semester of the
+// evaluation, together with the lecturers of that semester. Semesters with fewer than
+// anonymityThreshold ratings are left out.
+func (s *Service) RatingsBySemester(ctx context.Context, course string) ([]sql.GetCourseRatingsBySemesterRow, error) {
+	rows, err := s.db.GetCourseRatingsBySemester(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	published := rows[:0]
+	for _, row := range rows {
+		if publishable(row.RatingCount) {
+			published = append(published, row)
+		}
+	}
+	return published, nil
+}
+
+// Lecturer returns a lecturer, the courses they taught and the rating averages of the
+// evaluations from those course semesters.
+func (s *Service) Lecturer(ctx context.Context, id int32) (sql.Lecturer, []sql.GetLecturerCoursesRow, sql.GetLecturerRatingsAvgRow, error) {
+	var ratings sql.GetLecturerRatingsAvgRow
+	lecturer, err := s.db.GetLecturer(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return lecturer, nil, ratings, ErrLecturerNotFound
+	}
+	if err != nil {
+		return lecturer, nil, ratings, err
+	}
+	courses, err := s.db.GetLecturerCourses(ctx, id)
+	if err != nil {
+		return lecturer, nil, ratings, err
+	}
+	ratings, err = s.db.GetLecturerRatingsAvg(ctx, id)
+	if err == nil && !publishable(ratings.RatingCount) {
+		ratings = sql.GetLecturerRatingsAvgRow{}
+	}
+	return lecturer, courses, ratings, err
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// request bodies of the legacy routes, authenticated ones also accept the token in the body
+type legacyUserBody struct {
+	User string `json:"user"`
+}
+
+type legacyInsertReviewBody struct {
+	CourseNumber string `json:"courseNumber"`
+	Semester     string `json:"semester"`
+	Review       string `json:"review"`
+	UniqueId     string `json:"randomString"`
+	Ratings
+}
+
+type legacyIDBody struct {
+	Id int32 `json:"id"`
+}
+
+type legacyReviewBody struct {
+	Id     int32  `json:"id"`
+	Review string `json:"review"`
+}
+
+type legacyRatingBody struct {
+	Id int32 `json:"id"`
+	Ratings
+}
+
+type legacySemesterBody struct { //can I parse this directly to pgtype.Text?
+	Id       int32  `json:"id"`
+	Semester string `json:"semester"`
+}
+
+type legacySemesterListBody struct {
+	List []string `json:"list"`
+}
+
+type legacyRejectBody struct {
+	Id               int32  `json:"id"`
+	RequestedChanges string `json:"requested_changes"`
+}
+
+type legacyRedactBody struct {
+	Id     int32  `json:"id"`
+	Review string `json:"review"`
+	Reason string `json:"reason"`
+}
+
+// registerLegacyRoutes mounts the original RPC-style routes. They stay until the frontend
+// has moved to /v1 and answer with a Deprecation header pointing to their successor.
+func registerLegacyRoutes(app *fiber.App, svc *Service, cache *responseCache) {
+	app.Get("/all", deprecated("/v1/evaluations"), cache.cached(time.Minute, EventReviewChanged, EventRatingChanged), func(c *fiber.Ctx) error {
+		data, err := svc.AllData(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(data)
+	})
+
+	app.Get("/stats", deprecated("/v1/stats"), cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		stats, err := svc.Stats(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(stats)
+	})
+
+	app.Get("/latestReviews", deprecated("/v1/reviews/latest"), cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		reviews, err := svc.LatestReviews(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(reviews)
+	})
+
+	app.Get("/getReviews", deprecated("/v1/courses/:number/reviews"), cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		reviews, err := svc.Reviews(c.Context(), c.Query("course"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(reviews)
+	})
+
+	app.Get("/getRatings", deprecated("/v1/courses/:number/ratings"), cache.cached(time.Minute, EventRatingChanged), func(c *fiber.Ctx) error {
+		ratings, err := svc.Ratings(c.Context(), c.Query("course"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(ratings)
+	})
+	app.Get("/getRatingsAvg", deprecated("/v1/courses/:number/ratings/average"), cache.cached(time.Minute, EventRatingChanged), func(c *fiber.Ctx) error {
+		ratings, err := svc.RatingsAvg(c.Context(), c.Query("course"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(ratings)
+	})
+	app.Get("/getAllRatingsAvg", deprecated("/v1/ratings/averages"), cache.cached(time.Minute, EventRatingChanged), func(c *fiber.Ctx) error {
+		filter, err := ratingsAvgFilter(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		ratings, _, err := svc.AllRatingsAvg(c.Context(), filter)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(ratings)
+	})
+
+	app.Get("/courses", deprecated("/v1/courses"), cache.cached(time.Hour, EventCourseChanged), func(c *fiber.Ctx) error {
+		data, err := svc.Courses(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(data)
+	})
+
+	app.Get("/coursesWithReviewAmount", deprecated("/v1/courses/review-counts"), cache.cached(time.Minute, EventReviewChanged, EventCourseChanged), func(c *fiber.Ctx) error {
+		data, err := svc.CoursesWithReviewAmount(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(data)
+	})
+
+	app.Get("/searchCourses", func(c *fiber.Ctx) error {
+		return c.Status(500).JSON(fiber.Map{"error": "Not implemented"})
+	})
+
+	app.Get("/currentSemesters", deprecated("/v1/semesters/current"), cache.cached(5*time.Minute, EventSemesterChanged), func(c *fiber.Ctx) error {
+		semester, err := svc.CurrentSemesters(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(semester)
+	})
+
+	app.Get("/courseName", deprecated("/v1/courses/:number"), cache.cached(time.Hour, EventCourseChanged), func(c *fiber.Ctx) error {
+		data, err := svc.CourseName(c.Context(), c.Query("course"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(data)
+	})
+
+	app.Get("/coursesWithRatingsOrReviews", deprecated("/v1/courses/evaluated"), cache.cached(time.Minute, EventReviewChanged, EventRatingChanged), func(c *fiber.Ctx) error {
+		courses, err := svc.CoursesWithRatingsOrReviews(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(courses)
+	})
+
+	// unauthenticated user creation has no /v1 successor
+	app.Post("/setUser", deprecated("/v1"), func(c *fiber.Ctx) error {
+		var data legacyUserBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		user, err := svc.CreateUser(c.Context(), data.User)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(user)
+	})
+
+	app.Post("/insertReview", deprecated("/v1/evaluations"), func(c *fiber.Ctx) error {
+		var data legacyInsertReviewBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		uniqueId := data.UniqueId + "noAuth"
+		if err := svc.CheckBan(c.Context(), uniqueId, true); err != nil {
+			return sendError(c, err)
+		}
+
+		if err := svc.EnsureUser(c.Context(), uniqueId); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := svc.Evaluation(c.Context(), uniqueId, data.CourseNumber, data.Semester)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// the review is optional here, the answer is the one of the rating
+		if _, err := svc.SetReview(c.Context(), id, data.Review); err != nil && !errors.Is(err, ErrReviewEmpty) {
+			return sendError(c, err)
+		}
+		return legacyRatingChange(c, svc, id, data.Ratings)
+	})
+
+	// // // // // // // // //
+	// authentication needed //
+	// // // // // // // // //
+	auth := app.Group("/auth")
+
+	auth.Use("/", func(c *fiber.Ctx) error {
+		token := c.Query("token")
+		if token == "" {
+			type Token struct {
+				Token string `json:"token"`
+			}
+			var data Token
+			if err := c.BodyParser(&data); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			}
+			token = data.Token
+		}
+		uniqueId, err := svc.Authenticate(c.Context(), token)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if err := checkBan(c, svc, uniqueId); err != nil {
+			return sendError(c, err)
+		}
+		c.Locals("unique_id", uniqueId)
+		return c.Next()
+	})
+
+	auth.Get("/getUserData", deprecated("/v1/me/evaluations"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		data, err := svc.UserData(c.Context(), uniqueId)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(data)
+	})
+
+	auth.Get("/recommendations", deprecated("/v1/me/recommendations"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		recommendations, err := svc.Recommendations(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(recommendations)
+	})
+
+	auth.Post("/export", deprecated("/v1/me/exports"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		export, err := svc.RequestDataExport(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(202).JSON(export)
+	})
+
+	auth.Get("/export/:id", deprecated("/v1/me/exports/:id"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		export, err := svc.DataExport(c.Context(), uniqueId, c.Params("id"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(export)
+	})
+
+	auth.Post("/deleteAccount", deprecated("/v1/me/deletion"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data api.AccountDeletionBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		deletion, err := svc.RequestAccountDeletion(c.Context(), uniqueId, data.Mode)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(202).JSON(deletion)
+	})
+
+	auth.Post("/updateReview", deprecated("/v1/evaluations/:id/review"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyReviewBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		if _, err := svc.UpdateReview(c.Context(), uniqueId, data.Id, data.Review); err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": "Set review"})
+	})
+
+	auth.Post("/deleteRating", deprecated("/v1/evaluations/:id/rating"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyIDBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		rating, err := svc.DeleteRating(c.Context(), uniqueId, data.Id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(rating)
+	})
+
+	auth.Post("/deleteReview", deprecated("/v1/evaluations/:id/review"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyIDBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		review, err := svc.DeleteReview(c.Context(), uniqueId, data.Id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(review)
+	})
+
+	auth.Post("/restoreRating", deprecated("/v1/evaluations/:id/rating/restore"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyIDBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		rating, err := svc.RestoreRating(c.Context(), uniqueId, data.Id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(rating)
+	})
+
+	auth.Post("/restoreReview", deprecated("/v1/evaluations/:id/review/restore"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyIDBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		review, err := svc.RestoreReview(c.Context(), uniqueId, data.Id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(review)
+	})
+
+	auth.Post("/updateRating", deprecated("/v1/evaluations/:id/rating"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyRatingBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		if err := svc.checkOwner(c.Context(), uniqueId, data.Id); err != nil {
+			return sendError(c, err)
+		}
+		return legacyRatingChange(c, svc, data.Id, data.Ratings)
+	})
+
+	auth.Post("/updateSemester", deprecated("/v1/evaluations/:id"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacySemesterBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		semester, err := svc.UpdateSemester(c.Context(), uniqueId, data.Id, data.Semester)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(semester)
+	})
+
+	// // // // // // // //
+	// mod / admin needed //
+	// // // // // // // //
+	moderator := auth.Group("/moderator")
+	moderator.Use("/", requireModerator(svc))
+
+	admin := auth.Group("/admin")
+	admin.Use("/", requireAdmin(svc))
+
+	moderator.Post("/setCurrentSemester", deprecated("/v1/semesters/current"), func(c *fiber.Ctx) error {
+		var data legacySemesterListBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		if err := svc.SetCurrentSemesters(c.Context(), data.List); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": "Semester set"})
+	})
+
+	admin.Post("/setModerator", deprecated("/v1/admin/moderators/:user"), func(c *fiber.Ctx) error {
+		var data legacyUserBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		val, err := svc.SetModerator(c.Context(), data.User)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": val})
+	})
+
+	moderator.Get("/getUnverifiedReviews", deprecated("/v1/moderation/reviews"), func(c *fiber.Ctx) error {
+		reviews, err := svc.UnverifiedReviews(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(reviews)
+	})
+
+	moderator.Post("/verifyReview", deprecated("/v1/moderation/reviews/:id/verify"), func(c *fiber.Ctx) error {
+		var data legacyIDBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		review, err := svc.VerifyReview(c.Context(), data.Id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(review)
+	})
+
+	moderator.Post("/rejectReview", deprecated("/v1/moderation/reviews/:id/reject"), func(c *fiber.Ctx) error {
+		var data legacyRejectBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		review, err := svc.RejectReview(c.Context(), data.Id, data.RequestedChanges)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(review)
+	})
+
+	moderator.Post("/redactReview", deprecated("/v1/moderation/reviews/:id/redact"), func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data legacyRedactBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		review, err := svc.RedactReview(c.Context(), uniqueId, data.Id, data.Review, data.Reason)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(review)
+	})
+
+	moderator.Get("/usageStats", deprecated("/v1/moderation/usage-stats"), func(c *fiber.Ctx) error {
+		userEntries, pathEntries, err := svc.UsageStats()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"users": userEntries, "paths": pathEntries})
+	})
+
+	//todo: not used yet
+	admin.Post("/addCourse", deprecated("/v1/admin/courses"), func(c *fiber.Ctx) error {
+		data := new(sql.SetCourseParams)
+		if err := c.BodyParser(data); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		course, err := svc.AddCourse(c.Context(), *data)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(course)
+	})
+	//todo: change to SSE
+	moderator.Post("/scrapeCourses", deprecated("/v1/moderation/scrapes"), func(c *fiber.Ctx) error {
+		var data api.SemesterBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		svc.StartScrape(data.Semester)
+		return c.JSON(fiber.Map{"success": "Scraped courses"})
+	})
+}
+
+// legacyRatingChange answers like the old routes did, where a submission without any
+// rating is not an error.
+func legacyRatingChange(c *fiber.Ctx, svc *Service, evalId int32, newRating Ratings) error {
+	created, err := svc.SetRating(c.Context(), evalId, newRating)
+	if errors.Is(err, ErrRatingsNotSet) {
+		return c.Status(200).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return sendError(c, err)
+	}
+	if created {
+		return c.JSON(fiber.Map{"success": "Set rating"})
+	}
+	return c.JSON(fiber.Map{"success": "Updated rating"})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendError answers with the status of a ServiceError, or 500 for anything else.
+func sendError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		status = serviceErr.Status
+	}
+	return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+// urlWithoutToken is the path and query of the request without the token legacy routes
+// accept as query parameter, for logs and cache keys. Its claims include the unique_id.
+func urlWithoutToken(c *fiber.Ctx) string {
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return c.Path()
+	}
+	query.Del("token")
+	if len(query) == 0 {
+		return c.Path()
+	}
+	return c.Path() + "?" + query.Encode()
+}
+
+// paramID parses the :id route parameter of an evaluation.
+func paramID(c *fiber.Ctx) (int32, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, &ServiceError{Status: fiber.StatusBadRequest, Message: "Invalid id"}
+	}
+	return int32(id), nil
+}
+
+// ratingsAvgFilter reads the page, pageSize, sort, minCount and department query parameters.
+func ratingsAvgFilter(c *fiber.Ctx) (RatingsAvgFilter, error) {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return RatingsAvgFilter{}, ErrInvalidPage
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "0"))
+	if err != nil {
+		return RatingsAvgFilter{}, ErrInvalidPage
+	}
+	minCount, err := strconv.ParseInt(c.Query("minCount", "0"), 10, 32)
+	if err != nil {
+		return RatingsAvgFilter{}, &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid minCount"}
+	}
+	return RatingsAvgFilter{
+		Page:       page,
+		PageSize:   pageSize,
+		Sort:       c.Query("sort"),
+		MinCount:   int32(minCount),
+		Department: c.Query("department"),
+	}, nil
+}
+
+// deprecated marks a legacy route and points clients to its /v1 successor.
+func deprecated(successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		return c.Next()
+	}
+}
+
+// bearerAuth authenticates the "Authorization: Bearer <token>" header.
+func bearerAuth(svc *Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || token == "" {
+			return c.Status(401).JSON(fiber.Map{"error": "Missing bearer token"})
+		}
+		uniqueId, err := svc.Authenticate(c.Context(), token)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if err := checkBan(c, svc, uniqueId); err != nil {
+			return sendError(c, err)
+		}
+		c.Locals("unique_id", uniqueId)
+		return c.Next()
+	}
+}
+
+// banExemptPrefixes are the routes that stay open to banned users, they can still export
+// their data and delete their account.
+var banExemptPrefixes = []string{"/v1/me/exports", "/v1/me/deletion", "/auth/export", "/auth/deleteAccount"}
+
+func banExempt(path string) bool {
+	for _, prefix := range banExemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBan applies the ban of the authenticated user to the request, shared by the bearer
+// and the legacy token authentication.
+func checkBan(c *fiber.Ctx, svc *Service, uniqueId string) error {
+	if banExempt(c.Path()) {
+		return nil
+	}
+	return svc.CheckBan(c.Context(), uniqueId, c.Method() != fiber.MethodGet)
+}
+
+func requireModerator(svc *Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		user, err := svc.User(c.Context(), uniqueId)
+		if err != nil || (!user.Moderator.Bool && !user.Admin.Bool) {
+			return c.Status(403).JSON(fiber.Map{"error": "Forbidden, user not at least moderator"})
+		}
+		return c.Next()
+	}
+}
+
+func requireAdmin(svc *Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		user, err := svc.User(c.Context(), uniqueId)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !user.Admin.Bool {
+			return c.Status(401).JSON(fiber.Map{"error": "Not authorized"})
+		}
+		return c.Next()
+	}
+}
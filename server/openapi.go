@@ -0,0 +1,355 @@
+package main
+
+import (
+	_ "embed"
+	"log"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// apiDoc describes a route for the generated OpenAPI spec. Request and response
+// schemas are derived from the Go types of Body and Response.
+type apiDoc struct {
+	Summary  string
+	Tag      string
+	Auth     string // "", "user", "moderator" or "admin"
+	Query    []string
+	Body     any
+	Response any
+	Status   int // success status, defaults to 200
+}
+
+// apiDocs is keyed by "METHOD /path" as registered in fiber. Routes missing here still show
+// up in the spec, but are logged on startup so they get documented.
+var apiDocs = map[string]apiDoc{
+	"GET /": {Summary: "Health check", Tag: "public", Response: map[string]string{}},
+
+	// v1 public
+	"GET /v1/stats":                              {Summary: "Number of reviewed courses and verified reviews", Tag: "courses", Response: api.Stats{}},
+	"GET /v1/evaluations":                        {Summary: "All verified reviews with their ratings", Tag: "evaluations", Response: []api.PublicEvaluation{}},
+	"POST /v1/evaluations":                       {Summary: "Submit a review and/or rating without login", Tag: "evaluations", Body: api.EvaluationSubmission{}, Response: api.Created{}, Status: 201},
+	"GET /v1/reviews/latest":                     {Summary: "Courses ordered by their latest verified review", Tag: "courses", Response: []api.CourseActivity{}},
+	"GET /v1/ratings/averages":                   {Summary: "Per-dimension rating averages and counts of all courses, sort by a dimension, count or courseNumber (\"-\" prefix for descending)", Tag: "courses", Query: []string{"page", "pageSize", "sort", "minCount", "department"}, Response: api.RatingAvgPage{}},
+	"GET /v1/semesters/current":                  {Summary: "Semesters that can currently be selected", Tag: "semesters", Response: []string{}},
+	"GET /v1/courses":                            {Summary: "All courses", Tag: "courses", Response: []api.Course{}},
+	"GET /v1/courses/review-counts":              {Summary: "All courses with their amount of reviews", Tag: "courses", Response: []api.CourseReviewCount{}},
+	"GET /v1/courses/evaluated":                  {Summary: "Courses with a verified review or a rating", Tag: "courses", Response: []api.CourseActivity{}},
+	"GET /v1/courses/:number":                    {Summary: "Name of a course", Tag: "courses", Response: api.Course{}},
+	"GET /v1/courses/:number/reviews":            {Summary: "Verified reviews of a course", Tag: "courses", Response: []api.Review{}},
+	"GET /v1/courses/:number/ratings":            {Summary: "All ratings of a course, none until it has ANONYMITY_THRESHOLD of them", Tag: "courses", Response: []api.Rating{}},
+	"GET /v1/courses/:number/ratings/average":    {Summary: "Rating averages of a course", Tag: "courses", Response: api.RatingAvg{}},
+	"GET /v1/courses/:number/ratings/dimensions": {Summary: "Average, distribution and latest verified comments of every active rating dimension of a course", Tag: "courses", Response: []api.DimensionAvg{}},
+	"GET /v1/rating-dimensions":                  {Summary: "Active rating dimensions with their labels and scales, in display order", Tag: "courses", Response: []api.RatingDimension{}},
+	"GET /v1/courses/:number/lecturers":          {Summary: "Lecturers of a course by semester, latest first", Tag: "courses", Response: []api.SemesterLecturers{}},
+	"GET /v1/courses/:number/ratings/semesters":  {Summary: "Rating averages of a course split by semester and its lecturers", Tag: "courses", Response: []api.SemesterRatingAvg{}},
+	"GET /v1/courses/:number/similar":            {Summary: "Courses whose verified reviews and name read most alike, recomputed every 6 hours", Tag: "courses", Response: []api.SimilarCourse{}},
+	"GET /v1/courses/:number/summary":            {Summary: "Keyphrases and sentiment of the verified reviews of a course, positive and negative phrases and a sentiment trend per semester", Tag: "courses", Response: api.CourseSummary{}},
+	"GET /v1/courses/:number/workload":           {Summary: "Median and IQR of the hours per week spent on a course and its workload per ECTS credit", Tag: "courses", Response: api.CourseWorkload{}},
+	"GET /v1/courses/:number/trends":             {Summary: "Rating averages of a course per semester in chronological order, flagging significant shifts", Tag: "courses", Response: []api.TrendPoint{}},
+	"GET /v1/lecturers/:id":                      {Summary: "Courses of a lecturer and the rating averages of the semesters they taught", Tag: "courses", Response: api.LecturerProfile{}},
+	"GET /v1/compare":                            {Summary: "Compare up to 5 courses side by side, courses is a comma separated list of course numbers", Tag: "courses", Query: []string{"courses"}, Response: []api.CourseComparison{}},
+	"GET /v1/departments":                        {Summary: "Departments with their course number prefixes", Tag: "departments", Response: []api.Department{}},
+	"GET /v1/departments/:code/courses":          {Summary: "Courses of a department", Tag: "departments", Response: []api.Course{}},
+	"GET /v1/exports/:id/download":               {Summary: "Download a personal data export through the signed link from GET /v1/me/exports/:id", Tag: "evaluations", Query: []string{"format", "expires", "signature"}, Response: ""},
+	"GET /v1/departments/:code/stats":            {Summary: "Rating averages and counts over all courses of a department", Tag: "departments", Response: api.DepartmentStats{}},
+
+	// v1 authenticated
+	"GET /v1/me/evaluations":                  {Summary: "Reviews and ratings of the logged in user", Tag: "evaluations", Auth: "user", Response: []api.UserEvaluation{}},
+	"GET /v1/me/recommendations":              {Summary: "Courses the user might like, each with the reason it was suggested", Tag: "evaluations", Auth: "user", Response: []api.Recommendation{}},
+	"POST /v1/me/exports":                     {Summary: "Build a JSON and zipped CSV archive of all personal data of the user in the background", Tag: "evaluations", Auth: "user", Response: api.DataExport{}, Status: 202},
+	"GET /v1/me/exports/:id":                  {Summary: "State of a personal data export, with download links valid for 15 minutes once it is ready", Tag: "evaluations", Auth: "user", Response: api.DataExport{}},
+	"POST /v1/me/deletion":                    {Summary: "Delete the account after a grace period of 14 days, mode delete removes all contributions, detach keeps them without the account", Tag: "evaluations", Auth: "user", Body: api.AccountDeletionBody{}, Response: api.AccountDeletion{}, Status: 202},
+	"GET /v1/me/deletion":                     {Summary: "The pending deletion of the account", Tag: "evaluations", Auth: "user", Response: api.AccountDeletion{}},
+	"DELETE /v1/me/deletion":                  {Summary: "Cancel the pending deletion of the account", Tag: "evaluations", Auth: "user", Response: api.AccountDeletion{}},
+	"PATCH /v1/evaluations/:id":               {Summary: "Change the semester of an evaluation", Tag: "evaluations", Auth: "user", Body: api.SemesterBody{}, Response: api.Evaluation{}},
+	"PUT /v1/evaluations/:id/review":          {Summary: "Create or replace the review of an evaluation", Tag: "evaluations", Auth: "user", Body: api.ReviewBody{}, Response: api.Success{}},
+	"DELETE /v1/evaluations/:id/review":       {Summary: "Delete the review of an evaluation, it can be restored within the undo window", Tag: "evaluations", Auth: "user", Status: 204},
+	"POST /v1/evaluations/:id/review/restore": {Summary: "Restore the deleted review of an evaluation within the undo window", Tag: "evaluations", Auth: "user", Response: api.Success{}},
+	"PUT /v1/evaluations/:id/rating":          {Summary: "Create or replace the rating of an evaluation", Tag: "evaluations", Auth: "user", Body: api.Ratings{}, Response: api.Success{}},
+	"DELETE /v1/evaluations/:id/rating":       {Summary: "Delete the rating of an evaluation, it can be restored within the undo window", Tag: "evaluations", Auth: "user", Status: 204},
+	"POST /v1/evaluations/:id/rating/restore": {Summary: "Restore the deleted rating of an evaluation within the undo window", Tag: "evaluations", Auth: "user", Response: api.Success{}},
+
+	// v1 moderator / admin
+	"PUT /v1/semesters/current":                                 {Summary: "Replace the current semesters", Tag: "semesters", Auth: "moderator", Body: []string{}, Response: []string{}},
+	"GET /v1/moderation/reviews":                                {Summary: "Reviews waiting for moderation", Tag: "moderation", Auth: "moderator", Response: []api.PendingReview{}},
+	"POST /v1/moderation/reviews/:id/verify":                    {Summary: "Publish a review", Tag: "moderation", Auth: "moderator", Response: api.ReviewRecord{}},
+	"POST /v1/moderation/reviews/:id/reject":                    {Summary: "Reject a review and request changes", Tag: "moderation", Auth: "moderator", Body: api.RejectBody{}, Response: api.ReviewRecord{}},
+	"POST /v1/moderation/reviews/:id/redact":                    {Summary: "Replace the text of a review and publish it, the original is kept as revision and shown to the author", Tag: "moderation", Auth: "moderator", Body: api.RedactBody{}, Response: api.ReviewRecord{}},
+	"GET /v1/moderation/reviews/:id/revisions":                  {Summary: "Former texts of a review, the latest first", Tag: "moderation", Auth: "moderator", Response: []api.ReviewRevision{}},
+	"GET /v1/moderation/reviews/embargoed":                      {Summary: "Verified reviews hidden until the end of their semester", Tag: "moderation", Auth: "moderator", Response: []api.EmbargoedReview{}},
+	"GET /v1/moderation/semester-calendar":                      {Summary: "End dates of semesters, reviews of a running semester are embargoed until its end", Tag: "moderation", Auth: "moderator", Response: []api.SemesterEnd{}},
+	"PUT /v1/moderation/semester-calendar/:semester":            {Summary: "Set the end date of a semester, moving the embargo of its reviews", Tag: "moderation", Auth: "moderator", Body: api.SemesterEndBody{}, Response: api.SemesterEnd{}},
+	"GET /v1/moderation/rating-comments":                        {Summary: "Comments on rating dimensions waiting for moderation", Tag: "moderation", Auth: "moderator", Response: []api.PendingRatingComment{}},
+	"POST /v1/moderation/rating-comments/:id/:dimension/verify": {Summary: "Publish a comment on a rating dimension", Tag: "moderation", Auth: "moderator", Response: api.RatingCommentRecord{}},
+	"POST /v1/moderation/rating-comments/:id/:dimension/reject": {Summary: "Reject a comment on a rating dimension and request changes", Tag: "moderation", Auth: "moderator", Body: api.RejectBody{}, Response: api.RatingCommentRecord{}},
+	"GET /v1/moderation/usage-stats":                            {Summary: "Parsed usage log", Tag: "moderation", Auth: "moderator", Response: api.UsageStats{}},
+	"POST /v1/moderation/scrapes":                               {Summary: "Scrape the VVZ for new courses in the background", Tag: "moderation", Auth: "moderator", Body: api.SemesterBody{}, Response: api.Success{}, Status: 202},
+	"PUT /v1/admin/moderators/:user":                            {Summary: "Make a user moderator", Tag: "admin", Auth: "admin", Response: api.User{}},
+	"POST /v1/admin/courses":                                    {Summary: "Add a course", Tag: "admin", Auth: "admin", Body: api.Course{}, Response: []api.Course{}, Status: 201},
+	"PUT /v1/admin/departments/:code":                           {Summary: "Create or rename a department and move course number prefixes to it", Tag: "admin", Auth: "admin", Body: api.DepartmentBody{}, Response: api.Department{}},
+	"GET /v1/admin/account-deletions":                           {Summary: "Requested account deletions, completed ones only keep the hash of the user id", Tag: "admin", Auth: "admin", Response: []api.AccountDeletion{}},
+	"GET /v1/admin/bans":                                        {Summary: "Bans of users, including expired ones", Tag: "admin", Auth: "admin", Response: []api.UserBan{}},
+	"PUT /v1/admin/bans/:user":                                  {Summary: "Ban a user by unique_id or pseudonym with a full, write or shadow ban, replacing an earlier one", Tag: "admin", Auth: "admin", Body: api.UserBanBody{}, Response: api.UserBan{}},
+	"DELETE /v1/admin/bans/:user":                               {Summary: "Lift the ban of a user", Tag: "admin", Auth: "admin", Response: api.UserBan{}},
+	"GET /v1/admin/rating-dimensions":                           {Summary: "All rating dimensions including retired ones", Tag: "admin", Auth: "admin", Response: []api.RatingDimension{}},
+	"PUT /v1/admin/rating-dimensions/:key":                      {Summary: "Add or relabel a rating dimension, bringing it back if retired", Tag: "admin", Auth: "admin", Body: api.RatingDimensionBody{}, Response: api.RatingDimension{}},
+	"DELETE /v1/admin/rating-dimensions/:key":                   {Summary: "Retire a rating dimension, its values are kept", Tag: "admin", Auth: "admin", Response: api.RatingDimension{}},
+
+	// legacy
+	"GET /all":                                 {Summary: "All verified reviews with their ratings", Tag: "legacy", Response: []sql.GetAllTheDataRow{}},
+	"GET /stats":                               {Summary: "Number of reviewed courses and verified reviews", Tag: "legacy", Response: sql.GetStatsRow{}},
+	"GET /latestReviews":                       {Summary: "Courses ordered by their latest verified review", Tag: "legacy", Response: []sql.GetReviewedCoursesRow{}},
+	"GET /getReviews":                          {Summary: "Verified reviews of a course", Tag: "legacy", Query: []string{"course"}, Response: []sql.GetReviewsRow{}},
+	"GET /getRatings":                          {Summary: "All ratings of a course, none until it has ANONYMITY_THRESHOLD of them", Tag: "legacy", Query: []string{"course"}, Response: []sql.GetCourseRatingsRow{}},
+	"GET /getRatingsAvg":                       {Summary: "Rating averages of a course", Tag: "legacy", Query: []string{"course"}, Response: sql.GetRatingsAvgRow{}},
+	"GET /getAllRatingsAvg":                    {Summary: "Rating averages of all courses, 200 per page", Tag: "legacy", Query: []string{"page", "pageSize", "sort", "minCount", "department"}, Response: []sql.GetAllRatingsAvgRow{}},
+	"GET /courses":                             {Summary: "All courses", Tag: "legacy", Response: []sql.Course{}},
+	"GET /coursesWithReviewAmount":             {Summary: "All courses with their amount of reviews", Tag: "legacy", Response: []sql.GetCoursesWithReviewAmountRow{}},
+	"GET /searchCourses":                       {Summary: "Not implemented", Tag: "legacy", Response: api.Error{}},
+	"GET /currentSemesters":                    {Summary: "Semesters that can currently be selected", Tag: "legacy", Response: []string{}},
+	"GET /courseName":                          {Summary: "Name of a course", Tag: "legacy", Query: []string{"course"}, Response: ""},
+	"GET /coursesWithRatingsOrReviews":         {Summary: "Courses with a verified review or a rating", Tag: "legacy", Response: []sql.GetAllCoursesWithReviewsOrRatingsRow{}},
+	"POST /setUser":                            {Summary: "Create a user", Tag: "legacy", Body: legacyUserBody{}, Response: []sql.User{}},
+	"POST /insertReview":                       {Summary: "Submit a review and rating without login", Tag: "legacy", Body: legacyInsertReviewBody{}, Response: api.Success{}},
+	"GET /auth/getUserData":                    {Summary: "Reviews and ratings of the logged in user", Tag: "legacy", Auth: "user", Response: []sql.GetUserDataRow{}},
+	"GET /auth/recommendations":                {Summary: "Courses the user might like, each with the reason it was suggested", Tag: "legacy", Auth: "user", Response: []api.Recommendation{}},
+	"POST /auth/export":                        {Summary: "Build an archive of all personal data of the user in the background", Tag: "legacy", Auth: "user", Response: api.DataExport{}, Status: 202},
+	"GET /auth/export/:id":                     {Summary: "State of a personal data export with its download links", Tag: "legacy", Auth: "user", Response: api.DataExport{}},
+	"POST /auth/deleteAccount":                 {Summary: "Delete the account after a grace period, mode is delete or detach", Tag: "legacy", Auth: "user", Body: api.AccountDeletionBody{}, Response: api.AccountDeletion{}, Status: 202},
+	"POST /auth/updateReview":                  {Summary: "Create or replace a review", Tag: "legacy", Auth: "user", Body: legacyReviewBody{}, Response: api.Success{}},
+	"POST /auth/deleteRating":                  {Summary: "Delete a rating", Tag: "legacy", Auth: "user", Body: legacyIDBody{}, Response: sql.Rating{}},
+	"POST /auth/deleteReview":                  {Summary: "Delete a review", Tag: "legacy", Auth: "user", Body: legacyIDBody{}, Response: sql.Review{}},
+	"POST /auth/restoreRating":                 {Summary: "Restore a deleted rating within the undo window", Tag: "legacy", Auth: "user", Body: legacyIDBody{}, Response: sql.Rating{}},
+	"POST /auth/restoreReview":                 {Summary: "Restore a deleted review within the undo window", Tag: "legacy", Auth: "user", Body: legacyIDBody{}, Response: sql.Review{}},
+	"POST /auth/updateRating":                  {Summary: "Create or replace a rating", Tag: "legacy", Auth: "user", Body: legacyRatingBody{}, Response: api.Success{}},
+	"POST /auth/updateSemester":                {Summary: "Change the semester of an evaluation", Tag: "legacy", Auth: "user", Body: legacySemesterBody{}, Response: sql.CourseEvaluationMap{}},
+	"POST /auth/moderator/setCurrentSemester":  {Summary: "Replace the current semesters", Tag: "legacy", Auth: "moderator", Body: legacySemesterListBody{}, Response: api.Success{}},
+	"GET /auth/moderator/getUnverifiedReviews": {Summary: "Reviews waiting for moderation", Tag: "legacy", Auth: "moderator", Response: []sql.GetUnverifiedReviewsRow{}},
+	"POST /auth/moderator/redactReview":        {Summary: "Replace the text of a review and publish it", Tag: "legacy", Auth: "moderator", Body: legacyRedactBody{}, Response: sql.Review{}},
+	"POST /auth/moderator/verifyReview":        {Summary: "Publish a review", Tag: "legacy", Auth: "moderator", Body: legacyIDBody{}, Response: sql.Review{}},
+	"POST /auth/moderator/rejectReview":        {Summary: "Reject a review and request changes", Tag: "legacy", Auth: "moderator", Body: legacyRejectBody{}, Response: sql.Review{}},
+	"GET /auth/moderator/usageStats":           {Summary: "Parsed usage log", Tag: "legacy", Auth: "moderator", Response: api.UsageStats{}},
+	"POST /auth/moderator/scrapeCourses":       {Summary: "Scrape the VVZ for new courses in the background", Tag: "legacy", Auth: "moderator", Body: api.SemesterBody{}, Response: api.Success{}},
+	"POST /auth/admin/setModerator":            {Summary: "Make a user moderator", Tag: "legacy", Auth: "admin", Body: legacyUserBody{}, Response: api.Success{}},
+	"POST /auth/admin/addCourse":               {Summary: "Add a course", Tag: "legacy", Auth: "admin", Body: sql.SetCourseParams{}, Response: []sql.Course{}},
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// specBuilder collects named struct schemas into components while walking the types.
+type specBuilder struct {
+	schemas map[string]any
+}
+
+// schema derives the JSON schema of a Go type the way encoding/json would marshal it.
+func (b *specBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(pgtype.Text{}):
+		return map[string]any{"type": "string", "nullable": true}
+	case reflect.TypeOf(pgtype.Int4{}), reflect.TypeOf(pgtype.Int8{}):
+		return map[string]any{"type": "integer", "nullable": true}
+	case reflect.TypeOf(pgtype.Float8{}), reflect.TypeOf(pgtype.Numeric{}):
+		return map[string]any{"type": "number", "nullable": true}
+	case reflect.TypeOf(pgtype.Bool{}):
+		return map[string]any{"type": "boolean", "nullable": true}
+	case reflect.TypeOf(pgtype.Date{}):
+		return map[string]any{"type": "string", "format": "date", "nullable": true}
+	case reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := b.schema(t.Elem())
+		if _, isRef := s["$ref"]; isRef {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, done := b.schemas[t.Name()]; !done {
+			b.schemas[t.Name()] = map[string]any{} // placeholder for recursive types
+			b.schemas[t.Name()] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	// interface{} columns of sqlc, e.g. MAX(date)
+	return map[string]any{}
+}
+
+func (b *specBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	b.fields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// fields adds the JSON properties of a struct, flattening embedded structs like encoding/json.
+func (b *specBuilder) fields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.fields(field.Type, properties)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+	}
+}
+
+func (b *specBuilder) content(v any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(v))}}
+}
+
+// buildOpenAPI generates the spec from the routes registered on the app.
+func buildOpenAPI(app *fiber.App) map[string]any {
+	b := &specBuilder{schemas: map[string]any{}}
+	paths := map[string]any{}
+
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Path == "/openapi.json" || route.Path == "/docs" {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		doc, documented := apiDocs[key]
+		if !documented {
+			log.Println("openapi: undocumented route", key)
+			doc = apiDoc{Summary: "Undocumented", Tag: "undocumented"}
+		}
+
+		legacy := !strings.HasPrefix(route.Path, "/v1") && route.Path != "/"
+		op := map[string]any{
+			"summary":    doc.Summary,
+			"tags":       []string{doc.Tag},
+			"deprecated": legacy,
+		}
+
+		var params []any
+		for _, match := range routeParam.FindAllStringSubmatch(route.Path, -1) {
+			paramSchema := map[string]any{"type": "string"}
+			if match[1] == "id" {
+				paramSchema = map[string]any{"type": "integer", "format": "int32"}
+			}
+			params = append(params, map[string]any{"name": match[1], "in": "path", "required": true, "schema": paramSchema})
+		}
+		for _, query := range doc.Query {
+			params = append(params, map[string]any{"name": query, "in": "query", "schema": map[string]any{"type": "string"}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if doc.Body != nil {
+			op["requestBody"] = map[string]any{"required": true, "content": b.content(doc.Body)}
+		}
+		if doc.Auth != "" {
+			scheme := "bearer"
+			if legacy {
+				scheme = "legacyToken"
+			}
+			op["security"] = []any{map[string]any{scheme: []string{}}}
+			if doc.Auth != "user" {
+				op["description"] = "Needs a " + doc.Auth + " account."
+			}
+		}
+
+		status := doc.Status
+		if status == 0 {
+			status = 200
+		}
+		success := map[string]any{"description": "Success"}
+		if doc.Response != nil && status != 204 {
+			success["content"] = b.content(doc.Response)
+		}
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default":            map[string]any{"description": "Error", "content": b.content(api.Error{})},
+		}
+
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	var tags []any
+	for _, name := range []string{"public", "courses", "departments", "semesters", "evaluations", "moderation", "admin", "legacy"} {
+		tags = append(tags, map[string]any{"name": name})
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Course Review API",
+			"version":     "1",
+			"description": "Routes outside of /v1 are deprecated and answer with a Deprecation header.",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"legacyToken": map[string]any{
+					"type":        "apiKey",
+					"in":          "query",
+					"name":        "token",
+					"description": "Can also be sent as `token` in the JSON body.",
+				},
+			},
+		},
+	}
+}
+
+// registerDocs serves the spec and the docs UI. It has to run after all other routes are registered.
+func registerDocs(app *fiber.App) {
+	spec := buildOpenAPI(app)
+
+	app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		return c.JSON(spec)
+	})
+	app.Get("/docs", func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.Send(docsPage)
+	})
+}
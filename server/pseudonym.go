@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"coursereview/app/generated/sql"
+)
+
+// pseudonymKey is a versioned secret for deriving pseudonyms from the unique_id of tokens.
+type pseudonymKey struct {
+	version string
+	secret  []byte
+}
+
+var (
+	regexPseudonym  = regexp.MustCompile(`^v([0-9]+)_[0-9a-f]{64}$`)
+	regexKeyVersion = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// pseudonymKeys are read from USER_ID_KEYS, a comma separated list of version:secret with
+// the current key first, e.g. "2:newsecret,1:oldsecret". Older keys are only needed until
+// every user logged in once after a rotation. Without valid keys the server doesn't start.
+var pseudonymKeys, pseudonymKeysErr = parsePseudonymKeys(os.Getenv("USER_ID_KEYS"))
+
+func parsePseudonymKeys(config string) ([]pseudonymKey, error) {
+	if config == "" {
+		return nil, errors.New("user id key not set")
+	}
+	var keys []pseudonymKey
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(config, ",") {
+		version, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || secret == "" || !regexKeyVersion.MatchString(version) {
+			return nil, fmt.Errorf("invalid user id key %q, use version:secret", version)
+		}
+		// a version names the pseudonyms of its key, two keys can't share one
+		if seen[version] {
+			return nil, fmt.Errorf("duplicate user id key version %q", version)
+		}
+		seen[version] = true
+		keys = append(keys, pseudonymKey{version: version, secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
+func (k pseudonymKey) pseudonym(uniqueID string) string {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(uniqueID))
+	return "v" + k.version + "_" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// pseudonymize derives the id a user is stored and logged under from their unique_id.
+func pseudonymize(uniqueID string) (string, error) {
+	if pseudonymKeysErr != nil {
+		return "", pseudonymKeysErr
+	}
+	return pseudonymKeys[0].pseudonym(uniqueID), nil
+}
+
+// storedUserID pseudonymizes a unique_id given by an admin, pseudonyms and the ids of
+// anonymous submitters are kept as they are.
+func storedUserID(userID string) (string, error) {
+	if !unpseudonymized(userID) {
+		return userID, nil
+	}
+	return pseudonymize(userID)
+}
+
+// unpseudonymized reports whether a stored user id is still a raw unique_id. Anonymous
+// submitters and detached contributions have random ids that need no pseudonym.
+func unpseudonymized(userID string) bool {
+	return !regexPseudonym.MatchString(userID) && !strings.HasSuffix(userID, "noAuth") && !strings.HasPrefix(userID, "deleted-")
+}
+
+// pseudonymousUser returns the current pseudonym of a user. Users still stored under their
+// raw unique_id or under a pseudonym of an older key are renamed to it.
+func (s *Service) pseudonymousUser(ctx context.Context, uniqueID string) (string, error) {
+	userID, err := pseudonymize(uniqueID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.db.GetUser(ctx, userID); err == nil {
+		return userID, nil
+	}
+	previous := []string{uniqueID}
+	for _, key := range pseudonymKeys[1:] {
+		previous = append(previous, key.pseudonym(uniqueID))
+	}
+	for _, oldID := range previous {
+		if _, err := s.db.GetUser(ctx, oldID); err != nil {
+			continue
+		}
+		if err := s.db.RenameUser(ctx, sql.RenameUserParams{OldID: oldID, NewID: userID}); err != nil {
+			return "", err
+		}
+	}
+	return userID, nil
+}
+
+// Pseudonymize replaces the raw unique_ids stored before pseudonymization by pseudonyms of
+// the current key, in the db and in the usage log. Pseudonyms of older keys can't be
+// rotated without the unique_id, their users are renamed on their next login.
+// Run it once with `go run . pseudonymize` while the server is stopped, it replaces the log file.
+func Pseudonymize() error {
+	ctx := context.Background()
+	if pseudonymKeysErr != nil {
+		return pseudonymKeysErr
+	}
+	pool, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	db := sql.New(tx)
+
+	userIDs, err := db.GetUserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	renamed, outdated := 0, 0
+	for _, userID := range userIDs {
+		if match := regexPseudonym.FindStringSubmatch(userID); match != nil {
+			if match[1] != pseudonymKeys[0].version {
+				outdated++
+			}
+			continue
+		}
+		if !unpseudonymized(userID) {
+			continue
+		}
+		if err := db.RenameUser(ctx, sql.RenameUserParams{OldID: userID, NewID: pseudonymKeys[0].pseudonym(userID)}); err != nil {
+			return err
+		}
+		renamed++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	lines, err := pseudonymizeStatsLog()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pseudonymized %d users and %d usage log lines, %d users wait for their next login to move to key version %s\n",
+		renamed, lines, outdated, pseudonymKeys[0].version)
+	return nil
+}
+
+// pseudonymizeStatsLog rewrites the user_id= and new_user= lines of the usage log that hold
+// a raw unique_id.
+func pseudonymizeStatsLog() (int, error) {
+	file, err := os.Open(statsLogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var out strings.Builder
+	changed := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		parts := strings.Split(line, " ")
+		if len(parts) >= 3 {
+			key, value, _ := strings.Cut(parts[2], "=")
+			if (key == "user_id" || key == "new_user") && unpseudonymized(value) {
+				parts[2] = key + "=" + pseudonymKeys[0].pseudonym(value)
+				line = strings.Join(parts, " ")
+				changed++
+			}
+		}
+		out.WriteString(line + "\n")
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	tmp := statsLogPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(out.String()), 0644); err != nil {
+		return 0, err
+	}
+	return changed, os.Rename(tmp, statsLogPath)
+}
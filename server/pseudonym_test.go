@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePseudonymKeys(t *testing.T) {
+	tests := []struct {
+		config   string
+		versions []string
+		valid    bool
+	}{
+		{"2:newsecret,1:oldsecret", []string{"2", "1"}, true},
+		{" 3:secret ", []string{"3"}, true},
+		{"", nil, false},
+		{"secret", nil, false},
+		{"v2:secret", nil, false},
+		{"2:", nil, false},
+		{"2:secret,1:", nil, false},
+		{"2:secret,2:other", nil, false},
+	}
+	for _, tt := range tests {
+		keys, err := parsePseudonymKeys(tt.config)
+		if (err == nil) != tt.valid {
+			t.Errorf("parsePseudonymKeys(%q) error %v, want valid %v", tt.config, err, tt.valid)
+			continue
+		}
+		if len(keys) != len(tt.versions) {
+			t.Errorf("parsePseudonymKeys(%q) = %d keys, want %d", tt.config, len(keys), len(tt.versions))
+			continue
+		}
+		for i, key := range keys {
+			if key.version != tt.versions[i] {
+				t.Errorf("parsePseudonymKeys(%q) key %d has version %s, want %s", tt.config, i, key.version, tt.versions[i])
+			}
+		}
+	}
+}
+
+func TestPseudonym(t *testing.T) {
+	key := pseudonymKey{version: "2", secret: []byte("secret")}
+	pseudonym := key.pseudonym("alice")
+	if !regexPseudonym.MatchString(pseudonym) || pseudonym[:3] != "v2_" {
+		t.Errorf("got %q, want a version 2 pseudonym", pseudonym)
+	}
+	if pseudonym == key.pseudonym("bob") || pseudonym == (pseudonymKey{version: "2", secret: []byte("other")}).pseudonym("alice") {
+		t.Error("pseudonyms of another user or key collide")
+	}
+	for id, want := range map[string]bool{pseudonym: false, "anon-noAuth": false, "deleted-0123": false, "alice": true} {
+		if got := unpseudonymized(id); got != want {
+			t.Errorf("unpseudonymized(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// withPseudonymKeys replaces the keys for the duration of the test.
+func withPseudonymKeys(t *testing.T, config string) []pseudonymKey {
+	t.Helper()
+	keys, err := parsePseudonymKeys(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, previousErr := pseudonymKeys, pseudonymKeysErr
+	pseudonymKeys, pseudonymKeysErr = keys, nil
+	t.Cleanup(func() { pseudonymKeys, pseudonymKeysErr = previous, previousErr })
+	return keys
+}
+
+func TestPseudonymousUserMovesToCurrentKey(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	keys := withPseudonymKeys(t, "3:current,2:previous,1:first")
+	tests := []struct {
+		uniqueID string
+		storedAs string
+	}{
+		{"alice", "alice"},
+		{"bob", keys[1].pseudonym("bob")},
+		{"carol", keys[2].pseudonym("carol")},
+		{"dave", keys[0].pseudonym("dave")},
+	}
+	for _, tt := range tests {
+		evaluation := seedContributions(t, pool, tt.storedAs)
+		userID, err := svc.pseudonymousUser(context.Background(), tt.uniqueID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := keys[0].pseudonym(tt.uniqueID); userID != want {
+			t.Errorf("%s: got %q, want %q", tt.uniqueID, userID, want)
+		}
+		if n := countRows(t, pool, "SELECT COUNT(*) FROM course_evaluation_map WHERE id = $1 AND user_id = $2", evaluation, userID); n != 1 {
+			t.Errorf("%s: evaluation stored as %s was not moved to the current pseudonym", tt.uniqueID, tt.storedAs)
+		}
+		if tt.storedAs != userID && countRows(t, pool, "SELECT COUNT(*) FROM users WHERE user_id = $1", tt.storedAs) != 0 {
+			t.Errorf("%s: the user under the old id is left", tt.uniqueID)
+		}
+	}
+}
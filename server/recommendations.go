@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	recommendationCount = 10
+	// similarities between courses fewer users rated both of are too noisy
+	similarityMinCommonRaters = 2
+	similarityInterval        = 6 * time.Hour
+	// courses need this many "recommended" ratings to count as popular
+	popularMinRatings = 3
+)
+
+// RefreshSimilarities recomputes course_similarities from the "recommended" and "engaging"
+// ratings of all users. Pairs that lost their common raters are removed afterwards.
+func (s *Service) RefreshSimilarities(ctx context.Context) error {
+	computedAt := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	err := s.db.ComputeCourseSimilarities(ctx, sql.ComputeCourseSimilaritiesParams{ComputedAt: computedAt, MinCommonRaters: similarityMinCommonRaters})
+	if err != nil {
+		return err
+	}
+	return s.db.DeleteCourseSimilaritiesBefore(ctx, computedAt)
+}
+
+// Recommendations suggests courses the user hasn't evaluated yet. Courses similar to the
+// ones the user rated come first, the rest is filled with popular courses of the user's
+// departments, or of all departments for users without any evaluation.
+func (s *Service) Recommendations(ctx context.Context, userID string) ([]api.Recommendation, error) {
+	similar, err := s.db.GetRecommendations(ctx, sql.GetRecommendationsParams{UserID: userID, MaxResults: recommendationCount})
+	if err != nil {
+		return nil, err
+	}
+	recommendations := make([]api.Recommendation, 0, recommendationCount)
+	seen := map[string]bool{}
+	for _, row := range similar {
+		seen[row.CourseNumber] = true
+		recommendations = append(recommendations, api.Recommendation{
+			CourseNumber: row.CourseNumber,
+			CourseName:   row.CourseName,
+			Score:        numericPtr(row.Predicted),
+			Reason:       api.ReasonSimilar,
+			Explanation: fmt.Sprintf("You rated %s %s with %.1f, students who rated it alike also liked this course",
+				row.BasedOn, row.BasedOnName, row.BasedOnScore),
+			BasedOn: &api.RecommendationSource{
+				CourseNumber: row.BasedOn,
+				CourseName:   row.BasedOnName,
+				Similarity:   row.BasedOnSimilarity,
+				YourScore:    row.BasedOnScore,
+			},
+		})
+	}
+	if len(recommendations) >= recommendationCount {
+		return recommendations, nil
+	}
+
+	departments, err := s.db.GetUserDepartments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	popular, err := s.db.GetPopularCourses(ctx, sql.GetPopularCoursesParams{
+		MinCount:    popularMinRatings,
+		Departments: departments,
+		UserID:      userID,
+		MaxResults:  recommendationCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range popular {
+		if seen[row.CourseNumber] || len(recommendations) >= recommendationCount {
+			continue
+		}
+		score := numericPtr(row.Recommended)
+		explanation := fmt.Sprintf("Popular course, recommended with %.1f on average by %d students", *score, row.RatingCount)
+		if len(departments) > 0 && row.DepartmentCode.Valid {
+			explanation = fmt.Sprintf("Popular in %s, where you took courses, recommended with %.1f on average by %d students",
+				row.DepartmentCode.String, *score, row.RatingCount)
+		}
+		recommendations = append(recommendations, api.Recommendation{
+			CourseNumber: row.CourseNumber,
+			CourseName:   row.CourseName,
+			Score:        score,
+			Reason:       api.ReasonPopular,
+			Explanation:  explanation,
+			Department:   textPtr(row.DepartmentCode),
+		})
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrRedactionUnchanged = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "The redacted review must differ from the original"}
+
+// actionRedactReview is the name of redactions in actions, the event log is the audit log.
+const actionRedactReview = "redact_review"
+
+// RedactReview lets a moderator replace the text of a review instead of rejecting it, e.g.
+// to remove the name of a TA. The original is kept as revision and shown to the author with
+// the reason, the public sees the review annotated as redacted. A review that isn't
+// published yet is published with the change.
+func (s *Service) RedactReview(ctx context.Context, moderatorID string, evalID int32, review, reason string) (sql.Review, error) {
+	review, reason = strings.TrimSpace(review), strings.TrimSpace(reason)
+	if review == "" {
+		return sql.Review{}, ErrReviewEmpty
+	}
+	notFound := &ServiceError{Status: fiber.StatusNotFound, Message: "Review not found"}
+	var redacted sql.Review
+	err := s.inTx(ctx, func(db *sql.Queries) error {
+		original, err := db.GetReviewWithId(ctx, evalID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound
+		}
+		if err != nil {
+			return err
+		}
+		if original == review {
+			return ErrRedactionUnchanged
+		}
+		err = db.AddReviewRevision(ctx, sql.AddReviewRevisionParams{EvaluationID: evalID, Reason: pgtype.Text{String: reason, Valid: reason != ""}})
+		if err != nil {
+			return err
+		}
+		// the author may have deleted the review since it was read
+		redacted, err = db.RedactReview(ctx, sql.RedactReviewParams{EvaluationID: evalID, Review: review})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound
+		}
+		if err != nil {
+			return err
+		}
+		if redacted.Published.Status != sql.StatusVerified {
+			redacted, err = db.VerifyReview(ctx, evalID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return db.LogEvent(ctx, sql.LogEventParams{
+			EvaluationID: pgtype.Int4{Int32: evalID, Valid: true},
+			UserID:       pgtype.Text{String: moderatorID, Valid: true},
+			Action:       actionRedactReview,
+			Info:         pgtype.Text{String: reason, Valid: reason != ""},
+		})
+	})
+	if err != nil {
+		return redacted, err
+	}
+	s.publish(EventReviewChanged)
+	return redacted, nil
+}
+
+// ReviewRevisions lists the former texts of a review, the latest first.
+func (s *Service) ReviewRevisions(ctx context.Context, evalID int32) ([]sql.ReviewRevision, error) {
+	return s.db.GetReviewRevisions(ctx, evalID)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+)
+
+const (
+	// safety net for reviews verified while the job was down, it also runs after every review change
+	reviewAnalysisInterval = time.Hour
+	// keyphrases stored per review
+	reviewPhraseMax = 8
+	// words a keyphrase may have at most
+	phraseMaxWords = 3
+	// phrases in the summary per list, each must appear in this many reviews
+	summaryPhraseCount    = 10
+	summaryPhraseMinCount = 2
+	// mean sentiment a phrase needs to count as positive or negative
+	summaryPhraseMinSentiment = 0.2
+	// smooths the sentiment sum into -1 to 1, higher values need more evidence for extremes
+	sentimentAlpha = 4
+)
+
+// sentimentLexicon scores opinion words of course reviews from -2 to 2. German words are
+// also found after stripping their inflection, see lexiconScore.
+var sentimentLexicon = map[string]float64{
+	// German
+	"gut": 1, "super": 2, "toll": 2, "spannend": 1.5, "interessant": 1.5, "hilfreich": 1.5,
+	"empfehlenswert": 2, "verständlich": 1, "klar": 1, "strukturiert": 1, "fair": 1,
+	"motiviert": 1, "engagiert": 1.5, "lehrreich": 1.5, "angenehm": 1, "machbar": 0.5,
+	"einfach": 0.5, "genial": 2, "hervorragend": 2, "ausgezeichnet": 2, "exzellent": 2,
+	"lohnt": 1, "gefallen": 1, "gefällt": 1, "spass": 1.5, "spaß": 1.5, "empfehlen": 1.5,
+	"schlecht": -1.5, "langweilig": -1.5, "schwierig": -0.5, "schwer": -0.5, "unklar": -1,
+	"chaotisch": -1.5, "unfair": -1.5, "mühsam": -1, "anstrengend": -0.5, "unverständlich": -1.5,
+	"enttäuschend": -1.5, "katastrophe": -2, "katastrophal": -2, "schrecklich": -2,
+	"furchtbar": -2, "nutzlos": -1.5, "veraltet": -1, "unorganisiert": -1.5, "stressig": -1,
+	"überfordert": -1, "frustrierend": -1.5, "zeitaufwendig": -0.5, "aufwendig": -0.5,
+	"mangelhaft": -1.5, "schade": -1, "nervig": -1.5, "unnötig": -1, "leider": -0.5,
+	// English
+	"good": 1, "great": 2, "excellent": 2, "amazing": 2, "awesome": 2, "interesting": 1.5,
+	"helpful": 1.5, "clear": 1, "structured": 1, "engaging": 1.5, "enjoyed": 1.5, "enjoy": 1,
+	"fun": 1.5, "recommend": 1.5, "recommended": 1.5, "easy": 0.5, "useful": 1.5,
+	"love": 2, "loved": 2, "nice": 1, "best": 2, "worth": 1, "motivated": 1,
+	"bad": -1.5, "boring": -1.5, "difficult": -0.5, "hard": -0.5, "confusing": -1.5,
+	"unclear": -1, "chaotic": -1.5, "terrible": -2, "awful": -2, "horrible": -2,
+	"useless": -1.5, "outdated": -1, "disorganized": -1.5, "stressful": -1, "frustrating": -1.5,
+	"tedious": -1, "disappointing": -1.5, "worst": -2, "poor": -1.5, "unnecessary": -1,
+	"hate": -2, "hated": -2, "waste": -1.5, "unfortunately": -0.5,
+}
+
+// negations flip the sentiment of the words following them
+var negations = toSet(strings.Fields(`
+	nicht kein keine keinen keinem keiner nie niemals nichts not no never nothing hardly isn wasn don doesn didn
+`))
+
+// intensifiers strengthen the sentiment word following them
+var intensifiers = toSet(strings.Fields(`
+	sehr extrem wirklich echt total besonders äusserst äußerst very really extremely especially highly so too
+`))
+
+const negationWindow = 3
+
+var regexSentenceEnd = regexp.MustCompile(`[.!?;:\n]+`)
+
+// lexiconScore looks a word up in the lexicon, falling back to its German stem.
+func lexiconScore(word string) (float64, bool) {
+	if score, ok := sentimentLexicon[word]; ok {
+		return score, true
+	}
+	for _, ending := range []string{"em", "en", "er", "es", "e"} {
+		if stem, ok := strings.CutSuffix(word, ending); ok {
+			if score, ok := sentimentLexicon[stem]; ok {
+				return score, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func normalizeSentiment(sum float64) float64 {
+	return sum / math.Sqrt(sum*sum+sentimentAlpha)
+}
+
+// analyzedPhrase is a keyphrase of a review together with the sentiment of its sentences.
+type analyzedPhrase struct {
+	Phrase    string  `json:"phrase"`
+	Sentiment float64 `json:"sentiment"`
+}
+
+type reviewAnalysis struct {
+	language  string
+	sentiment float64
+	phrases   []analyzedPhrase
+}
+
+// analyzeReview scores the sentiment of a review and extracts its keyphrases. Keyphrases
+// are runs of up to three meaningful words between stopwords and opinion words, ranked like
+// RAKE by the degree of their words. Each one keeps the sentiment of the sentences it was
+// used in, so "prüfung" in "die Prüfung war unfair" counts as negative.
+func analyzeReview(text string) reviewAnalysis {
+	type candidate struct {
+		words     []string
+		sentiment float64
+		count     int
+	}
+	candidates := map[string]*candidate{}
+	var order []string
+	var total float64
+
+	for _, sentence := range regexSentenceEnd.Split(text, -1) {
+		var sum float64
+		var phrases [][]string
+		var run []string
+		negated, boost := 0, 1.0
+		flush := func() {
+			if len(run) > 0 && len(run) <= phraseMaxWords {
+				phrases = append(phrases, run)
+			}
+			run = nil
+		}
+		for _, word := range words(sentence) {
+			if score, ok := lexiconScore(word); ok {
+				if negated > 0 {
+					score = -score * 0.75
+				}
+				sum += score * boost
+				boost = 1
+				flush()
+			} else if isTerm(word) && !intensifiers[word] {
+				run = append(run, word)
+			} else {
+				flush()
+			}
+			if negated > 0 {
+				negated--
+			}
+			if negations[word] {
+				negated = negationWindow
+			}
+			if intensifiers[word] {
+				boost = 1.5
+			}
+		}
+		flush()
+
+		total += sum
+		for _, phrase := range phrases {
+			key := strings.Join(phrase, " ")
+			c, ok := candidates[key]
+			if !ok {
+				c = &candidate{words: phrase}
+				candidates[key] = c
+				order = append(order, key)
+			}
+			c.sentiment += normalizeSentiment(sum)
+			c.count++
+		}
+	}
+
+	// RAKE word score: how often a word appears in long phrases relative to its frequency
+	degree, frequency := map[string]float64{}, map[string]float64{}
+	for _, c := range candidates {
+		for _, word := range c.words {
+			degree[word] += float64(len(c.words) * c.count)
+			frequency[word] += float64(c.count)
+		}
+	}
+	score := func(key string) float64 {
+		var sum float64
+		for _, word := range candidates[key].words {
+			sum += degree[word] / frequency[word]
+		}
+		return sum * float64(candidates[key].count)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return score(order[a]) > score(order[b]) })
+	if len(order) > reviewPhraseMax {
+		order = order[:reviewPhraseMax]
+	}
+
+	analysis := reviewAnalysis{language: detectLanguage(text), sentiment: normalizeSentiment(total)}
+	for _, key := range order {
+		c := candidates[key]
+		analysis.phrases = append(analysis.phrases, analyzedPhrase{Phrase: key, Sentiment: c.sentiment / float64(c.count)})
+	}
+	return analysis
+}
+
+// AnalyzeReviews analyzes the verified reviews that are new or changed since their last analysis.
+func (s *Service) AnalyzeReviews(ctx context.Context) error {
+	rows, err := s.db.GetUnanalyzedReviews(ctx)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		analysis := analyzeReview(row.Review)
+		phrases, err := json.Marshal(analysis.phrases)
+		if err != nil {
+			return err
+		}
+		err = s.db.SetReviewAnalysis(ctx, sql.SetReviewAnalysisParams{
+			EvaluationID: row.EvaluationID,
+			ReviewHash:   row.ReviewHash,
+			Language:     analysis.language,
+			Sentiment:    analysis.sentiment,
+			Phrases:      phrases,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		s.publish(EventSummaryChanged)
+	}
+	return nil
+}
+
+// CourseSummary aggregates the analyses of the verified reviews of a course.
+func (s *Service) CourseSummary(ctx context.Context, course string) (api.CourseSummary, error) {
+	rows, err := s.db.GetCourseReviewAnalyses(ctx, course)
+	if err != nil {
+		return api.CourseSummary{}, err
+	}
+
+	summary := api.CourseSummary{CourseNumber: course, Languages: map[string]int32{}}
+	phrases := map[string]*api.SummaryPhrase{}
+	semesters := map[string]*api.SemesterSentiment{}
+	var total float64
+	for _, row := range rows {
+		var reviewPhrases []analyzedPhrase
+		if err := json.Unmarshal(row.Phrases, &reviewPhrases); err != nil {
+			return api.CourseSummary{}, err
+		}
+		for _, phrase := range reviewPhrases {
+			p, ok := phrases[phrase.Phrase]
+			if !ok {
+				p = &api.SummaryPhrase{Phrase: phrase.Phrase}
+				phrases[phrase.Phrase] = p
+			}
+			p.Count++
+			p.Sentiment += phrase.Sentiment
+		}
+
+		summary.ReviewCount++
+		summary.Languages[row.Language]++
+		total += row.Sentiment
+		if row.Semester.Valid {
+			entry, ok := semesters[row.Semester.String]
+			if !ok {
+				entry = &api.SemesterSentiment{Semester: row.Semester.String}
+				semesters[row.Semester.String] = entry
+			}
+			entry.ReviewCount++
+			entry.Sentiment += row.Sentiment
+		}
+	}
+	if summary.ReviewCount > 0 {
+		mean := total / float64(summary.ReviewCount)
+		summary.Sentiment = &mean
+	}
+
+	var frequent []api.SummaryPhrase
+	for _, p := range phrases {
+		if p.Count >= summaryPhraseMinCount {
+			p.Sentiment /= float64(p.Count)
+			frequent = append(frequent, *p)
+		}
+	}
+	sort.Slice(frequent, func(a, b int) bool {
+		if frequent[a].Count != frequent[b].Count {
+			return frequent[a].Count > frequent[b].Count
+		}
+		return frequent[a].Phrase < frequent[b].Phrase
+	})
+	summary.TopPhrases = []api.SummaryPhrase{}
+	summary.PositivePhrases = []api.SummaryPhrase{}
+	summary.NegativePhrases = []api.SummaryPhrase{}
+	for _, p := range frequent {
+		if len(summary.TopPhrases) < summaryPhraseCount {
+			summary.TopPhrases = append(summary.TopPhrases, p)
+		}
+		if p.Sentiment >= summaryPhraseMinSentiment && len(summary.PositivePhrases) < summaryPhraseCount {
+			summary.PositivePhrases = append(summary.PositivePhrases, p)
+		}
+		if p.Sentiment <= -summaryPhraseMinSentiment && len(summary.NegativePhrases) < summaryPhraseCount {
+			summary.NegativePhrases = append(summary.NegativePhrases, p)
+		}
+	}
+
+	summary.Trend = []api.SemesterSentiment{}
+	for _, entry := range semesters {
+		// a semester with few reviews would give away the semester of each
+		if !publishable(entry.ReviewCount) {
+			continue
+		}
+		entry.Sentiment /= float64(entry.ReviewCount)
+		summary.Trend = append(summary.Trend, *entry)
+	}
+	sort.Slice(summary.Trend, func(a, b int) bool {
+		return compareSemesters(summary.Trend[a].Semester, summary.Trend[b].Semester) < 0
+	})
+	return summary, nil
+}
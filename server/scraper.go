@@ -6,6 +6,7 @@ import (
 	"coursereview/app/generated/sql"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"os"
@@ -14,11 +15,14 @@ import (
 
 	"github.com/gocolly/colly"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var webhookURL = os.Getenv("DISCORD_WEBHOOK_URL")
 var regexRuleCourseNumber = regexp.MustCompile("<b>(\\d{3}-\\d{4}-[A-Z0-9]{3})<\\/b>")
 var regexRuleCourseName = regexp.MustCompile("\\w{2}\">(.*?)<\\/a><\\/b>")
+var regexRuleECTS = regexp.MustCompile("(\\d+(?:\\.\\d+)?)\\s*KP")
+var regexRuleLecturer = regexp.MustCompile("dozide=(\\d+)[^\"]*\">([^<]+)<\\/a>")
 
 const (
 	username     = "VVZ Scrape-Inator 6000"
@@ -110,6 +114,9 @@ func routine(semester string, context context.Context, db *sql.Queries) {
 
 	var courseNumbers []string
 	var courseNames []string
+	// lecturers and credits listed in the row of a course, keyed by its number
+	lecturers := map[string][][]string{}
+	credits := map[string]string{}
 
 	collector.OnHTML("tr", func(e *colly.HTMLElement) {
 		tableRow, _ := e.DOM.Html()
@@ -127,8 +134,22 @@ func routine(semester string, context context.Context, db *sql.Queries) {
 				courseNames = append(courseNames, match[1])
 			}
 		}
+
+		if len(matchesCourseNumber) == 1 {
+			number := matchesCourseNumber[0][1]
+			lecturers[number] = append(lecturers[number], regexRuleLecturer.FindAllStringSubmatch(tableRow, -1)...)
+			if match := regexRuleECTS.FindStringSubmatch(tableRow); match != nil {
+				credits[number] = match[1]
+			}
+		}
 	})
 
+	// lecturers are stored with the semester in our format, the VVZ wants its own
+	parsedSemester, semesterErr := ParseSemester(semester)
+	if semesterErr == nil {
+		semester = parsedSemester.VVZ()
+	}
+
 	err := collector.Visit(vvzListUrl(semester, language))
 	if err != nil {
 		fmt.Println("Error visiting URL:", err)
@@ -144,17 +165,46 @@ func routine(semester string, context context.Context, db *sql.Queries) {
 	for i, item := range courseNumbers {
 		// check db
 		_, err := db.GetCourseName(context, item)
-		if err == nil {
+		if err != nil {
+			// add course to db
+			_, err = db.AddCourse(context, sql.AddCourseParams{CourseNumber: item, CourseName: courseNames[i]})
+			if err != nil {
+				fmt.Println("Error adding course to DB:", err)
+				sendScrapingError(item)
+				continue
+			}
+			newCourses++
+
+			// new teaching units show up as their own department until an admin names them
+			if number, err := ParseCourseNumber(item); err == nil {
+				if err := db.AddDepartmentPrefix(context, number.Prefix); err != nil {
+					fmt.Println("Error adding department to DB:", err)
+				}
+			}
+		}
+
+		if semesterErr != nil {
 			continue
 		}
-		// add course to db
-		_, err = db.AddCourse(context, sql.AddCourseParams{CourseNumber: item, CourseName: courseNames[i]})
+		var ects pgtype.Numeric
+		if value, ok := credits[item]; ok {
+			ects.Scan(value)
+		}
+		err = db.SetCourseOffering(context, sql.SetCourseOfferingParams{CourseNumber: item, Semester: parsedSemester.String(), Ects: ects})
 		if err != nil {
-			fmt.Println("Error adding course to DB:", err)
-			sendScrapingError(item)
-			continue
+			fmt.Println("Error adding course offering to DB:", err)
+		}
+		for _, match := range lecturers[item] {
+			lecturer, err := db.SetLecturer(context, sql.SetLecturerParams{VvzID: match[1], Name: html.UnescapeString(match[2])})
+			if err != nil {
+				fmt.Println("Error adding lecturer to DB:", err)
+				continue
+			}
+			err = db.AddCourseLecturer(context, sql.AddCourseLecturerParams{CourseNumber: item, LecturerID: lecturer.ID, Semester: parsedSemester.String()})
+			if err != nil {
+				fmt.Println("Error adding course lecturer to DB:", err)
+			}
 		}
-		newCourses++
 	}
 
 	sendScrapingEnd(newCourses, semester)
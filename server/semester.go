@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	semesterPattern    = regexp.MustCompile(`^(\d{2})(FS|HS)$`)
+	vvzSemesterPattern = regexp.MustCompile(`^(\d{4})(S|W)$`)
+)
+
+var ErrInvalidSemester = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid semester, expected a format like 23HS"}
+
+// Semester is a semester in the format of course_evaluation_map, 24FS for the spring and
+// 24HS for the autumn semester of 2024. The VVZ calls them 2024S and 2024W.
+type Semester struct {
+	Year   int
+	Autumn bool
+}
+
+// ParseSemester accepts both our and the VVZ format.
+func ParseSemester(s string) (Semester, error) {
+	if match := semesterPattern.FindStringSubmatch(s); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return Semester{Year: 2000 + year, Autumn: match[2] == "HS"}, nil
+	}
+	if match := vvzSemesterPattern.FindStringSubmatch(s); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return Semester{Year: year, Autumn: match[2] == "W"}, nil
+	}
+	return Semester{}, ErrInvalidSemester
+}
+
+func (s Semester) String() string {
+	if s.Autumn {
+		return fmt.Sprintf("%02dHS", s.Year%100)
+	}
+	return fmt.Sprintf("%02dFS", s.Year%100)
+}
+
+// VVZ returns the semkez the VVZ uses in its URLs.
+func (s Semester) VVZ() string {
+	if s.Autumn {
+		return fmt.Sprintf("%dW", s.Year)
+	}
+	return fmt.Sprintf("%dS", s.Year)
+}
+
+func (s Semester) Before(other Semester) bool {
+	if s.Year != other.Year {
+		return s.Year < other.Year
+	}
+	return !s.Autumn && other.Autumn
+}
+
+// compareSemesters orders semesters chronologically, unknown formats go last.
+func compareSemesters(a, b string) int {
+	semA, errA := ParseSemester(a)
+	semB, errB := ParseSemester(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	case semA.Before(semB):
+		return -1
+	case semB.Before(semA):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestParseSemester(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Semester
+		ok   bool
+	}{
+		{"23HS", Semester{Year: 2023, Autumn: true}, true},
+		{"24FS", Semester{Year: 2024}, true},
+		{"05HS", Semester{Year: 2005, Autumn: true}, true},
+		{"2024S", Semester{Year: 2024}, true},
+		{"2023W", Semester{Year: 2023, Autumn: true}, true},
+		{"23hs", Semester{}, false},
+		{"23WS", Semester{}, false},
+		{"2023HS", Semester{}, false},
+		{"23W", Semester{}, false},
+		{"", Semester{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemester(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSemester(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestSemesterFormats(t *testing.T) {
+	tests := []struct {
+		semester Semester
+		ours     string
+		vvz      string
+	}{
+		{Semester{Year: 2023, Autumn: true}, "23HS", "2023W"},
+		{Semester{Year: 2024}, "24FS", "2024S"},
+		{Semester{Year: 2005}, "05FS", "2005S"},
+	}
+	for _, tt := range tests {
+		if got := tt.semester.String(); got != tt.ours {
+			t.Errorf("%+v.String() = %q, want %q", tt.semester, got, tt.ours)
+		}
+		if got := tt.semester.VVZ(); got != tt.vvz {
+			t.Errorf("%+v.VVZ() = %q, want %q", tt.semester, got, tt.vvz)
+		}
+	}
+}
+
+func TestSemesterBefore(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"23FS", "23HS", true},
+		{"23HS", "24FS", true},
+		{"22HS", "23FS", true},
+		{"23HS", "23FS", false},
+		{"24FS", "23HS", false},
+		{"23HS", "23HS", false},
+	}
+	for _, tt := range tests {
+		a, _ := ParseSemester(tt.a)
+		b, _ := ParseSemester(tt.b)
+		if got := a.Before(b); got != tt.want {
+			t.Errorf("%s.Before(%s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const statsLogPath = "logs/stats.log"
+
+// ServiceError is an error that knows which HTTP status it should be answered with.
+type ServiceError struct {
+	Status  int
+	Message string
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrReviewEmpty        = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Review cannot be empty"}
+	ErrRatingsNotSet      = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Ratings not set"}
+	ErrRatingsEmpty       = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Ratings cannot be empty"}
+	ErrRatingsOutOfRange  = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Ratings must lie within the scale of their dimension, 1 to 5 for the built-in ones"}
+	ErrHoursOutOfRange    = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Hours per week must be between 0.5 and 60"}
+	ErrEvaluationNotFound = &ServiceError{Status: fiber.StatusNotFound, Message: "Evaluation not found"}
+	ErrCourseNotFound     = &ServiceError{Status: fiber.StatusNotFound, Message: "Course not found"}
+	ErrMissingCourse      = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Course number missing"}
+	ErrMissingSemester    = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Semester missing"}
+	ErrMissingAnonymousID = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Anonymous id missing"}
+	ErrInvalidPage        = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid page"}
+	ErrInvalidSort        = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid sort, use a rating dimension, count or courseNumber"}
+	ErrInvalidDepartment  = &ServiceError{Status: fiber.StatusUnprocessableEntity, Message: "Invalid department, use the three digits a course number starts with"}
+)
+
+// Event is published by the service whenever data shown on public routes changes.
+type Event string
+
+const (
+	EventReviewChanged     Event = "review"
+	EventRatingChanged     Event = "rating"
+	EventCourseChanged     Event = "course"
+	EventSemesterChanged   Event = "semester"
+	EventDepartmentChanged Event = "department"
+	// published by the review analysis job, after the events that triggered it
+	EventSummaryChanged Event = "summary"
+	// published by the text similarity job
+	EventSimilarityChanged Event = "similarity"
+	EventDimensionChanged  Event = "dimension"
+)
+
+// Service holds the logic shared by the legacy routes and the /v1 router.
+// Handlers only parse requests and shape responses.
+type Service struct {
+	db        *sql.Queries
+	pool      *pgxpool.Pool
+	stats     *log.Logger
+	listeners []func(Event)
+}
+
+func NewService(pool *pgxpool.Pool, stats *log.Logger) *Service {
+	return &Service{db: sql.New(pool), pool: pool, stats: stats}
+}
+
+// inTx runs fn with queries bound to one transaction, which is committed if fn succeeds.
+func (s *Service) inTx(ctx context.Context, fn func(db *sql.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(s.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Subscribe registers a listener for write-side events. Listeners run synchronously
+// after the change was stored and must not block.
+func (s *Service) Subscribe(listener func(Event)) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Service) publish(events ...Event) {
+	for _, event := range events {
+		for _, listener := range s.listeners {
+			listener(event)
+		}
+	}
+}
+
+func (r Ratings) empty() bool {
+	return len(r.values()) == 0 && r.HoursPerWeek == nil
+}
+
+// valid reports whether no dimension is negative, 0 meaning "not set". The scale of every
+// dimension is checked by checkRatingValues.
+func (r Ratings) valid() bool {
+	for _, v := range []int32{r.Recommended, r.Engaging, r.Difficulty, r.Effort, r.Resources} {
+		if v < 0 {
+			return false
+		}
+	}
+	for _, v := range r.Values {
+		if v < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// validHours reports whether the workload is unset or within hoursPerWeekMin and hoursPerWeekMax.
+func (r Ratings) validHours() bool {
+	return r.HoursPerWeek == nil || (*r.HoursPerWeek >= hoursPerWeekMin && *r.HoursPerWeek <= hoursPerWeekMax)
+}
+
+// // // // // // //
+// public reading //
+// // // // // // //
+
+func (s *Service) AllData(ctx context.Context) ([]sql.GetAllTheDataRow, error) {
+	rows, err := s.db.GetAllTheData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.anonymizeEvaluations(ctx, rows)
+}
+
+func (s *Service) Stats(ctx context.Context) (sql.GetStatsRow, error) {
+	return s.db.GetStats(ctx)
+}
+
+func (s *Service) LatestReviews(ctx context.Context) ([]sql.GetReviewedCoursesRow, error) {
+	return s.db.GetReviewedCourses(ctx)
+}
+
+// Reviews returns the verified reviews of a course, with only the year of semesters too
+// few students evaluated.
+func (s *Service) Reviews(ctx context.Context, course string) ([]sql.GetReviewsRow, error) {
+	reviews, err := s.db.GetReviews(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.cohorts(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		reviews[i].Semester = c.semester(course, reviews[i].Semester)
+	}
+	return reviews, nil
+}
+
+// Ratings returns the single ratings of a course, none until it has anonymityThreshold of them.
+func (s *Service) Ratings(ctx context.Context, course string) ([]sql.GetCourseRatingsRow, error) {
+	c, err := s.cohorts(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	if !publishable(c.ratings(course)) {
+		return []sql.GetCourseRatingsRow{}, nil
+	}
+	return s.db.GetCourseRatings(ctx, course)
+}
+
+// RatingsAvg returns the rating averages of a course, all unset until it has
+// anonymityThreshold ratings.
+func (s *Service) RatingsAvg(ctx context.Context, course string) (sql.GetRatingsAvgRow, error) {
+	c, err := s.cohorts(ctx, course)
+	if err != nil {
+		return sql.GetRatingsAvgRow{}, err
+	}
+	if !publishable(c.ratings(course)) {
+		return sql.GetRatingsAvgRow{}, nil
+	}
+	return s.db.GetRatingsAvg(ctx, course)
+}
+
+const ratingsAvgMaxPageSize = 200
+
+// RatingsAvgFilter selects and orders a page of AllRatingsAvg.
+type RatingsAvgFilter struct {
+	Page     int // starts at 1
+	PageSize int // at most ratingsAvgMaxPageSize, 0 means the maximum
+	// Sort is a rating dimension, "count" or "courseNumber", a "-" prefix sorts descending.
+	Sort       string
+	MinCount   int32  // minimum number of ratings of a course
+	Department string // course number prefix, e.g. "263"
+}
+
+// ratingsAvgSortKeys are the sort keys besides the active dimensions, a retired built-in
+// dimension can't be sorted by anymore.
+var ratingsAvgSortKeys = map[string]bool{"courseNumber": true, "count": true}
+
+// AllRatingsAvg returns a page of per-dimension rating averages and the number of courses
+// matching the filter. Missing dimensions of a rating only leave out that dimension.
+func (s *Service) AllRatingsAvg(ctx context.Context, filter RatingsAvgFilter) ([]sql.GetAllRatingsAvgRow, int64, error) {
+	if filter.Page < 1 || filter.PageSize < 0 || filter.PageSize > ratingsAvgMaxPageSize || filter.MinCount < 0 {
+		return nil, 0, ErrInvalidPage
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = ratingsAvgMaxPageSize
+	}
+	descending := strings.HasPrefix(filter.Sort, "-")
+	sortKey := strings.TrimPrefix(filter.Sort, "-")
+	if sortKey == "" {
+		sortKey = "courseNumber"
+	}
+	if !ratingsAvgSortKeys[sortKey] {
+		active, err := s.activeDimension(ctx, sortKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !active {
+			return nil, 0, ErrInvalidSort
+		}
+	}
+	if filter.Department != "" && !validCoursePrefix(filter.Department) {
+		return nil, 0, ErrInvalidDepartment
+	}
+	filter.MinCount = max(filter.MinCount, anonymityThreshold)
+
+	total, err := s.db.CountAllRatingsAvg(ctx, sql.CountAllRatingsAvgParams{MinCount: filter.MinCount, Department: filter.Department})
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.GetAllRatingsAvg(ctx, sql.GetAllRatingsAvgParams{
+		Descending: descending,
+		SortKey:    sortKey,
+		PageLimit:  int32(filter.PageSize),
+		PageOffset: int32((filter.Page - 1) * filter.PageSize),
+		MinCount:   filter.MinCount,
+		Department: filter.Department,
+	})
+	return rows, total, err
+}
+
+func (s *Service) Courses(ctx context.Context) ([]sql.Course, error) {
+	return s.db.GetCourses(ctx)
+}
+
+func (s *Service) CoursesWithReviewAmount(ctx context.Context) ([]sql.GetCoursesWithReviewAmountRow, error) {
+	return s.db.GetCoursesWithReviewAmount(ctx)
+}
+
+func (s *Service) CoursesWithRatingsOrReviews(ctx context.Context) ([]sql.GetAllCoursesWithReviewsOrRatingsRow, error) {
+	return s.db.GetAllCoursesWithReviewsOrRatings(ctx)
+}
+
+func (s *Service) CourseName(ctx context.Context, course string) (string, error) {
+	name, err := s.db.GetCourseName(ctx, course)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrCourseNotFound
+	}
+	return name, err
+}
+
+func (s *Service) CurrentSemesters(ctx context.Context) ([]string, error) {
+	return s.db.GetCurrentSemester(ctx)
+}
+
+// // // // // //
+// users / auth //
+// // // // // //
+
+// Authenticate validates the token and makes sure its user exists in the db. The user is
+// identified by the pseudonym of the unique_id, the unique_id itself is neither stored nor logged.
+func (s *Service) Authenticate(ctx context.Context, token string) (string, error) {
+	user, err := DecodeJWT(token)
+	if err != nil {
+		return "", &ServiceError{Status: fiber.StatusUnauthorized, Message: err.Error()}
+	}
+	userID, err := s.pseudonymousUser(ctx, user.UniqueID)
+	if err != nil {
+		return "", err
+	}
+	s.stats.Println("user_id=" + userID)
+	if err := s.EnsureUser(ctx, userID); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// EnsureUser creates the user if it doesn't exist yet.
+func (s *Service) EnsureUser(ctx context.Context, userID string) error {
+	_, err := s.db.GetUser(ctx, userID)
+	if err == nil {
+		return nil
+	}
+	if _, err = s.db.SetUser(ctx, userID); err != nil {
+		return err
+	}
+	s.stats.Println("new_user=" + userID)
+	return nil
+}
+
+func (s *Service) User(ctx context.Context, userID string) (sql.User, error) {
+	return s.db.GetUser(ctx, userID)
+}
+
+func (s *Service) CreateUser(ctx context.Context, userID string) ([]sql.User, error) {
+	userID, err := storedUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.SetUser(ctx, userID)
+}
+
+// UserData returns the evaluations of a user. Shadow banned users see their silently
+// rejected reviews as pending.
+func (s *Service) UserData(ctx context.Context, userID string) ([]sql.GetUserDataRow, error) {
+	data, err := s.db.GetUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range data {
+		if data[i].ReviewShadowed {
+			data[i].Published.Status = sql.StatusPending
+		}
+	}
+	return data, nil
+}
+
+// // // // // // // // //
+// reviews and ratings  //
+// // // // // // // // //
+
+// Evaluation returns the evaluation of a user for a course, creating it if needed.
+// The semester of an existing evaluation is overwritten.
+func (s *Service) Evaluation(ctx context.Context, userID, course, semester string) (int32, error) {
+	id, err := s.db.GetCourseEvaluationMap(ctx, sql.GetCourseEvaluationMapParams{UserID: userID, CourseNumber: course})
+	if err != nil {
+		return s.db.SetCourseEvaluationMap(ctx, sql.SetCourseEvaluationMapParams{UserID: userID, CourseNumber: course, Semester: pgtype.Text{String: semester, Valid: true}})
+	}
+	_, err = s.db.UpdateSemester(ctx, sql.UpdateSemesterParams{EvaluationID: id, Semester: pgtype.Text{String: semester, Valid: true}, UserID: userID})
+	return id, err
+}
+
+// SubmitEvaluation stores an anonymous submission and returns its evaluation id.
+func (s *Service) SubmitEvaluation(ctx context.Context, sub api.EvaluationSubmission) (int32, error) {
+	if sub.AnonymousID == "" {
+		return 0, ErrMissingAnonymousID
+	}
+	if sub.CourseNumber == "" {
+		return 0, ErrMissingCourse
+	}
+	if sub.Semester == "" {
+		return 0, ErrMissingSemester
+	}
+	var rating Ratings
+	if sub.Rating != nil {
+		rating = Ratings(*sub.Rating)
+	}
+	if strings.TrimSpace(sub.Review) == "" && rating.empty() {
+		return 0, ErrRatingsNotSet
+	}
+	if !rating.valid() {
+		return 0, ErrRatingsOutOfRange
+	}
+	if !rating.validHours() {
+		return 0, ErrHoursOutOfRange
+	}
+	if err := s.checkRatingValues(ctx, rating.values()); err != nil {
+		return 0, err
+	}
+	if _, err := rating.comments(rating.values()); err != nil {
+		return 0, err
+	}
+
+	userID := sub.AnonymousID + "noAuth"
+	if err := s.CheckBan(ctx, userID, true); err != nil {
+		return 0, err
+	}
+	if err := s.EnsureUser(ctx, userID); err != nil {
+		return 0, err
+	}
+	id, err := s.Evaluation(ctx, userID, sub.CourseNumber, sub.Semester)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(sub.Review) != "" {
+		if _, err := s.SetReview(ctx, id, sub.Review); err != nil {
+			return 0, err
+		}
+	}
+	if !rating.empty() {
+		if _, err := s.SetRating(ctx, id, rating); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// checkOwner makes sure the evaluation exists and belongs to the user.
+func (s *Service) checkOwner(ctx context.Context, userID string, evalID int32) error {
+	_, err := s.db.CheckUserWithId(ctx, sql.CheckUserWithIdParams{EvaluationID: evalID, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEvaluationNotFound
+	}
+	return err
+}
+
+// SetReview inserts or updates the review of an evaluation and reports whether it was newly created.
+// Every change puts the review back into the moderation queue.
+func (s *Service) SetReview(ctx context.Context, evalID int32, review string) (bool, error) {
+	review = strings.TrimSpace(review)
+	if review == "" {
+		return false, ErrReviewEmpty
+	}
+	shadowed, err := s.shadowBanned(ctx, evalID)
+	if err != nil {
+		return false, err
+	}
+	if !shadowed {
+		SendDiscordMessage("Review to review: https://coursereview.ch/admin", "", 16712959)
+	}
+
+	// a new review replaces one that was deleted, it can't be restored anymore
+	if err := s.db.PurgeDeletedReview(ctx, evalID); err != nil {
+		return false, err
+	}
+	created := false
+	_, err = s.db.GetReviewWithId(ctx, evalID)
+	if err != nil {
+		created = true
+		_, err = s.db.SetReview(ctx, sql.SetReviewParams{EvaluationID: evalID, Review: review})
+	} else {
+		_, err = s.db.UpdateReview(ctx, sql.UpdateReviewParams{EvaluationID: evalID, Review: review})
+	}
+	if err != nil {
+		return false, err
+	}
+	// reviews of shadow banned users skip the queue, without changes requested from them
+	if shadowed {
+		if err := s.db.ShadowReview(ctx, evalID); err != nil {
+			return false, err
+		}
+	}
+	s.publish(EventReviewChanged)
+	return created, nil
+}
+
+// SetRating inserts or updates the rating of an evaluation and reports whether it was newly created.
+// A dimension set to 0 is not rated, the built-in ones are also stored in the columns of ratings.
+func (s *Service) SetRating(ctx context.Context, evalID int32, newRating Ratings) (bool, error) {
+	if !newRating.valid() {
+		return false, ErrRatingsOutOfRange
+	}
+	if !newRating.validHours() {
+		return false, ErrHoursOutOfRange
+	}
+	values := newRating.values()
+	if err := s.checkRatingValues(ctx, values); err != nil {
+		return false, err
+	}
+	comments, err := newRating.comments(values)
+	if err != nil {
+		return false, err
+	}
+	ratings := sql.SetRatingParams{
+		EvaluationID: evalID,
+		Recommended:  builtInColumn(values, "recommended"),
+		Engaging:     builtInColumn(values, "engaging"),
+		Difficulty:   builtInColumn(values, "difficulty"),
+		Effort:       builtInColumn(values, "effort"),
+		Resources:    builtInColumn(values, "resources"),
+	}
+	if newRating.HoursPerWeek != nil {
+		ratings.HoursPerWeek = pgtype.Float8{Float64: *newRating.HoursPerWeek, Valid: true}
+	}
+
+	// ratings aren't moderated, those of shadow banned users are stored but only shown to them
+	ratings.Shadowed, err = s.shadowBanned(ctx, evalID)
+	if err != nil {
+		return false, err
+	}
+	created := false
+	if _, err := s.db.GetRatingWithId(ctx, evalID); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return false, err
+		}
+		if newRating.empty() {
+			return false, ErrRatingsNotSet
+		}
+		created = true
+	} else if newRating.empty() {
+		return false, ErrRatingsEmpty
+	}
+	err = s.inTx(ctx, func(db *sql.Queries) error {
+		// a deleted rating still in its undo window only gives way once the new one is written
+		if err := db.PurgeDeletedRating(ctx, evalID); err != nil {
+			return err
+		}
+		var err error
+		if created {
+			_, err = db.SetRating(ctx, ratings)
+		} else {
+			_, err = db.UpdateRating(ctx, sql.UpdateRatingParams(ratings))
+		}
+		if err != nil {
+			return err
+		}
+		return setRatingValues(ctx, db, evalID, values, comments)
+	})
+	if err != nil {
+		return false, err
+	}
+	s.publish(EventRatingChanged)
+	return created, nil
+}
+
+func (s *Service) UpdateReview(ctx context.Context, userID string, evalID int32, review string) (bool, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return false, err
+	}
+	return s.SetReview(ctx, evalID, review)
+}
+
+func (s *Service) UpdateRating(ctx context.Context, userID string, evalID int32, ratings Ratings) (bool, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return false, err
+	}
+	return s.SetRating(ctx, evalID, ratings)
+}
+
+// DeleteReview hides the review until it is purged after the undo window.
+func (s *Service) DeleteReview(ctx context.Context, userID string, evalID int32) (sql.Review, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return sql.Review{}, err
+	}
+	review, err := s.db.DeleteReview(ctx, evalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return review, &ServiceError{Status: fiber.StatusNotFound, Message: "Review not found"}
+	}
+	if err != nil {
+		return review, err
+	}
+	s.publish(EventReviewChanged)
+	return review, nil
+}
+
+// DeleteRating hides the rating and its values until they are purged after the undo window.
+func (s *Service) DeleteRating(ctx context.Context, userID string, evalID int32) (sql.Rating, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return sql.Rating{}, err
+	}
+	rating, err := s.db.DeleteRating(ctx, evalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rating, &ServiceError{Status: fiber.StatusNotFound, Message: "Rating not found"}
+	}
+	if err != nil {
+		return rating, err
+	}
+	s.publish(EventRatingChanged)
+	return rating, nil
+}
+
+// cleanupEvaluation removes the evaluation once it has neither a review nor a rating left.
+func (s *Service) cleanupEvaluation(ctx context.Context, evalID int32) error {
+	if _, err := s.db.CheckRatingAndReview(ctx, evalID); err == nil {
+		return nil
+	}
+	_, err := s.db.DeleteCourseEvaluationMap(ctx, evalID)
+	return err
+}
+
+func (s *Service) UpdateSemester(ctx context.Context, userID string, evalID int32, semester string) (sql.CourseEvaluationMap, error) {
+	if semester == "" {
+		return sql.CourseEvaluationMap{}, ErrMissingSemester
+	}
+	evaluation, err := s.db.UpdateSemester(ctx, sql.UpdateSemesterParams{EvaluationID: evalID, Semester: pgtype.Text{String: semester, Valid: true}, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return evaluation, ErrEvaluationNotFound
+	}
+	if err != nil {
+		return evaluation, err
+	}
+	s.publish(EventReviewChanged, EventRatingChanged)
+	return evaluation, nil
+}
+
+// // // // // // //
+// mod / admin    //
+// // // // // // //
+
+func (s *Service) SetCurrentSemesters(ctx context.Context, semesters []string) error {
+	defer s.publish(EventSemesterChanged)
+	s.db.RemoveCurrentSemester(ctx)
+	for _, semester := range semesters {
+		if _, err := s.db.SetCurrentSemester(ctx, semester); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) UnverifiedReviews(ctx context.Context) ([]sql.GetUnverifiedReviewsRow, error) {
+	return s.db.GetUnverifiedReviews(ctx)
+}
+
+func (s *Service) VerifyReview(ctx context.Context, evalID int32) (sql.Review, error) {
+	review, err := s.db.VerifyReview(ctx, evalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return review, &ServiceError{Status: fiber.StatusNotFound, Message: "Review not found"}
+	}
+	if err != nil {
+		return review, err
+	}
+	s.publish(EventReviewChanged)
+	return review, nil
+}
+
+func (s *Service) RejectReview(ctx context.Context, evalID int32, requestedChanges string) (sql.Review, error) {
+	review, err := s.db.RejectReview(ctx, sql.RejectReviewParams{EvaluationID: evalID, RequestedChanges: pgtype.Text{String: requestedChanges, Valid: true}})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return review, &ServiceError{Status: fiber.StatusNotFound, Message: "Review not found"}
+	}
+	if err != nil {
+		return review, err
+	}
+	s.publish(EventReviewChanged)
+	return review, nil
+}
+
+// SetModerator takes the unique_id of the user or their pseudonym.
+func (s *Service) SetModerator(ctx context.Context, userID string) (sql.User, error) {
+	userID, err := storedUserID(userID)
+	if err != nil {
+		return sql.User{}, err
+	}
+	return s.db.SetModerator(ctx, userID)
+}
+
+func (s *Service) AddCourse(ctx context.Context, course sql.SetCourseParams) ([]sql.Course, error) {
+	number, err := ParseCourseNumber(course.CourseNumber)
+	if err != nil {
+		return nil, err
+	}
+	courses, err := s.db.SetCourse(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.AddDepartmentPrefix(ctx, number.Prefix); err != nil {
+		return nil, err
+	}
+	s.publish(EventCourseChanged, EventDepartmentChanged)
+	return courses, nil
+}
+
+// StartScrape scrapes the VVZ for the semester in the background.
+func (s *Service) StartScrape(semester string) {
+	log.Println("Scraping courses for semester:", semester)
+	go func() {
+		vvzScraper(semester, context.Background())
+		s.publish(EventCourseChanged, EventDepartmentChanged)
+	}()
+}
+
+// UsageStats parses the stats log into user and path entries.
+func (s *Service) UsageStats() ([]api.StatEntry, []api.StatEntry, error) {
+	file, err := os.Open(statsLogPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var userEntries []api.StatEntry
+	var pathEntries []api.StatEntry
+
+	// file is not json, so we need to parse it line by line
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// split line by space
+		parts := strings.Split(line, " ")
+		if len(parts) < 3 {
+			continue
+		}
+		// get value
+		itemParts := strings.Split(parts[2], "=")
+		if len(itemParts) < 2 {
+			continue
+		}
+		key := itemParts[0]
+		value := itemParts[1]
+		stat := api.StatEntry{
+			Time:  parts[0] + " " + parts[1],
+			Value: value,
+		}
+		// check if key exists in map
+		if key == "user_id" {
+			userEntries = append(userEntries, stat)
+		} else if key == "path" {
+			pathEntries = append(pathEntries, stat)
+		} else if key != "new_user" {
+			log.Println("Unknown key:", key)
+		}
+	}
+	return userEntries, pathEntries, scanner.Err()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"coursereview/app/generated/sql"
+)
+
+func TestAllRatingsAvgSortsByCourseNumber(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	courses := []string{"252-0027-00L", "252-0028-00L", "263-0006-00L"}
+	for _, course := range courses {
+		execSQL(t, pool, "INSERT INTO courses (course_number, course_name) VALUES ($1, $1)", course)
+		for i := range anonymityThreshold {
+			user := fmt.Sprintf("%s-%d-noAuth", course, i)
+			execSQL(t, pool, "INSERT INTO users (user_id) VALUES ($1)", user)
+			execSQL(t, pool, "INSERT INTO course_evaluation_map (user_id, course_number) VALUES ($1, $2)", user, course)
+			execSQL(t, pool, "INSERT INTO ratings (evaluation_id) SELECT id FROM course_evaluation_map WHERE user_id = $1", user)
+		}
+	}
+
+	for sort, want := range map[string][]string{
+		"":              courses,
+		"courseNumber":  courses,
+		"-courseNumber": {"263-0006-00L", "252-0028-00L", "252-0027-00L"},
+	} {
+		rows, _, err := svc.AllRatingsAvg(context.Background(), RatingsAvgFilter{Page: 1, Sort: sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := mapAll(rows, func(row sql.GetAllRatingsAvgRow) string { return row.CourseNumber })
+		if !slices.Equal(got, want) {
+			t.Errorf("sort %q: got %v, want %v", sort, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+var germanStopwords = toSet(strings.Fields(`
+	aber alle allem allen aller alles als also am an ander andere anderen anderer anderes auch auf aus
+	bei beim bin bis bist da damit dann das dass dein deine dem den denn der des dessen deshalb die dies
+	diese diesem diesen dieser dieses doch dort du durch ein eine einem einen einer eines einige einmal
+	er es etwas euch euer eure für gegen gewesen hab habe haben hat hatte hatten hier hin hinter ich ihm
+	ihn ihnen ihr ihre ihrem ihren ihrer im in indem ins ist jede jedem jeden jeder jedes jedoch jetzt
+	kann kannst kein keine keinem keinen keiner könnte man manche mehr mein meine mich mir mit muss musste
+	nach nicht nichts noch nun nur ob oder ohne schon sehr sein seine seinem seinen seiner sich sie sind
+	so solche soll sollte sondern sonst über um und uns unser unsere unter viel vom von vor war waren
+	warst was weil welche welchem welchen welcher welches wenn wer werde werden wie wieder will wir wird
+	wirst wo wurde wurden zu zum zur zwar zwischen
+`))
+
+var englishStopwords = toSet(strings.Fields(`
+	about above after again against all also am an and any are as at be because been before being below
+	between both but by can could did do does doing down during each few for from further had has have
+	having he her here hers herself him himself his how if in into is it its itself just me more most my
+	myself no nor not now of off on once only or other our ours ourselves out over own same she should so
+	some such than that the their theirs them themselves then there these they this those through to too
+	under until up very was we were what when where which while who whom why will with would you your
+	yours yourself yourselves though although however
+`))
+
+// stopwords of German and English, reviews are written in both
+var stopwords = union(germanStopwords, englishStopwords)
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+func union(sets ...map[string]bool) map[string]bool {
+	out := map[string]bool{}
+	for _, set := range sets {
+		for word := range set {
+			out[word] = true
+		}
+	}
+	return out
+}
+
+// words splits a text into lowercase words.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isTerm reports whether a word carries meaning: no stopword, no number and at least three letters.
+func isTerm(word string) bool {
+	return len([]rune(word)) >= 3 && !stopwords[word] && strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
+
+// tokenize splits a text into its lowercase terms.
+func tokenize(text string) []string {
+	all := words(text)
+	tokens := all[:0]
+	for _, word := range all {
+		if isTerm(word) {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// detectLanguage guesses "de" or "en" by which stopwords the text uses more.
+func detectLanguage(text string) string {
+	var german, english int
+	for _, word := range words(text) {
+		if germanStopwords[word] {
+			german++
+		}
+		if englishStopwords[word] {
+			english++
+		}
+	}
+	if english > german {
+		return "en"
+	}
+	return "de"
+}
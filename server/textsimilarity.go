@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	textSimilarityInterval = 6 * time.Hour
+	// similar courses stored per course
+	textSimilarityNeighbors = 10
+	textSimilarityMin       = 0.05
+	// the course name counts like this many mentions in a review
+	courseNameWeight = 3
+)
+
+type textPosting struct {
+	doc    int
+	weight float64
+}
+
+// textSimilarities returns the most similar documents of every document by the cosine
+// similarity of their TF-IDF vectors, using sublinear term frequencies.
+func textSimilarities(docs []map[string]float64, neighbors int, minSimilarity float64) [][]textPosting {
+	df := map[string]int{}
+	for _, doc := range docs {
+		for term := range doc {
+			df[term]++
+		}
+	}
+
+	// terms in more than half of the documents barely tell them apart, but cost the most below
+	maxDF := max(2, len(docs)/2)
+	index := map[string][]textPosting{}
+	for i, doc := range docs {
+		vector := map[string]float64{}
+		var norm float64
+		for term, tf := range doc {
+			weight := (1 + math.Log(tf)) * math.Log(float64(len(docs))/float64(df[term]))
+			norm += weight * weight
+			// terms of a single document can't make documents similar
+			if df[term] >= 2 && df[term] <= maxDF && weight > 0 {
+				vector[term] = weight
+			}
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			continue
+		}
+		for term, weight := range vector {
+			index[term] = append(index[term], textPosting{doc: i, weight: weight / norm})
+		}
+	}
+
+	scores := make([]map[int]float64, len(docs))
+	for i := range scores {
+		scores[i] = map[int]float64{}
+	}
+	for _, postings := range index {
+		for _, a := range postings {
+			for _, b := range postings {
+				if a.doc != b.doc {
+					scores[a.doc][b.doc] += a.weight * b.weight
+				}
+			}
+		}
+	}
+
+	similar := make([][]textPosting, len(docs))
+	for i, docScores := range scores {
+		for doc, score := range docScores {
+			if score >= minSimilarity {
+				similar[i] = append(similar[i], textPosting{doc: doc, weight: score})
+			}
+		}
+		sort.Slice(similar[i], func(a, b int) bool {
+			return similar[i][a].weight > similar[i][b].weight
+		})
+		if len(similar[i]) > neighbors {
+			similar[i] = similar[i][:neighbors]
+		}
+	}
+	return similar
+}
+
+// RefreshTextSimilarities recomputes course_text_similarities from the verified reviews
+// and names of all reviewed courses.
+func (s *Service) RefreshTextSimilarities(ctx context.Context) error {
+	rows, err := s.db.GetVerifiedReviewTexts(ctx)
+	if err != nil {
+		return err
+	}
+
+	// rows are ordered by course
+	var courses []string
+	var docs []map[string]float64
+	for _, row := range rows {
+		if len(courses) == 0 || courses[len(courses)-1] != row.CourseNumber {
+			courses = append(courses, row.CourseNumber)
+			doc := map[string]float64{}
+			for _, token := range tokenize(row.CourseName) {
+				doc[token] += courseNameWeight
+			}
+			docs = append(docs, doc)
+		}
+		doc := docs[len(docs)-1]
+		for _, token := range tokenize(row.Review) {
+			doc[token]++
+		}
+	}
+
+	var params sql.SetCourseTextSimilaritiesParams
+	for i, similar := range textSimilarities(docs, textSimilarityNeighbors, textSimilarityMin) {
+		for _, posting := range similar {
+			params.CourseNumbers = append(params.CourseNumbers, courses[i])
+			params.SimilarCourseNumbers = append(params.SimilarCourseNumbers, courses[posting.doc])
+			params.Similarities = append(params.Similarities, posting.weight)
+		}
+	}
+	params.ComputedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if err := s.db.SetCourseTextSimilarities(ctx, params); err != nil {
+		return err
+	}
+	if err := s.db.DeleteCourseTextSimilaritiesBefore(ctx, params.ComputedAt); err != nil {
+		return err
+	}
+	s.publish(EventSimilarityChanged)
+	return nil
+}
+
+// SimilarCourses returns the courses whose reviews read most alike, most similar first.
+func (s *Service) SimilarCourses(ctx context.Context, course string) ([]api.SimilarCourse, error) {
+	rows, err := s.db.GetSimilarCourses(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	return mapAll(rows, func(row sql.GetSimilarCoursesRow) api.SimilarCourse {
+		return api.SimilarCourse{CourseNumber: row.CourseNumber, CourseName: row.CourseName, Similarity: row.Similarity}
+	}), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Die Übungen waren sehr gut!", []string{"übungen", "gut"}},
+		{"The exercises are great, the exam was hard.", []string{"exercises", "great", "exam", "hard"}},
+		{"Lecture 2023 had 12 ECTS and C++", []string{"lecture", "ects"}},
+		{"Analysis II / Lineare Algebra", []string{"analysis", "lineare", "algebra"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTextSimilarities(t *testing.T) {
+	tests := []struct {
+		name          string
+		docs          []map[string]float64
+		neighbors     int
+		minSimilarity float64
+		// the similar documents of every document, most similar first
+		want [][]int
+	}{
+		{
+			name: "pairs",
+			docs: []map[string]float64{
+				{"compiler": 1, "parsing": 1},
+				{"compiler": 1, "parsing": 1},
+				{"biology": 2, "cells": 1},
+				{"biology": 1, "cells": 1},
+				{"unique": 5},
+			},
+			neighbors: 10,
+			want:      [][]int{{1}, {0}, {3}, {2}, nil},
+		},
+		{
+			name: "terms of a single document",
+			docs: []map[string]float64{
+				{"compiler": 1},
+				{"biology": 1},
+				{"physics": 1},
+			},
+			neighbors: 10,
+			want:      [][]int{nil, nil, nil},
+		},
+		{
+			name: "terms in more than half of the documents",
+			docs: []map[string]float64{
+				{"lecture": 1, "compiler": 1},
+				{"lecture": 1, "biology": 1},
+				{"lecture": 1, "physics": 1},
+				{"lecture": 1, "history": 1},
+				{"chemistry": 1},
+			},
+			neighbors: 10,
+			want:      [][]int{nil, nil, nil, nil, nil},
+		},
+		{
+			name: "most similar first, cut at the neighbors",
+			docs: []map[string]float64{
+				{"compiler": 1, "parsing": 1, "types": 1},
+				{"compiler": 1, "parsing": 1, "types": 1},
+				{"compiler": 1, "biology": 1, "cells": 1},
+				{"biology": 1, "cells": 1, "history": 1},
+				{"history": 1, "physics": 1, "chemistry": 1},
+				{"physics": 1, "chemistry": 1},
+			},
+			neighbors: 1,
+			want:      [][]int{{1}, {0}, {3}, {2}, {5}, {4}},
+		},
+		{
+			name: "below the minimum",
+			docs: []map[string]float64{
+				{"compiler": 1, "a": 1, "b": 1, "c": 1, "d": 1},
+				{"compiler": 1, "e": 1, "f": 1, "g": 1, "h": 1},
+				{"other": 1},
+				{"words": 1},
+			},
+			neighbors:     10,
+			minSimilarity: 0.5,
+			want:          [][]int{nil, nil, nil, nil},
+		},
+	}
+	for _, tt := range tests {
+		similar := textSimilarities(tt.docs, tt.neighbors, tt.minSimilarity)
+		for i, postings := range similar {
+			var docs []int
+			for _, posting := range postings {
+				docs = append(docs, posting.doc)
+				if posting.weight < tt.minSimilarity || posting.weight > 1+1e-9 {
+					t.Errorf("%s: similarity of %d and %d is %v", tt.name, i, posting.doc, posting.weight)
+				}
+			}
+			if !slices.Equal(docs, tt.want[i]) {
+				t.Errorf("%s: document %d is similar to %v, want %v", tt.name, i, docs, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestTextSimilaritiesIdenticalDocuments(t *testing.T) {
+	similar := textSimilarities([]map[string]float64{
+		{"compiler": 2, "parsing": 1},
+		{"compiler": 2, "parsing": 1},
+		{"biology": 1},
+		{"physics": 1},
+	}, 10, 0)
+	if len(similar[0]) != 1 || math.Abs(similar[0][0].weight-1) > 1e-9 {
+		t.Errorf("identical documents: %+v, want a similarity of 1", similar[0])
+	}
+}
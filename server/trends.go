@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// A dimension's average counts as shifted if it moved by at least trendMinDelta and the
+// difference is at least trendMinZ standard errors, with trendMinCount ratings on both sides.
+const (
+	trendMinCount = 3
+	trendMinDelta = 0.5
+	trendMinZ     = 2.0
+)
+
+type trendSample struct {
+	mean     *float64
+	variance float64
+	count    int32
+}
+
+func newTrendSample(mean, variance pgtype.Numeric, count int32) trendSample {
+	sample := trendSample{mean: numericPtr(mean), count: count}
+	if v := numericPtr(variance); v != nil {
+		sample.variance = *v
+	}
+	return sample
+}
+
+func shifted(prev, cur trendSample) bool {
+	if prev.mean == nil || cur.mean == nil || prev.count < trendMinCount || cur.count < trendMinCount {
+		return false
+	}
+	delta := math.Abs(*cur.mean - *prev.mean)
+	if delta < trendMinDelta {
+		return false
+	}
+	stdErr := math.Sqrt(prev.variance/float64(prev.count) + cur.variance/float64(cur.count))
+	return stdErr == 0 || delta/stdErr >= trendMinZ
+}
+
+// RatingTrend returns the per-semester rating averages of a course in chronological order
+// and flags where they shifted. Semesters in an unknown format or with fewer than
+// anonymityThreshold ratings are left out.
+func (s *Service) RatingTrend(ctx context.Context, course string) ([]api.TrendPoint, error) {
+	rows, err := s.db.GetCourseRatingTrend(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	dimensionRows, err := s.db.GetSemesterDimensionAverages(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	dimensions := map[string][]sql.GetSemesterDimensionAveragesRow{}
+	for _, row := range dimensionRows {
+		dimensions[row.Semester] = append(dimensions[row.Semester], row)
+	}
+
+	type semesterRow struct {
+		semester Semester
+		row      sql.GetCourseRatingTrendRow
+	}
+	semesters := make([]semesterRow, 0, len(rows))
+	for _, row := range rows {
+		semester, err := ParseSemester(row.Semester)
+		if err != nil || !publishable(row.RatingCount) {
+			continue
+		}
+		semesters = append(semesters, semesterRow{semester, row})
+	}
+	sort.Slice(semesters, func(i, j int) bool {
+		return semesters[i].semester.Before(semesters[j].semester)
+	})
+
+	// the last semester a dimension was rated in, in the order of api.TrendPoint
+	var previous [5]trendSample
+	previousByKey := map[string]trendSample{}
+	var previousLecturers []int32
+	points := make([]api.TrendPoint, 0, len(semesters))
+	for _, entry := range semesters {
+		row := entry.row
+		point := api.TrendPoint{
+			Semester:   row.Semester,
+			Count:      row.RatingCount,
+			Lecturers:  make([]api.Lecturer, 0, len(row.LecturerIds)),
+			Dimensions: map[string]api.TrendValue{},
+		}
+		for i, id := range row.LecturerIds {
+			point.Lecturers = append(point.Lecturers, api.Lecturer{ID: id, Name: row.LecturerNames[i]})
+		}
+		if len(row.LecturerIds) > 0 {
+			ids := slices.Sorted(slices.Values(row.LecturerIds))
+			point.LecturerChanged = previousLecturers != nil && !slices.Equal(ids, previousLecturers)
+			previousLecturers = ids
+		}
+
+		samples := [5]trendSample{
+			newTrendSample(row.Recommended, row.RecommendedVariance, row.RecommendedCount),
+			newTrendSample(row.Engaging, row.EngagingVariance, row.EngagingCount),
+			newTrendSample(row.Difficulty, row.DifficultyVariance, row.DifficultyCount),
+			newTrendSample(row.Effort, row.EffortVariance, row.EffortCount),
+			newTrendSample(row.Resources, row.ResourcesVariance, row.ResourcesCount),
+		}
+		values := [5]*api.TrendValue{&point.Recommended, &point.Engaging, &point.Difficulty, &point.Effort, &point.Resources}
+		for i, sample := range samples {
+			*values[i] = api.TrendValue{Average: sample.mean, Count: sample.count, Shift: shifted(previous[i], sample)}
+			point.Shift = point.Shift || values[i].Shift
+			if sample.mean != nil {
+				previous[i] = sample
+			}
+		}
+		for _, dimension := range dimensions[row.Semester] {
+			sample := newTrendSample(dimension.Average, dimension.Variance, dimension.Count)
+			value := api.TrendValue{Average: sample.mean, Count: sample.count, Shift: shifted(previousByKey[dimension.DimensionKey], sample)}
+			point.Dimensions[dimension.DimensionKey] = value
+			point.Shift = point.Shift || value.Shift
+			if sample.mean != nil {
+				previousByKey[dimension.DimensionKey] = sample
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
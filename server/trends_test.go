@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestShifted(t *testing.T) {
+	sample := func(mean, variance float64, count int32) trendSample {
+		return trendSample{mean: &mean, variance: variance, count: count}
+	}
+	tests := []struct {
+		name      string
+		prev, cur trendSample
+		want      bool
+	}{
+		{"clear shift", sample(2, 0.5, 10), sample(4, 0.5, 10), true},
+		{"shift down", sample(4, 0.5, 10), sample(2, 0.5, 10), true},
+		{"small delta", sample(3, 0, 10), sample(3.4, 0, 10), false},
+		{"delta at the minimum", sample(3, 0, 10), sample(3.5, 0, 10), true},
+		{"noisy", sample(2, 4, 5), sample(3, 4, 5), false},
+		{"no variance", sample(2, 0, 3), sample(3, 0, 3), true},
+		{"too few before", sample(2, 0.5, 2), sample(4, 0.5, 10), false},
+		{"too few after", sample(2, 0.5, 10), sample(4, 0.5, 2), false},
+		{"nothing before", trendSample{}, sample(4, 0.5, 10), false},
+		{"not rated now", sample(2, 0.5, 10), trendSample{count: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := shifted(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s: shifted = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
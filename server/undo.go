@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrNothingToRestore = &ServiceError{Status: fiber.StatusNotFound, Message: "Nothing deleted within the undo window"}
+
+const purgeDeletedInterval = time.Hour
+
+// undoWindow is how long a deleted review or rating can be restored before it is purged.
+// It is read from UNDO_WINDOW as a duration like 24h or 30m.
+var undoWindow = parseUndoWindow(os.Getenv("UNDO_WINDOW"))
+
+// parseUndoWindow falls back to 24h if the window is not set or not a positive duration.
+func parseUndoWindow(value string) time.Duration {
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		if value != "" {
+			log.Printf("Invalid UNDO_WINDOW, using 24h")
+		}
+		return 24 * time.Hour
+	}
+	return window
+}
+
+// undoableSince is the earliest deletion that can still be restored.
+func undoableSince() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(-undoWindow), Valid: true}
+}
+
+// RestoreReview undoes the deletion of a review within the undo window.
+func (s *Service) RestoreReview(ctx context.Context, userID string, evalID int32) (sql.Review, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return sql.Review{}, err
+	}
+	review, err := s.db.RestoreReview(ctx, sql.RestoreReviewParams{EvaluationID: evalID, DeletedAfter: undoableSince()})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return review, ErrNothingToRestore
+	}
+	if err != nil {
+		return review, err
+	}
+	s.publish(EventReviewChanged)
+	return review, nil
+}
+
+// RestoreRating undoes the deletion of a rating and its values within the undo window.
+func (s *Service) RestoreRating(ctx context.Context, userID string, evalID int32) (sql.Rating, error) {
+	if err := s.checkOwner(ctx, userID, evalID); err != nil {
+		return sql.Rating{}, err
+	}
+	rating, err := s.db.RestoreRating(ctx, sql.RestoreRatingParams{EvaluationID: evalID, DeletedAfter: undoableSince()})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rating, ErrNothingToRestore
+	}
+	if err != nil {
+		return rating, err
+	}
+	s.publish(EventRatingChanged)
+	return rating, nil
+}
+
+// PurgeDeleted finalizes the deletions older than the undo window and removes the
+// evaluations left without a review or rating.
+func (s *Service) PurgeDeleted(ctx context.Context) error {
+	reviews, err := s.db.PurgeDeletedReviews(ctx, undoableSince())
+	if err != nil {
+		return err
+	}
+	ratings, err := s.db.PurgeDeletedRatings(ctx, undoableSince())
+	if err != nil {
+		return err
+	}
+	purged := map[int32]bool{}
+	for _, evalID := range append(reviews, ratings...) {
+		if purged[evalID] {
+			continue
+		}
+		purged[evalID] = true
+		if err := s.cleanupEvaluation(ctx, evalID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
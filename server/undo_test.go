@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseUndoWindow(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 24 * time.Hour},
+		{"30m", 30 * time.Minute},
+		{"48h", 48 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{"0s", 24 * time.Hour},
+		{"-1h", 24 * time.Hour},
+		{"24", 24 * time.Hour},
+		{"one day", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := parseUndoWindow(tt.value); got != tt.want {
+			t.Errorf("parseUndoWindow(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUndoableSince(t *testing.T) {
+	defer func(window time.Duration) { undoWindow = window }(undoWindow)
+	undoWindow = 2 * time.Hour
+
+	before := time.Now()
+	since := undoableSince()
+	after := time.Now()
+	if !since.Valid || since.Time.Before(before.Add(-undoWindow)) || since.Time.After(after.Add(-undoWindow)) {
+		t.Errorf("undoableSince() = %v, want %v before now", since.Time, undoWindow)
+	}
+}
+
+func TestEmptyRatingKeepsDeletedOne(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	execSQL(t, pool, "UPDATE ratings SET deleted_at = NOW() WHERE evaluation_id = $1", evaluation)
+
+	if _, err := svc.SetRating(context.Background(), evaluation, Ratings{}); !errors.Is(err, ErrRatingsNotSet) {
+		t.Fatalf("got %v, want %v", err, ErrRatingsNotSet)
+	}
+	if _, err := svc.RestoreRating(context.Background(), "alice", evaluation); err != nil {
+		t.Fatalf("deleted rating can no longer be restored: %v", err)
+	}
+}
+
+func TestPurgeDeletedKeepsUndoableDeletions(t *testing.T) {
+	pool := testDB(t)
+	svc := NewService(pool, nil)
+	evaluation := seedContributions(t, pool, "alice")
+	execSQL(t, pool, "UPDATE reviews SET deleted_at = $2 WHERE evaluation_id = $1", evaluation, time.Now().Add(-undoWindow-time.Minute))
+	execSQL(t, pool, "UPDATE ratings SET deleted_at = $2 WHERE evaluation_id = $1", evaluation, time.Now().Add(-undoWindow+time.Minute))
+
+	if err := svc.PurgeDeleted(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM reviews WHERE evaluation_id = $1", evaluation); n != 0 {
+		t.Error("review deleted before the undo window was kept")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM ratings WHERE evaluation_id = $1", evaluation); n != 1 {
+		t.Error("rating deleted within the undo window was purged")
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM course_evaluation_map WHERE id = $1", evaluation); n != 1 {
+		t.Error("evaluation purged while its rating can still be restored")
+	}
+
+	execSQL(t, pool, "UPDATE ratings SET deleted_at = $2 WHERE evaluation_id = $1", evaluation, time.Now().Add(-undoWindow-time.Minute))
+	if err := svc.PurgeDeleted(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, pool, "SELECT COUNT(*) FROM course_evaluation_map WHERE id = $1", evaluation); n != 0 {
+		t.Error("evaluation without review and rating was kept")
+	}
+}
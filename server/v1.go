@@ -0,0 +1,709 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"coursereview/app/api"
+	"coursereview/app/generated/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerV1Routes mounts the resource-oriented API. Authenticated routes expect the
+// token in an "Authorization: Bearer <token>" header instead of the body.
+func registerV1Routes(app *fiber.App, svc *Service, cache *responseCache) {
+	v1 := app.Group("/v1")
+	authed := bearerAuth(svc)
+
+	// // // // // //
+	// public      //
+	// // // // // //
+	v1.Get("/stats", cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		stats, err := svc.Stats(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toStatsDTO(stats))
+	})
+
+	v1.Get("/evaluations", cache.cached(time.Minute, EventReviewChanged, EventRatingChanged), func(c *fiber.Ctx) error {
+		data, err := svc.AllData(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(data, toPublicEvaluationDTO))
+	})
+
+	v1.Get("/reviews/latest", cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		reviews, err := svc.LatestReviews(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(reviews, toLatestReviewDTO))
+	})
+
+	v1.Get("/ratings/averages", cache.cached(time.Minute, EventRatingChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		filter, err := ratingsAvgFilter(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		page, err := svc.RatingAveragesPage(c.Context(), filter)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(page)
+	})
+
+	v1.Get("/semesters/current", cache.cached(5*time.Minute, EventSemesterChanged), func(c *fiber.Ctx) error {
+		semesters, err := svc.CurrentSemesters(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(semesters)
+	})
+
+	v1.Get("/rating-dimensions", cache.cached(5*time.Minute, EventDimensionChanged), func(c *fiber.Ctx) error {
+		dimensions, err := svc.RatingDimensions(c.Context(), false)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(dimensions, toRatingDimensionDTO))
+	})
+
+	v1.Get("/courses", cache.cached(time.Hour, EventCourseChanged), func(c *fiber.Ctx) error {
+		courses, err := svc.Courses(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(courses, toCourseDTO))
+	})
+
+	v1.Get("/courses/review-counts", cache.cached(time.Minute, EventReviewChanged, EventCourseChanged), func(c *fiber.Ctx) error {
+		courses, err := svc.CoursesWithReviewAmount(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(courses, toCourseReviewCountDTO))
+	})
+
+	v1.Get("/courses/evaluated", cache.cached(time.Minute, EventReviewChanged, EventRatingChanged), func(c *fiber.Ctx) error {
+		courses, err := svc.CoursesWithRatingsOrReviews(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(courses, toEvaluatedCourseDTO))
+	})
+
+	v1.Get("/courses/:number", cache.cached(time.Hour, EventCourseChanged), func(c *fiber.Ctx) error {
+		name, err := svc.CourseName(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(api.Course{CourseNumber: c.Params("number"), CourseName: name})
+	})
+
+	v1.Get("/courses/:number/reviews", cache.cached(time.Minute, EventReviewChanged), func(c *fiber.Ctx) error {
+		reviews, err := svc.Reviews(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(reviews, toReviewDTO))
+	})
+
+	v1.Get("/courses/:number/ratings", cache.cached(time.Minute, EventRatingChanged), func(c *fiber.Ctx) error {
+		ratings, err := svc.Ratings(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(ratings, toRatingDTO))
+	})
+
+	v1.Get("/courses/:number/ratings/average", cache.cached(time.Minute, EventRatingChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		average, err := svc.CourseRatingAverage(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(average)
+	})
+
+	v1.Get("/courses/:number/ratings/dimensions", cache.cached(time.Minute, EventRatingChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		averages, err := svc.CourseDimensionAverages(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(averages)
+	})
+
+	v1.Get("/courses/:number/lecturers", cache.cached(time.Hour, EventCourseChanged), func(c *fiber.Ctx) error {
+		lecturers, err := svc.CourseLecturers(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toSemesterLecturersDTO(lecturers))
+	})
+
+	v1.Get("/courses/:number/ratings/semesters", cache.cached(time.Minute, EventRatingChanged, EventCourseChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		averages, err := svc.SemesterRatingAverages(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(averages)
+	})
+
+	v1.Get("/courses/:number/similar", cache.cached(time.Hour, EventSimilarityChanged, EventReviewChanged, EventCourseChanged), func(c *fiber.Ctx) error {
+		similar, err := svc.SimilarCourses(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(similar)
+	})
+
+	v1.Get("/courses/:number/summary", cache.cached(time.Minute, EventSummaryChanged, EventReviewChanged), func(c *fiber.Ctx) error {
+		summary, err := svc.CourseSummary(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(summary)
+	})
+
+	v1.Get("/courses/:number/workload", cache.cached(time.Minute, EventRatingChanged, EventReviewChanged, EventCourseChanged), func(c *fiber.Ctx) error {
+		workload, err := svc.CourseWorkload(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(workload)
+	})
+
+	v1.Get("/courses/:number/trends", cache.cached(time.Minute, EventRatingChanged, EventCourseChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		trend, err := svc.RatingTrend(c.Context(), c.Params("number"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(trend)
+	})
+
+	v1.Get("/lecturers/:id", cache.cached(time.Minute, EventRatingChanged, EventCourseChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		profile, err := svc.LecturerProfile(c.Context(), id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(profile)
+	})
+
+	v1.Get("/compare", cache.cached(time.Minute, EventRatingChanged, EventReviewChanged, EventCourseChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		comparisons, err := svc.CompareCourses(c.Context(), strings.Split(c.Query("courses"), ","))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(comparisons)
+	})
+
+	v1.Get("/departments", cache.cached(time.Hour, EventCourseChanged, EventDepartmentChanged), func(c *fiber.Ctx) error {
+		departments, err := svc.Departments(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(departments, toDepartmentDTO))
+	})
+
+	v1.Get("/departments/:code/courses", cache.cached(time.Hour, EventCourseChanged, EventDepartmentChanged), func(c *fiber.Ctx) error {
+		courses, err := svc.DepartmentCourses(c.Context(), c.Params("code"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(courses, toCourseDTO))
+	})
+
+	v1.Get("/departments/:code/stats", cache.cached(time.Minute, EventRatingChanged, EventReviewChanged, EventCourseChanged, EventDepartmentChanged, EventDimensionChanged), func(c *fiber.Ctx) error {
+		stats, err := svc.DepartmentStats(c.Context(), c.Params("code"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(stats)
+	})
+
+	// anonymous submission, the author is identified by the client generated anonymousId
+	v1.Post("/evaluations", func(c *fiber.Ctx) error {
+		var data api.EvaluationSubmission
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		id, err := svc.SubmitEvaluation(c.Context(), data)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(201).JSON(fiber.Map{"id": id})
+	})
+
+	// the signed link from GET /v1/me/exports/:id authorizes the download
+	v1.Get("/exports/:id/download", func(c *fiber.Ctx) error {
+		format := c.Query("format")
+		archive, err := svc.DataExportArchive(c.Context(), c.Params("id"), format, c.Query("expires"), c.Query("signature"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		contentType := fiber.MIMEApplicationJSON
+		if format == api.ExportZip {
+			contentType = "application/zip"
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="coursereview-export.`+format+`"`)
+		return c.Send(archive)
+	})
+
+	// // // // // // // // //
+	// authentication needed //
+	// // // // // // // // //
+	v1.Get("/me/evaluations", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		evaluations, err := svc.UserEvaluations(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(evaluations)
+	})
+
+	v1.Get("/me/recommendations", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		recommendations, err := svc.Recommendations(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(recommendations)
+	})
+
+	v1.Post("/me/exports", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		export, err := svc.RequestDataExport(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(202).JSON(export)
+	})
+
+	v1.Get("/me/exports/:id", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		export, err := svc.DataExport(c.Context(), uniqueId, c.Params("id"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(export)
+	})
+
+	v1.Post("/me/deletion", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data api.AccountDeletionBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		deletion, err := svc.RequestAccountDeletion(c.Context(), uniqueId, data.Mode)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(202).JSON(deletion)
+	})
+
+	v1.Get("/me/deletion", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		deletion, err := svc.PendingAccountDeletion(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(deletion)
+	})
+
+	v1.Delete("/me/deletion", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		deletion, err := svc.CancelAccountDeletion(c.Context(), uniqueId)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(deletion)
+	})
+
+	v1.Patch("/evaluations/:id", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.SemesterBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		evaluation, err := svc.UpdateSemester(c.Context(), uniqueId, id, data.Semester)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toEvaluationDTO(evaluation))
+	})
+
+	v1.Put("/evaluations/:id/review", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.ReviewBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		created, err := svc.UpdateReview(c.Context(), uniqueId, id, data.Review)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if created {
+			return c.Status(201).JSON(fiber.Map{"success": "Set review"})
+		}
+		return c.JSON(fiber.Map{"success": "Updated review"})
+	})
+
+	v1.Delete("/evaluations/:id/review", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if _, err := svc.DeleteReview(c.Context(), uniqueId, id); err != nil {
+			return sendError(c, err)
+		}
+		return c.SendStatus(204)
+	})
+
+	v1.Post("/evaluations/:id/review/restore", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if _, err := svc.RestoreReview(c.Context(), uniqueId, id); err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": "Restored review"})
+	})
+
+	v1.Put("/evaluations/:id/rating", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.Ratings
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+
+		created, err := svc.UpdateRating(c.Context(), uniqueId, id, Ratings(data))
+		if err != nil {
+			return sendError(c, err)
+		}
+		if created {
+			return c.Status(201).JSON(fiber.Map{"success": "Set rating"})
+		}
+		return c.JSON(fiber.Map{"success": "Updated rating"})
+	})
+
+	v1.Delete("/evaluations/:id/rating", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if _, err := svc.DeleteRating(c.Context(), uniqueId, id); err != nil {
+			return sendError(c, err)
+		}
+		return c.SendStatus(204)
+	})
+
+	v1.Post("/evaluations/:id/rating/restore", authed, func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		if _, err := svc.RestoreRating(c.Context(), uniqueId, id); err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": "Restored rating"})
+	})
+
+	// // // // // // // //
+	// mod / admin needed //
+	// // // // // // // //
+	v1.Put("/semesters/current", authed, requireModerator(svc), func(c *fiber.Ctx) error {
+		var semesters []string
+		if err := c.BodyParser(&semesters); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		if err := svc.SetCurrentSemesters(c.Context(), semesters); err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(semesters)
+	})
+
+	moderation := v1.Group("/moderation", authed, requireModerator(svc))
+
+	moderation.Get("/reviews", func(c *fiber.Ctx) error {
+		reviews, err := svc.UnverifiedReviews(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(reviews, toPendingReviewDTO))
+	})
+
+	moderation.Post("/reviews/:id/verify", func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		review, err := svc.VerifyReview(c.Context(), id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toReviewRecordDTO(review))
+	})
+
+	moderation.Post("/reviews/:id/reject", func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.RejectBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		review, err := svc.RejectReview(c.Context(), id, data.RequestedChanges)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toReviewRecordDTO(review))
+	})
+
+	moderation.Post("/reviews/:id/redact", func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.RedactBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		review, err := svc.RedactReview(c.Context(), uniqueId, id, data.Review, data.Reason)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toReviewRecordDTO(review))
+	})
+
+	moderation.Get("/reviews/:id/revisions", func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		revisions, err := svc.ReviewRevisions(c.Context(), id)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(revisions, toReviewRevisionDTO))
+	})
+
+	moderation.Get("/reviews/embargoed", func(c *fiber.Ctx) error {
+		reviews, err := svc.EmbargoedReviews(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(reviews)
+	})
+
+	moderation.Get("/semester-calendar", func(c *fiber.Ctx) error {
+		calendar, err := svc.SemesterCalendar(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(calendar)
+	})
+
+	moderation.Put("/semester-calendar/:semester", func(c *fiber.Ctx) error {
+		var data api.SemesterEndBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		end, err := svc.SetSemesterEnd(c.Context(), c.Params("semester"), data.EndsOn)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(end)
+	})
+
+	moderation.Get("/rating-comments", func(c *fiber.Ctx) error {
+		comments, err := svc.PendingRatingComments(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(comments, toPendingRatingCommentDTO))
+	})
+
+	moderation.Post("/rating-comments/:id/:dimension/verify", func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		comment, err := svc.VerifyRatingComment(c.Context(), id, c.Params("dimension"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toRatingCommentRecordDTO(comment))
+	})
+
+	moderation.Post("/rating-comments/:id/:dimension/reject", func(c *fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		var data api.RejectBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		comment, err := svc.RejectRatingComment(c.Context(), id, c.Params("dimension"), data.RequestedChanges)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toRatingCommentRecordDTO(comment))
+	})
+
+	moderation.Get("/usage-stats", func(c *fiber.Ctx) error {
+		userEntries, pathEntries, err := svc.UsageStats()
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(fiber.Map{"users": userEntries, "paths": pathEntries})
+	})
+
+	moderation.Post("/scrapes", func(c *fiber.Ctx) error {
+		var data api.SemesterBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		if data.Semester == "" {
+			return sendError(c, ErrMissingSemester)
+		}
+		svc.StartScrape(data.Semester)
+		return c.Status(202).JSON(fiber.Map{"success": "Scraping started"})
+	})
+
+	admin := v1.Group("/admin", authed, requireAdmin(svc))
+
+	admin.Put("/moderators/:user", func(c *fiber.Ctx) error {
+		user, err := svc.SetModerator(c.Context(), c.Params("user"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toUserDTO(user))
+	})
+
+	admin.Post("/courses", func(c *fiber.Ctx) error {
+		var data api.Course
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		course, err := svc.AddCourse(c.Context(), sql.SetCourseParams{CourseNumber: data.CourseNumber, CourseName: data.CourseName})
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.Status(201).JSON(mapAll(course, toCourseDTO))
+	})
+
+	admin.Put("/departments/:code", func(c *fiber.Ctx) error {
+		var data api.DepartmentBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		department, err := svc.SetDepartment(c.Context(), c.Params("code"), data.Name, data.Prefixes)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toDepartmentDTO(sql.GetDepartmentsRow(department)))
+	})
+
+	admin.Get("/account-deletions", func(c *fiber.Ctx) error {
+		deletions, err := svc.AccountDeletions(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(deletions)
+	})
+
+	admin.Get("/bans", func(c *fiber.Ctx) error {
+		bans, err := svc.UserBans(c.Context())
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(bans)
+	})
+
+	admin.Put("/bans/:user", func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		var data api.UserBanBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		ban, err := svc.BanUser(c.Context(), uniqueId, c.Params("user"), data)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(ban)
+	})
+
+	admin.Delete("/bans/:user", func(c *fiber.Ctx) error {
+		uniqueId, _ := c.Locals("unique_id").(string)
+		ban, err := svc.UnbanUser(c.Context(), uniqueId, c.Params("user"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(ban)
+	})
+
+	admin.Get("/rating-dimensions", func(c *fiber.Ctx) error {
+		dimensions, err := svc.RatingDimensions(c.Context(), true)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(mapAll(dimensions, toRatingDimensionDTO))
+	})
+
+	admin.Put("/rating-dimensions/:key", func(c *fiber.Ctx) error {
+		var data api.RatingDimensionBody
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+		dimension, err := svc.SetRatingDimension(c.Context(), sql.SetRatingDimensionParams{
+			Key:      c.Params("key"),
+			LabelDe:  data.LabelDe,
+			LabelEn:  data.LabelEn,
+			ScaleMin: data.ScaleMin,
+			ScaleMax: data.ScaleMax,
+			Position: data.Position,
+		})
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toRatingDimensionDTO(dimension))
+	})
+
+	// retiring keeps the values, PUT brings the dimension back
+	admin.Delete("/rating-dimensions/:key", func(c *fiber.Ctx) error {
+		dimension, err := svc.RetireRatingDimension(c.Context(), c.Params("key"))
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(toRatingDimensionDTO(dimension))
+	})
+}
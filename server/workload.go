@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coursereview/app/api"
+)
+
+const (
+	hoursPerWeekMin = 0.5
+	hoursPerWeekMax = 60
+	// lecture weeks of an ETH semester
+	semesterWeeks = 14
+	// an ECTS credit stands for 25 to 30 hours of work, ETH plans with 30
+	hoursPerCredit = 30
+)
+
+// regexHoursPerWeek finds workload mentions like "ca. 10h pro Woche", "8-10 Stunden/Woche"
+// or "about 6 hours a week" in review texts.
+var regexHoursPerWeek = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)(?:\s*(?:-|–|bis|to)\s*(\d+(?:[.,]\d+)?))?\s*(?:h|std\.?|stunden|hours?|hrs?)\s*(?:/|pro|per|a|each|every|in\s+der|die)\s*(?:woche|week|wk)\b`)
+
+// mentionedHours returns the first plausible hours per week mentioned in a review, the
+// middle of a range like "8-10h".
+func mentionedHours(review string) (float64, bool) {
+	for _, match := range regexHoursPerWeek.FindAllStringSubmatch(review, -1) {
+		hours, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", "."), 64)
+		if err != nil {
+			continue
+		}
+		if match[2] != "" {
+			upper, err := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", "."), 64)
+			if err != nil || upper < hours {
+				continue
+			}
+			hours = (hours + upper) / 2
+		}
+		if hours >= hoursPerWeekMin && hours <= hoursPerWeekMax {
+			return hours, true
+		}
+	}
+	return 0, false
+}
+
+// quantile interpolates linearly between the closest ranks of sorted values, like
+// PERCENTILE_CONT does.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// CourseWorkload aggregates the hours per week of a course and relates them to its ECTS.
+// Only the counts are returned until anonymityThreshold students gave their hours.
+func (s *Service) CourseWorkload(ctx context.Context, course string) (api.CourseWorkload, error) {
+	rows, err := s.db.GetCourseWorkload(ctx, course)
+	if err != nil {
+		return api.CourseWorkload{}, err
+	}
+	ects, err := s.db.GetCourseEcts(ctx, course)
+	if err != nil {
+		return api.CourseWorkload{}, err
+	}
+
+	workload := api.CourseWorkload{CourseNumber: course, ECTS: numericPtr(ects)}
+	var hours []float64
+	for _, row := range rows {
+		if row.HoursPerWeek.Valid {
+			hours = append(hours, row.HoursPerWeek.Float64)
+			workload.ReportedCount++
+		} else if mentioned, ok := mentionedHours(row.Review.String); ok {
+			hours = append(hours, mentioned)
+			workload.MentionedCount++
+		}
+	}
+	if !publishable(int32(len(hours))) {
+		return workload, nil
+	}
+
+	sort.Float64s(hours)
+	median, q1, q3 := quantile(hours, 0.5), quantile(hours, 0.25), quantile(hours, 0.75)
+	iqr := q3 - q1
+	workload.Median, workload.Q1, workload.Q3, workload.IQR = &median, &q1, &q3, &iqr
+	if workload.ECTS != nil && *workload.ECTS > 0 {
+		perCredit := median * semesterWeeks / *workload.ECTS
+		ratio := perCredit / hoursPerCredit
+		workload.HoursPerCredit, workload.CreditRatio = &perCredit, &ratio
+	}
+	return workload, nil
+}
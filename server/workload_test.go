@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		q      float64
+		want   float64
+	}{
+		{[]float64{5}, 0.5, 5},
+		{[]float64{5}, 0.25, 5},
+		{[]float64{2, 4}, 0.5, 3},
+		{[]float64{1, 2, 3}, 0.5, 2},
+		{[]float64{1, 2, 3, 4}, 0.5, 2.5},
+		{[]float64{1, 2, 3, 4}, 0.25, 1.75},
+		{[]float64{1, 2, 3, 4}, 0.75, 3.25},
+		{[]float64{1, 2, 3, 4, 5}, 0.25, 2},
+		{[]float64{1, 2, 3, 4, 5}, 0.75, 4},
+		{[]float64{1, 2, 3, 4, 5}, 0, 1},
+		{[]float64{1, 2, 3, 4, 5}, 1, 5},
+		{[]float64{2, 2, 8, 10}, 0.5, 5},
+	}
+	for _, tt := range tests {
+		if got := quantile(tt.sorted, tt.q); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("quantile(%v, %v) = %v, want %v", tt.sorted, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestMentionedHours(t *testing.T) {
+	tests := []struct {
+		review string
+		want   float64
+		ok     bool
+	}{
+		{"Ca. 10h pro Woche für die Serien", 10, true},
+		{"8-10 Stunden/Woche", 9, true},
+		{"about 6 hours a week", 6, true},
+		{"2,5 Std. pro Woche", 2.5, true},
+		{"4 bis 6 Stunden in der Woche", 5, true},
+		{"3 hrs per week", 3, true},
+		{"10-8 hours per week", 0, false},
+		{"100 hours per week", 0, false},
+		{"0.25h per week", 0, false},
+		{"100 hours per week, honestly more like 12 hours a week", 12, true},
+		{"The exam takes 3 hours", 0, false},
+		{"Woche 10 war hart", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := mentionedHours(tt.review)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("mentionedHours(%q) = %v, %v, want %v, %v", tt.review, got, ok, tt.want, tt.ok)
+		}
+	}
+}
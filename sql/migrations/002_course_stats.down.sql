@@ -0,0 +1,12 @@
+-- down migration: per-course aggregates
+DROP TRIGGER IF EXISTS courses_course_stats ON courses;
+DROP TRIGGER IF EXISTS course_evaluation_map_course_stats ON course_evaluation_map;
+DROP TRIGGER IF EXISTS reviews_course_stats ON reviews;
+DROP TRIGGER IF EXISTS ratings_course_stats ON ratings;
+
+DROP FUNCTION IF EXISTS course_stats_course_trigger();
+DROP FUNCTION IF EXISTS course_stats_evaluation_trigger();
+DROP FUNCTION IF EXISTS refresh_course_stats(VARCHAR);
+
+DROP VIEW IF EXISTS course_stats_computed;
+DROP TABLE IF EXISTS course_stats CASCADE;
@@ -0,0 +1,110 @@
+-- up migration: per-course aggregates maintained by triggers
+CREATE TABLE IF NOT EXISTS course_stats (
+    course_number VARCHAR(12) PRIMARY KEY, -- Course the aggregates belong to
+    rating_count INTEGER NOT NULL DEFAULT 0, -- Ratings of the course
+    recommended_sum DOUBLE PRECISION NOT NULL DEFAULT 0, -- Sum and amount of set values per dimension
+    recommended_count INTEGER NOT NULL DEFAULT 0,
+    engaging_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    engaging_count INTEGER NOT NULL DEFAULT 0,
+    difficulty_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    difficulty_count INTEGER NOT NULL DEFAULT 0,
+    effort_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    effort_count INTEGER NOT NULL DEFAULT 0,
+    resources_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    resources_count INTEGER NOT NULL DEFAULT 0,
+    review_count INTEGER NOT NULL DEFAULT 0, -- Reviews in any moderation state
+    verified_review_count INTEGER NOT NULL DEFAULT 0, -- Published reviews
+    latest_activity DATE DEFAULT NULL, -- Latest verified review or rating
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+-- single source of truth for the aggregates, used by the triggers and the reconcile command
+CREATE OR REPLACE VIEW course_stats_computed AS
+SELECT
+    courses.course_number,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    COALESCE(SUM(ratings.recommended), 0)::DOUBLE PRECISION AS recommended_sum,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    COALESCE(SUM(ratings.engaging), 0)::DOUBLE PRECISION AS engaging_sum,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    COALESCE(SUM(ratings.difficulty), 0)::DOUBLE PRECISION AS difficulty_sum,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    COALESCE(SUM(ratings.effort), 0)::DOUBLE PRECISION AS effort_sum,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    COALESCE(SUM(ratings.resources), 0)::DOUBLE PRECISION AS resources_sum,
+    COUNT(ratings.resources)::INTEGER AS resources_count,
+    COUNT(reviews.id)::INTEGER AS review_count,
+    (COUNT(reviews.id) FILTER (WHERE reviews.published = 'verified'))::INTEGER AS verified_review_count,
+    GREATEST(MAX(reviews.date) FILTER (WHERE reviews.published = 'verified'), MAX(ratings.date)) AS latest_activity
+FROM
+    courses
+    LEFT JOIN course_evaluation_map AS cem ON cem.course_number = courses.course_number
+    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
+    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+GROUP BY
+    courses.course_number;
+
+CREATE OR REPLACE FUNCTION refresh_course_stats(course VARCHAR) RETURNS VOID AS $$
+    INSERT INTO course_stats
+    SELECT * FROM course_stats_computed WHERE course_number = course
+    ON CONFLICT (course_number) DO UPDATE SET
+        rating_count = EXCLUDED.rating_count,
+        recommended_sum = EXCLUDED.recommended_sum,
+        recommended_count = EXCLUDED.recommended_count,
+        engaging_sum = EXCLUDED.engaging_sum,
+        engaging_count = EXCLUDED.engaging_count,
+        difficulty_sum = EXCLUDED.difficulty_sum,
+        difficulty_count = EXCLUDED.difficulty_count,
+        effort_sum = EXCLUDED.effort_sum,
+        effort_count = EXCLUDED.effort_count,
+        resources_sum = EXCLUDED.resources_sum,
+        resources_count = EXCLUDED.resources_count,
+        review_count = EXCLUDED.review_count,
+        verified_review_count = EXCLUDED.verified_review_count,
+        latest_activity = EXCLUDED.latest_activity;
+$$ LANGUAGE SQL;
+
+-- ratings and reviews reference the course through their evaluation
+CREATE OR REPLACE FUNCTION course_stats_evaluation_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM refresh_course_stats(course_number) FROM course_evaluation_map WHERE id = OLD.evaluation_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM refresh_course_stats(course_number) FROM course_evaluation_map WHERE id = NEW.evaluation_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION course_stats_course_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM refresh_course_stats(OLD.course_number);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM refresh_course_stats(NEW.course_number);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ratings_course_stats ON ratings;
+CREATE TRIGGER ratings_course_stats AFTER INSERT OR UPDATE OR DELETE ON ratings
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+DROP TRIGGER IF EXISTS reviews_course_stats ON reviews;
+CREATE TRIGGER reviews_course_stats AFTER INSERT OR UPDATE OR DELETE ON reviews
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+DROP TRIGGER IF EXISTS course_evaluation_map_course_stats ON course_evaluation_map;
+CREATE TRIGGER course_evaluation_map_course_stats AFTER UPDATE OF course_number OR DELETE ON course_evaluation_map
+    FOR EACH ROW EXECUTE FUNCTION course_stats_course_trigger();
+
+DROP TRIGGER IF EXISTS courses_course_stats ON courses;
+CREATE TRIGGER courses_course_stats AFTER INSERT ON courses
+    FOR EACH ROW EXECUTE FUNCTION course_stats_course_trigger();
+
+INSERT INTO course_stats
+SELECT * FROM course_stats_computed
+ON CONFLICT (course_number) DO NOTHING;
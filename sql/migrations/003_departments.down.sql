@@ -0,0 +1,3 @@
+-- down migration: departments derived from course number prefixes
+DROP TABLE IF EXISTS department_prefixes CASCADE;
+DROP TABLE IF EXISTS departments CASCADE;
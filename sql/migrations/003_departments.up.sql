@@ -0,0 +1,65 @@
+-- up migration: departments derived from course number prefixes
+CREATE TABLE IF NOT EXISTS departments (
+    code VARCHAR(16) PRIMARY KEY, -- Short name like D-INFK, the prefix itself for unknown units
+    name TEXT DEFAULT NULL -- Full name of the department
+);
+
+CREATE TABLE IF NOT EXISTS department_prefixes (
+    prefix VARCHAR(3) PRIMARY KEY, -- First three digits of a course number
+    department_code VARCHAR(16) NOT NULL, -- Department the unit belongs to
+    FOREIGN KEY (department_code) REFERENCES departments(code) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+INSERT INTO departments (code, name) VALUES
+    ('D-ARCH', 'Architecture'),
+    ('D-BAUG', 'Civil, Environmental and Geomatic Engineering'),
+    ('D-BIOL', 'Biology'),
+    ('D-BSSE', 'Biosystems Science and Engineering'),
+    ('D-CHAB', 'Chemistry and Applied Biosciences'),
+    ('D-ERDW', 'Earth Sciences'),
+    ('D-GESS', 'Humanities, Social and Political Sciences'),
+    ('D-HEST', 'Health Sciences and Technology'),
+    ('D-INFK', 'Computer Science'),
+    ('D-ITET', 'Information Technology and Electrical Engineering'),
+    ('D-MATH', 'Mathematics'),
+    ('D-MATL', 'Materials'),
+    ('D-MAVT', 'Mechanical and Process Engineering'),
+    ('D-MTEC', 'Management, Technology, and Economics'),
+    ('D-PHYS', 'Physics'),
+    ('D-USYS', 'Environmental Systems Science')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO department_prefixes (prefix, department_code) VALUES
+    ('051', 'D-ARCH'),
+    ('101', 'D-BAUG'),
+    ('103', 'D-BAUG'),
+    ('551', 'D-BIOL'),
+    ('636', 'D-BSSE'),
+    ('529', 'D-CHAB'),
+    ('535', 'D-CHAB'),
+    ('651', 'D-ERDW'),
+    ('851', 'D-GESS'),
+    ('376', 'D-HEST'),
+    ('252', 'D-INFK'),
+    ('263', 'D-INFK'),
+    ('227', 'D-ITET'),
+    ('401', 'D-MATH'),
+    ('406', 'D-MATH'),
+    ('327', 'D-MATL'),
+    ('151', 'D-MAVT'),
+    ('363', 'D-MTEC'),
+    ('402', 'D-PHYS'),
+    ('701', 'D-USYS')
+ON CONFLICT (prefix) DO NOTHING;
+
+-- units of existing courses that aren't known yet, the scraper does the same for new ones
+INSERT INTO departments (code)
+SELECT DISTINCT LEFT(course_number, 3) FROM courses
+WHERE LEFT(course_number, 3) ~ '^[0-9]{3}$'
+    AND LEFT(course_number, 3) NOT IN (SELECT prefix FROM department_prefixes)
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO department_prefixes (prefix, department_code)
+SELECT DISTINCT LEFT(course_number, 3), LEFT(course_number, 3) FROM courses
+WHERE LEFT(course_number, 3) ~ '^[0-9]{3}$'
+ON CONFLICT (prefix) DO NOTHING;
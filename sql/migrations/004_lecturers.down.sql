@@ -0,0 +1,3 @@
+-- down migration: lecturers and who taught a course in which semester
+DROP TABLE IF EXISTS course_lecturers CASCADE;
+DROP TABLE IF EXISTS lecturers CASCADE;
@@ -0,0 +1,17 @@
+-- up migration: lecturers and who taught a course in which semester
+CREATE TABLE IF NOT EXISTS lecturers (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the lecturer
+    vvz_id VARCHAR(16) NOT NULL UNIQUE, -- dozide of the lecturer in the VVZ
+    name TEXT NOT NULL -- Name as listed in the VVZ
+);
+
+CREATE TABLE IF NOT EXISTS course_lecturers (
+    course_number VARCHAR(12) NOT NULL, -- Course that was taught
+    lecturer_id INTEGER NOT NULL, -- Lecturer teaching it
+    semester VARCHAR(4) NOT NULL, -- Semester in the format of course_evaluation_map, e.g. 23HS
+    PRIMARY KEY (course_number, semester, lecturer_id),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (lecturer_id) REFERENCES lecturers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS course_lecturers_lecturer_idx ON course_lecturers (lecturer_id);
@@ -0,0 +1,2 @@
+-- down migration: semesters a course was offered in, with its ECTS
+DROP TABLE IF EXISTS course_offerings CASCADE;
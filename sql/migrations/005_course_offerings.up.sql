@@ -0,0 +1,12 @@
+-- up migration: semesters a course was offered in, with its ECTS
+CREATE TABLE IF NOT EXISTS course_offerings (
+    course_number VARCHAR(12) NOT NULL, -- Course that was offered
+    semester VARCHAR(4) NOT NULL, -- Semester in the format of course_evaluation_map, e.g. 23HS
+    ects NUMERIC(4, 1) DEFAULT NULL, -- Credits as listed in the VVZ, if the scraper found them
+    PRIMARY KEY (course_number, semester),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+INSERT INTO course_offerings (course_number, semester)
+SELECT DISTINCT course_number, semester FROM course_lecturers
+ON CONFLICT DO NOTHING;
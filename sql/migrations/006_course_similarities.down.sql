@@ -0,0 +1,2 @@
+-- down migration: item-item similarities for recommendations
+DROP TABLE IF EXISTS course_similarities CASCADE;
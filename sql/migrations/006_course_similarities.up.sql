@@ -0,0 +1,11 @@
+-- up migration: item-item similarities for recommendations, computed by a background job
+CREATE TABLE IF NOT EXISTS course_similarities (
+    course_number VARCHAR(12) NOT NULL, -- Course the similarity is computed for
+    similar_course_number VARCHAR(12) NOT NULL, -- Other course
+    similarity DOUBLE PRECISION NOT NULL, -- Adjusted cosine similarity, -1 to 1
+    common_raters INTEGER NOT NULL, -- Users that rated both courses
+    computed_at TIMESTAMPTZ NOT NULL, -- Run of the job that computed the row
+    PRIMARY KEY (course_number, similar_course_number),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (similar_course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- down migration: review text similarities between courses
+DROP TABLE IF EXISTS course_text_similarities CASCADE;
@@ -0,0 +1,10 @@
+-- up migration: review text similarities between courses, computed by a background job
+CREATE TABLE IF NOT EXISTS course_text_similarities (
+    course_number VARCHAR(12) NOT NULL, -- Course the similarity is computed for
+    similar_course_number VARCHAR(12) NOT NULL, -- One of its most similar courses
+    similarity DOUBLE PRECISION NOT NULL, -- Cosine similarity of the TF-IDF vectors, 0 to 1
+    computed_at TIMESTAMPTZ NOT NULL, -- Run of the job that computed the row
+    PRIMARY KEY (course_number, similar_course_number),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (similar_course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
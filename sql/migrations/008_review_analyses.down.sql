@@ -0,0 +1,2 @@
+-- down migration: keyphrases and sentiment of verified reviews
+DROP TABLE IF EXISTS review_analyses CASCADE;
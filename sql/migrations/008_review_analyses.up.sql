@@ -0,0 +1,10 @@
+-- up migration: keyphrases and sentiment of verified reviews, computed by a background job
+CREATE TABLE IF NOT EXISTS review_analyses (
+    evaluation_id INTEGER PRIMARY KEY, -- Review the analysis belongs to
+    review_hash TEXT NOT NULL, -- MD5 of the analyzed text, a changed review is analyzed again
+    language VARCHAR(2) NOT NULL, -- Detected language, de or en
+    sentiment DOUBLE PRECISION NOT NULL, -- Lexicon based sentiment, -1 to 1
+    phrases JSONB NOT NULL, -- Top keyphrases with the sentiment of their sentence
+    analyzed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- down migration: workload reported with a rating in hours per week
+ALTER TABLE ratings DROP COLUMN IF EXISTS hours_per_week;
@@ -0,0 +1,2 @@
+-- up migration: workload reported with a rating in hours per week
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS hours_per_week DOUBLE PRECISION DEFAULT NULL CHECK (hours_per_week BETWEEN 0.5 AND 60);
@@ -0,0 +1,3 @@
+-- down migration: configurable rating dimensions, the built-in ones are still in the ratings columns
+DROP TABLE IF EXISTS rating_values CASCADE;
+DROP TABLE IF EXISTS rating_dimensions CASCADE;
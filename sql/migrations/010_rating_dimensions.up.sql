@@ -0,0 +1,47 @@
+-- up migration: configurable rating dimensions, values move to rating_values
+CREATE TABLE IF NOT EXISTS rating_dimensions (
+    key VARCHAR(32) PRIMARY KEY, -- Name used by the API, like "recommended"
+    label_de TEXT NOT NULL, -- Label shown to German speaking users
+    label_en TEXT NOT NULL, -- Label shown to English speaking users
+    scale_min INTEGER NOT NULL DEFAULT 1, -- Lowest value that can be given
+    scale_max INTEGER NOT NULL DEFAULT 5, -- Highest value that can be given
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- Retired dimensions keep their values but can't be rated anymore
+    position INTEGER NOT NULL DEFAULT 0, -- Display order
+    CHECK (scale_min < scale_max)
+);
+
+CREATE TABLE IF NOT EXISTS rating_values (
+    evaluation_id INTEGER NOT NULL, -- Rating the value belongs to
+    dimension_key VARCHAR(32) NOT NULL, -- Dimension that was rated
+    value INTEGER NOT NULL, -- Within the scale of the dimension
+    PRIMARY KEY (evaluation_id, dimension_key),
+    FOREIGN KEY (evaluation_id) REFERENCES ratings(evaluation_id) ON DELETE CASCADE,
+    FOREIGN KEY (dimension_key) REFERENCES rating_dimensions(key) ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS rating_values_dimension_key_idx ON rating_values (dimension_key);
+
+INSERT INTO rating_dimensions (key, label_de, label_en, position) VALUES
+    ('recommended', 'Empfehlenswert', 'Recommended', 1),
+    ('engaging', 'Spannend', 'Engaging', 2),
+    ('difficulty', 'Schwierigkeit', 'Difficulty', 3),
+    ('effort', 'Aufwand', 'Effort', 4),
+    ('resources', 'Unterlagen', 'Resources', 5)
+ON CONFLICT (key) DO NOTHING;
+
+-- the columns stay as a copy of the built-in dimensions, course_stats and the legacy routes read them
+INSERT INTO rating_values (evaluation_id, dimension_key, value)
+SELECT evaluation_id, key, ROUND(value)
+FROM (
+    SELECT evaluation_id, 'recommended' AS key, recommended AS value FROM ratings
+    UNION ALL
+    SELECT evaluation_id, 'engaging', engaging FROM ratings
+    UNION ALL
+    SELECT evaluation_id, 'difficulty', difficulty FROM ratings
+    UNION ALL
+    SELECT evaluation_id, 'effort', effort FROM ratings
+    UNION ALL
+    SELECT evaluation_id, 'resources', resources FROM ratings
+) AS built_in
+WHERE value IS NOT NULL
+ON CONFLICT (evaluation_id, dimension_key) DO NOTHING;
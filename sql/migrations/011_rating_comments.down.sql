@@ -0,0 +1,7 @@
+-- down migration: optional short comment per rated dimension
+DROP INDEX IF EXISTS rating_values_pending_comments_idx;
+ALTER TABLE rating_values
+    DROP COLUMN IF EXISTS comment,
+    DROP COLUMN IF EXISTS comment_status,
+    DROP COLUMN IF EXISTS comment_requested_changes,
+    DROP COLUMN IF EXISTS comment_date;
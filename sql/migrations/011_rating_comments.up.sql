@@ -0,0 +1,8 @@
+-- up migration: optional short comment per rated dimension, moderated like reviews
+ALTER TABLE rating_values
+    ADD COLUMN IF NOT EXISTS comment TEXT DEFAULT NULL CHECK (char_length(comment) <= 280),
+    ADD COLUMN IF NOT EXISTS comment_status status DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS comment_requested_changes TEXT DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS comment_date DATE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS rating_values_pending_comments_idx ON rating_values (comment_status) WHERE comment_status = 'pending';
@@ -0,0 +1,2 @@
+-- down migration: archives of all personal data of a user
+DROP TABLE IF EXISTS data_exports CASCADE;
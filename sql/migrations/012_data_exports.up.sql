@@ -0,0 +1,14 @@
+-- up migration: archives of all personal data of a user, built in the background
+CREATE TABLE IF NOT EXISTS data_exports (
+    id VARCHAR(32) PRIMARY KEY, -- Random hex id, also part of the signed download link
+    user_id VARCHAR(128) NOT NULL, -- User the data belongs to
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    json_archive BYTEA DEFAULT NULL, -- Everything as one JSON document
+    zip_archive BYTEA DEFAULT NULL, -- The same data as one CSV per table
+    error TEXT DEFAULT NULL, -- Why building the archive failed
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports (user_id);
@@ -0,0 +1,22 @@
+-- down migration: account deletion with a grace period
+DROP TABLE IF EXISTS account_deletions CASCADE;
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_user_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(user_id);
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_evaluation_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id);
+
+ALTER TABLE ratings DROP CONSTRAINT IF EXISTS ratings_evaluation_id_fkey;
+ALTER TABLE ratings ADD CONSTRAINT ratings_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id);
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_evaluation_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id);
+
+ALTER TABLE course_evaluation_map DROP CONSTRAINT IF EXISTS course_evaluation_map_user_id_fkey;
+ALTER TABLE course_evaluation_map ADD CONSTRAINT course_evaluation_map_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(user_id);
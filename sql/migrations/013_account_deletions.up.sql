@@ -0,0 +1,37 @@
+-- up migration: account deletion with a grace period, contributions cascade with their user
+ALTER TABLE course_evaluation_map DROP CONSTRAINT IF EXISTS course_evaluation_map_user_id_fkey;
+ALTER TABLE course_evaluation_map ADD CONSTRAINT course_evaluation_map_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_evaluation_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE;
+
+ALTER TABLE ratings DROP CONSTRAINT IF EXISTS ratings_evaluation_id_fkey;
+ALTER TABLE ratings ADD CONSTRAINT ratings_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE;
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_evaluation_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE;
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_user_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the deletion
+    user_id VARCHAR(128) DEFAULT NULL, -- User to delete, cleared once the deletion ran
+    user_hash VARCHAR(64) NOT NULL, -- SHA-256 of the user id, kept as tombstone
+    mode VARCHAR(6) NOT NULL CHECK (mode IN ('delete', 'detach')), -- Delete contributions or keep them without the user
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    scheduled_for TIMESTAMPTZ NOT NULL, -- End of the grace period
+    cancelled_at TIMESTAMPTZ DEFAULT NULL,
+    completed_at TIMESTAMPTZ DEFAULT NULL,
+    evaluations INTEGER DEFAULT NULL, -- Evaluations deleted or detached
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+-- at most one deletion waiting per user
+CREATE UNIQUE INDEX IF NOT EXISTS account_deletions_pending_idx ON account_deletions (user_id)
+    WHERE cancelled_at IS NULL AND completed_at IS NULL;
@@ -0,0 +1,2 @@
+-- down migration: move a user to a new id
+DROP FUNCTION IF EXISTS rename_user(VARCHAR, VARCHAR);
@@ -0,0 +1,35 @@
+-- up migration: verified reviews of a running semester stay hidden until it ends
+CREATE TABLE IF NOT EXISTS semester_calendar (
+    semester VARCHAR(4) PRIMARY KEY, -- Semester like 24HS
+    ends_on DATE NOT NULL -- Reviews of the semester are embargoed until this day
+);
+
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS embargoed_until DATE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS reviews_embargoed_until_idx ON reviews (embargoed_until) WHERE embargoed_until IS NOT NULL;
+
+-- embargoed reviews don't count as published
+CREATE OR REPLACE VIEW course_stats_computed AS
+SELECT
+    courses.course_number,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    COALESCE(SUM(ratings.recommended), 0)::DOUBLE PRECISION AS recommended_sum,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    COALESCE(SUM(ratings.engaging), 0)::DOUBLE PRECISION AS engaging_sum,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    COALESCE(SUM(ratings.difficulty), 0)::DOUBLE PRECISION AS difficulty_sum,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    COALESCE(SUM(ratings.effort), 0)::DOUBLE PRECISION AS effort_sum,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    COALESCE(SUM(ratings.resources), 0)::DOUBLE PRECISION AS resources_sum,
+    COUNT(ratings.resources)::INTEGER AS resources_count,
+    COUNT(reviews.id)::INTEGER AS review_count,
+    (COUNT(reviews.id) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL))::INTEGER AS verified_review_count,
+    GREATEST(MAX(reviews.date) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL), MAX(ratings.date)) AS latest_activity
+FROM
+    courses
+    LEFT JOIN course_evaluation_map AS cem ON cem.course_number = courses.course_number
+    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
+    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+GROUP BY
+    courses.course_number;
@@ -0,0 +1,38 @@
+-- down migration: deleted reviews and ratings can be restored until they are purged
+CREATE OR REPLACE VIEW course_stats_computed AS
+SELECT
+    courses.course_number,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    COALESCE(SUM(ratings.recommended), 0)::DOUBLE PRECISION AS recommended_sum,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    COALESCE(SUM(ratings.engaging), 0)::DOUBLE PRECISION AS engaging_sum,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    COALESCE(SUM(ratings.difficulty), 0)::DOUBLE PRECISION AS difficulty_sum,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    COALESCE(SUM(ratings.effort), 0)::DOUBLE PRECISION AS effort_sum,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    COALESCE(SUM(ratings.resources), 0)::DOUBLE PRECISION AS resources_sum,
+    COUNT(ratings.resources)::INTEGER AS resources_count,
+    COUNT(reviews.id)::INTEGER AS review_count,
+    (COUNT(reviews.id) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL))::INTEGER AS verified_review_count,
+    GREATEST(MAX(reviews.date) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL), MAX(ratings.date)) AS latest_activity
+FROM
+    courses
+    LEFT JOIN course_evaluation_map AS cem ON cem.course_number = courses.course_number
+    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
+    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+GROUP BY
+    courses.course_number;
+
+DROP INDEX IF EXISTS ratings_deleted_at_idx;
+
+DROP INDEX IF EXISTS reviews_deleted_at_idx;
+
+-- deleted rows would show up again without the column
+DELETE FROM ratings WHERE deleted_at IS NOT NULL;
+
+DELETE FROM reviews WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE ratings DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS deleted_at;
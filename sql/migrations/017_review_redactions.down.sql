@@ -0,0 +1,8 @@
+-- down migration: moderators redact reviews, the replaced texts are kept as revisions
+DELETE FROM event_log WHERE action_id IN (SELECT id FROM actions WHERE name = 'redact_review');
+
+DELETE FROM actions WHERE name = 'redact_review';
+
+DROP TABLE IF EXISTS review_revisions;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS redacted_at;
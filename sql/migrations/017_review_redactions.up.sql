@@ -0,0 +1,18 @@
+-- up migration: moderators redact reviews, the replaced texts are kept as revisions
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS redacted_at TIMESTAMPTZ DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS review_revisions (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the revision
+    evaluation_id INTEGER NOT NULL, -- Review the text belonged to
+    review TEXT NOT NULL, -- Text before the moderator changed it
+    reason TEXT DEFAULT NULL, -- Why the moderator changed it, shown to the author
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS review_revisions_evaluation_id_idx ON review_revisions (evaluation_id);
+
+-- the event log is the audit log of moderation actions
+INSERT INTO actions (name)
+SELECT 'redact_review'
+WHERE NOT EXISTS (SELECT 1 FROM actions WHERE name = 'redact_review');
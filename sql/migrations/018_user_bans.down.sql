@@ -0,0 +1,31 @@
+-- down migration: bans of users, enforced when authenticating and submitting
+CREATE OR REPLACE FUNCTION rename_user(old_id VARCHAR, new_id VARCHAR) RETURNS VOID AS $$
+BEGIN
+    IF old_id = new_id THEN
+        RETURN;
+    END IF;
+    -- the new id may exist already if the user logged in before the rename, both are merged
+    INSERT INTO users (user_id, admin, moderator)
+    SELECT new_id, admin, moderator FROM users WHERE user_id = old_id
+    ON CONFLICT (user_id) DO UPDATE SET
+        admin = COALESCE(users.admin, FALSE) OR COALESCE(EXCLUDED.admin, FALSE),
+        moderator = COALESCE(users.moderator, FALSE) OR COALESCE(EXCLUDED.moderator, FALSE);
+
+    -- every table referencing users(user_id) must be listed here
+    UPDATE course_evaluation_map SET user_id = new_id WHERE user_id = old_id;
+    UPDATE event_log SET user_id = new_id WHERE user_id = old_id;
+    UPDATE data_exports SET user_id = new_id WHERE user_id = old_id;
+    UPDATE account_deletions SET cancelled_at = NOW()
+    WHERE user_id = old_id AND cancelled_at IS NULL AND completed_at IS NULL
+        AND EXISTS (SELECT 1 FROM account_deletions WHERE user_id = new_id AND cancelled_at IS NULL AND completed_at IS NULL);
+    UPDATE account_deletions SET user_id = new_id WHERE user_id = old_id;
+
+    DELETE FROM users WHERE user_id = old_id;
+END;
+$$ LANGUAGE plpgsql;
+
+DELETE FROM event_log WHERE action_id IN (SELECT id FROM actions WHERE name IN ('ban_user', 'unban_user'));
+
+DELETE FROM actions WHERE name IN ('ban_user', 'unban_user');
+
+DROP TABLE IF EXISTS user_bans;
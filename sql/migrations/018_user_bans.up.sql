@@ -0,0 +1,42 @@
+-- up migration: bans of users, enforced when authenticating and submitting
+CREATE TABLE IF NOT EXISTS user_bans (
+    user_id VARCHAR(128) PRIMARY KEY, -- Banned user, at most one ban at a time
+    kind VARCHAR(6) NOT NULL CHECK (kind IN ('full', 'write', 'shadow')), -- No access, read only, or submissions rejected silently
+    reason TEXT NOT NULL, -- Why the user was banned
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ DEFAULT NULL, -- NULL for a permanent ban
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- bans and lifted bans are written to the event log
+INSERT INTO actions (name)
+SELECT name FROM (VALUES ('ban_user'), ('unban_user')) AS new_actions (name)
+WHERE NOT EXISTS (SELECT 1 FROM actions WHERE actions.name = new_actions.name);
+
+CREATE OR REPLACE FUNCTION rename_user(old_id VARCHAR, new_id VARCHAR) RETURNS VOID AS $$
+BEGIN
+    IF old_id = new_id THEN
+        RETURN;
+    END IF;
+    -- the new id may exist already if the user logged in before the rename, both are merged
+    INSERT INTO users (user_id, admin, moderator)
+    SELECT new_id, admin, moderator FROM users WHERE user_id = old_id
+    ON CONFLICT (user_id) DO UPDATE SET
+        admin = COALESCE(users.admin, FALSE) OR COALESCE(EXCLUDED.admin, FALSE),
+        moderator = COALESCE(users.moderator, FALSE) OR COALESCE(EXCLUDED.moderator, FALSE);
+
+    -- every table referencing users(user_id) must be listed here
+    UPDATE course_evaluation_map SET user_id = new_id WHERE user_id = old_id;
+    UPDATE event_log SET user_id = new_id WHERE user_id = old_id;
+    UPDATE data_exports SET user_id = new_id WHERE user_id = old_id;
+    UPDATE account_deletions SET cancelled_at = NOW()
+    WHERE user_id = old_id AND cancelled_at IS NULL AND completed_at IS NULL
+        AND EXISTS (SELECT 1 FROM account_deletions WHERE user_id = new_id AND cancelled_at IS NULL AND completed_at IS NULL);
+    UPDATE account_deletions SET user_id = new_id WHERE user_id = old_id;
+    -- a ban of the new id wins, the one of the old id goes with it
+    UPDATE user_bans SET user_id = new_id
+    WHERE user_id = old_id AND NOT EXISTS (SELECT 1 FROM user_bans WHERE user_id = new_id);
+
+    DELETE FROM users WHERE user_id = old_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,2 @@
+-- down migration: attempts of data exports, a stalled export is claimed before it is built again
+ALTER TABLE data_exports DROP COLUMN IF EXISTS attempted_at;
@@ -0,0 +1,4 @@
+-- up migration: attempts of data exports, a stalled export is claimed before it is built again
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE data_exports SET attempted_at = created_at;
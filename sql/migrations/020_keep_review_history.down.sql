@@ -0,0 +1,14 @@
+-- down migration: revisions and the event log outlive the reviews they are about
+DELETE FROM event_log
+WHERE evaluation_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM course_evaluation_map WHERE id = event_log.evaluation_id);
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_evaluation_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE;
+
+DELETE FROM review_revisions
+WHERE NOT EXISTS (SELECT 1 FROM reviews WHERE evaluation_id = review_revisions.evaluation_id);
+
+ALTER TABLE review_revisions DROP CONSTRAINT IF EXISTS review_revisions_evaluation_id_fkey;
+ALTER TABLE review_revisions ADD CONSTRAINT review_revisions_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- up migration: revisions and the event log outlive the reviews they are about
+-- evaluation ids are never reused, the plain ids still point to the right review
+ALTER TABLE review_revisions DROP CONSTRAINT IF EXISTS review_revisions_evaluation_id_fkey;
+
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_evaluation_id_fkey;
@@ -0,0 +1,4 @@
+-- up migration: audit entries of the event log outlive their user
+ALTER TABLE event_log DROP CONSTRAINT IF EXISTS event_log_user_id_fkey;
+ALTER TABLE event_log ADD CONSTRAINT event_log_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+-- down migration: revisions go with their review again, only the audit entry outlives it
+ALTER TABLE review_revisions DROP CONSTRAINT IF EXISTS review_revisions_evaluation_id_fkey;
@@ -0,0 +1,7 @@
+-- up migration: revisions go with their review again, only the audit entry outlives it
+DELETE FROM review_revisions
+WHERE NOT EXISTS (SELECT 1 FROM reviews WHERE evaluation_id = review_revisions.evaluation_id);
+
+ALTER TABLE review_revisions DROP CONSTRAINT IF EXISTS review_revisions_evaluation_id_fkey;
+ALTER TABLE review_revisions ADD CONSTRAINT review_revisions_evaluation_id_fkey
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE;
@@ -0,0 +1,52 @@
+-- down migration: course_stats refreshes of the same course wait for each other, the counted rows get views of their own
+CREATE OR REPLACE FUNCTION refresh_course_stats(course VARCHAR) RETURNS VOID AS $$
+    INSERT INTO course_stats
+    SELECT * FROM course_stats_computed WHERE course_number = course
+    ON CONFLICT (course_number) DO UPDATE SET
+        rating_count = EXCLUDED.rating_count,
+        recommended_sum = EXCLUDED.recommended_sum,
+        recommended_count = EXCLUDED.recommended_count,
+        engaging_sum = EXCLUDED.engaging_sum,
+        engaging_count = EXCLUDED.engaging_count,
+        difficulty_sum = EXCLUDED.difficulty_sum,
+        difficulty_count = EXCLUDED.difficulty_count,
+        effort_sum = EXCLUDED.effort_sum,
+        effort_count = EXCLUDED.effort_count,
+        resources_sum = EXCLUDED.resources_sum,
+        resources_count = EXCLUDED.resources_count,
+        review_count = EXCLUDED.review_count,
+        verified_review_count = EXCLUDED.verified_review_count,
+        latest_activity = EXCLUDED.latest_activity;
+$$ LANGUAGE SQL;
+
+CREATE OR REPLACE VIEW course_stats_computed AS
+SELECT
+    courses.course_number,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    COALESCE(SUM(ratings.recommended), 0)::DOUBLE PRECISION AS recommended_sum,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    COALESCE(SUM(ratings.engaging), 0)::DOUBLE PRECISION AS engaging_sum,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    COALESCE(SUM(ratings.difficulty), 0)::DOUBLE PRECISION AS difficulty_sum,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    COALESCE(SUM(ratings.effort), 0)::DOUBLE PRECISION AS effort_sum,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    COALESCE(SUM(ratings.resources), 0)::DOUBLE PRECISION AS resources_sum,
+    COUNT(ratings.resources)::INTEGER AS resources_count,
+    COUNT(reviews.id)::INTEGER AS review_count,
+    (COUNT(reviews.id) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL))::INTEGER AS verified_review_count,
+    GREATEST(MAX(reviews.date) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL), MAX(ratings.date)) AS latest_activity
+FROM
+    courses
+    LEFT JOIN course_evaluation_map AS cem ON cem.course_number = courses.course_number
+    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
+    AND reviews.deleted_at IS NULL
+    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+GROUP BY
+    courses.course_number;
+
+DROP VIEW IF EXISTS counted_ratings;
+
+DROP VIEW IF EXISTS counted_reviews;
@@ -0,0 +1,13 @@
+-- down migration: aggregates are built from the values of the active dimensions, the built-in columns are a projection of them
+DROP TRIGGER IF EXISTS rating_dimensions_course_stats ON rating_dimensions;
+
+DROP TRIGGER IF EXISTS rating_values_course_stats ON rating_values;
+
+DROP FUNCTION IF EXISTS course_stats_dimension_trigger();
+
+CREATE OR REPLACE VIEW counted_ratings AS
+SELECT * FROM ratings WHERE deleted_at IS NULL AND NOT shadowed;
+
+DROP VIEW IF EXISTS built_in_rating_values;
+
+SELECT refresh_course_stats(course_number) FROM courses;
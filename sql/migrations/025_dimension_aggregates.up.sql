@@ -0,0 +1,57 @@
+-- up migration: aggregates are built from the values of the active dimensions, the built-in columns are a projection of them
+-- the values of the active built-in dimensions as columns, the legacy projection of
+-- rating_values for the aggregates and routes with a field per built-in dimension
+CREATE OR REPLACE VIEW built_in_rating_values AS
+SELECT
+    rating_values.evaluation_id,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'recommended'))::INTEGER AS recommended,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'engaging'))::INTEGER AS engaging,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'difficulty'))::INTEGER AS difficulty,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'effort'))::INTEGER AS effort,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'resources'))::INTEGER AS resources
+FROM
+    rating_values
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+WHERE
+    rating_dimensions.active
+GROUP BY
+    rating_values.evaluation_id;
+
+-- the built-in columns of a rating come from rating_values, a retired dimension reads as NULL
+CREATE OR REPLACE VIEW counted_ratings AS
+SELECT
+    ratings.id,
+    ratings.evaluation_id,
+    ratings.date,
+    built_in.recommended,
+    built_in.engaging,
+    built_in.difficulty,
+    built_in.effort,
+    built_in.resources,
+    ratings.hours_per_week,
+    ratings.deleted_at,
+    ratings.shadowed
+FROM
+    ratings
+    LEFT JOIN built_in_rating_values AS built_in ON built_in.evaluation_id = ratings.evaluation_id
+WHERE
+    ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed;
+
+-- retiring or bringing back a dimension changes the aggregates of every course
+CREATE OR REPLACE FUNCTION course_stats_dimension_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_course_stats(course_number) FROM courses;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS rating_values_course_stats ON rating_values;
+CREATE TRIGGER rating_values_course_stats AFTER INSERT OR UPDATE OR DELETE ON rating_values
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+DROP TRIGGER IF EXISTS rating_dimensions_course_stats ON rating_dimensions;
+CREATE TRIGGER rating_dimensions_course_stats AFTER UPDATE OF active ON rating_dimensions
+    FOR EACH ROW WHEN (OLD.active IS DISTINCT FROM NEW.active) EXECUTE FUNCTION course_stats_dimension_trigger();
+
+SELECT refresh_course_stats(course_number) FROM courses;
@@ -0,0 +1,2 @@
+-- down migration: reviews rejected under a shadow ban are marked, lifting the ban puts them back into the queue
+ALTER TABLE reviews DROP COLUMN IF EXISTS shadowed;
@@ -0,0 +1,16 @@
+-- up migration: reviews rejected under a shadow ban are marked, lifting the ban puts them back into the queue
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS shadowed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the reviews rejected without requested changes while their author is shadow banned
+UPDATE
+    reviews
+SET
+    shadowed = TRUE
+FROM
+    course_evaluation_map
+    JOIN user_bans ON user_bans.user_id = course_evaluation_map.user_id
+WHERE
+    reviews.evaluation_id = course_evaluation_map.id
+    AND reviews.published = 'rejected'
+    AND reviews.requested_changes IS NULL
+    AND user_bans.kind = 'shadow';
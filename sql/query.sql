@@ -7,7 +7,11 @@ FROM
     JOIN courses ON course_evaluation_map.course_number = courses.course_number
     JOIN ratings ON reviews.evaluation_id = ratings.evaluation_id
 WHERE
-    reviews.published = 'verified';
+    reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed;
 
 -- name: GetReviewedCourses :many
 SELECT
@@ -20,6 +24,8 @@ FROM
     JOIN courses ON course_evaluation_map.course_number = courses.course_number
 WHERE
     reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
 GROUP BY
     courses.course_name,
     courses.course_number
@@ -28,41 +34,34 @@ ORDER BY
 
 -- name: GetStats :one
 SELECT
-    COUNT(DISTINCT course_number) AS total_courses,
-    COUNT(*) AS total_reviews
+    COUNT(*) FILTER (WHERE verified_review_count > 0) AS total_courses,
+    COALESCE(SUM(verified_review_count), 0)::BIGINT AS total_reviews
 FROM
-    course_evaluation_map
-    JOIN reviews ON reviews.evaluation_id = course_evaluation_map.id
-WHERE
-    published = 'verified';
+    course_stats;
 
 -- name: GetAllCoursesWithReviewsOrRatings :many
 SELECT
     courses.course_number,
     courses.course_name,
-    MAX(GREATEST(reviews.date, ratings.date)) AS latest_date
+    course_stats.latest_activity AS latest_date
 FROM
-    course_evaluation_map as cem
-    JOIN courses ON cem.course_number = courses.course_number
-    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
-    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+    course_stats
+    JOIN courses ON course_stats.course_number = courses.course_number
 WHERE
-    reviews.published = 'verified'
-    OR ratings.evaluation_id IS NOT NULL
-GROUP BY
-    courses.course_number
+    course_stats.verified_review_count > 0
+    OR course_stats.rating_count > 0
 ORDER BY
     latest_date DESC;
 
 -- name: GetRatingsAvg :one
 SELECT
-    AVG(recommended) AS recommended,
-    AVG(engaging) AS engaging,
-    AVG(difficulty) AS difficulty,
-    AVG(effort) AS effort,
-    AVG(resources) AS resources
+    AVG(recommended)::numeric AS recommended,
+    AVG(engaging)::numeric AS engaging,
+    AVG(difficulty)::numeric AS difficulty,
+    AVG(effort)::numeric AS effort,
+    AVG(resources)::numeric AS resources
 FROM
-    ratings
+    counted_ratings AS ratings
     JOIN course_evaluation_map ON ratings.evaluation_id = course_evaluation_map.id
 WHERE
     course_number = @course_number;
@@ -70,13 +69,17 @@ WHERE
 -- name: GetReviews :many
 SELECT
     review,
-    semester
+    semester,
+    -- shown below a review a moderator changed
+    (CASE WHEN reviews.redacted_at IS NOT NULL THEN '[redacted by moderator]' ELSE '' END)::TEXT AS annotation
 FROM
     reviews
     JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
 WHERE
     course_number = @course_number
     AND reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
 ORDER BY
     reviews.date DESC;
 
@@ -85,11 +88,14 @@ SELECT
     reviews.review,
     reviews.published,
     reviews.requested_changes,
+    reviews.redacted_at,
+    COALESCE(reviews.shadowed, FALSE)::BOOLEAN AS review_shadowed,
     ratings.recommended,
     ratings.engaging,
     ratings.difficulty,
     ratings.effort,
     ratings.resources,
+    ratings.hours_per_week,
     semester,
     course_evaluation_map.course_number,
     course_name,
@@ -97,10 +103,16 @@ SELECT
 FROM
     course_evaluation_map
     LEFT JOIN reviews ON reviews.evaluation_id = course_evaluation_map.id
+    AND reviews.deleted_at IS NULL
     LEFT JOIN ratings ON course_evaluation_map.id = ratings.evaluation_id
+    AND ratings.deleted_at IS NULL
     JOIN courses ON course_evaluation_map.course_number = courses.course_number
 WHERE
     course_evaluation_map.user_id = @user_id
+    AND (
+        reviews.id IS NOT NULL
+        OR ratings.id IS NOT NULL
+    )
 ORDER BY
     GREATEST(reviews.date, ratings.date) DESC;
 
@@ -138,7 +150,9 @@ INSERT INTO
         engaging,
         difficulty,
         effort,
-        resources
+        resources,
+        hours_per_week,
+        shadowed
     )
 VALUES
     (
@@ -147,7 +161,9 @@ VALUES
         @engaging,
         @difficulty,
         @effort,
-        @resources
+        @resources,
+        @hours_per_week,
+        @shadowed
     ) RETURNING *;
 
 -- name: SetEventLog :many
@@ -191,13 +207,10 @@ FROM
 SELECT
     c.course_number,
     c.course_name,
-    Count(reviews.id)
+    COALESCE(course_stats.review_count, 0)::BIGINT AS count
 FROM
     courses AS c
-    LEFT JOIN course_evaluation_map ON c.course_number = course_evaluation_map.course_number
-    LEFT JOIN reviews ON course_evaluation_map.id = reviews.evaluation_id
-GROUP BY
-    c.course_number, c.course_name;
+    LEFT JOIN course_stats ON c.course_number = course_stats.course_number;
 
 -- name: GetUserReviewsAndRatings :many
 SELECT
@@ -213,7 +226,9 @@ FROM
     JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
     JOIN ratings ON reviews.evaluation_id = ratings.evaluation_id
 WHERE
-    user_id = @user_id;
+    user_id = @user_id
+    AND reviews.deleted_at IS NULL
+    AND ratings.deleted_at IS NULL;
 
 -- name: GetCourseReviews :many
 SELECT
@@ -223,7 +238,8 @@ FROM
     reviews
     JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
 WHERE
-    course_number = @course_number;
+    course_number = @course_number
+    AND reviews.deleted_at IS NULL;
 
 -- name: GetCourseRatings :many
 SELECT
@@ -231,37 +247,87 @@ SELECT
     engaging,
     difficulty,
     effort,
-    resources
+    resources,
+    hours_per_week
 FROM
-    ratings
+    counted_ratings AS ratings
     JOIN course_evaluation_map ON ratings.evaluation_id = course_evaluation_map.id
 WHERE
     course_number = @course_number;
 
 -- name: GetAllRatingsAvg :many
+WITH averages AS (
+    SELECT
+        course_number,
+        rating_count,
+        (recommended_sum / NULLIF(recommended_count, 0))::numeric AS recommended,
+        recommended_count,
+        (engaging_sum / NULLIF(engaging_count, 0))::numeric AS engaging,
+        engaging_count,
+        (difficulty_sum / NULLIF(difficulty_count, 0))::numeric AS difficulty,
+        difficulty_count,
+        (effort_sum / NULLIF(effort_count, 0))::numeric AS effort,
+        effort_count,
+        (resources_sum / NULLIF(resources_count, 0))::numeric AS resources,
+        resources_count
+    FROM
+        course_stats
+    WHERE
+        rating_count > 0
+        AND rating_count >= @min_count::INTEGER
+        AND course_number LIKE @department::TEXT || '%'
+),
+-- averages of the dimension sorted by, if sorted by one
+sort_values AS (
+    SELECT
+        cem.course_number,
+        AVG(rating_values.value)::numeric AS average
+    FROM
+        rating_values
+        JOIN counted_ratings AS ratings ON ratings.evaluation_id = rating_values.evaluation_id
+        JOIN course_evaluation_map AS cem ON cem.id = rating_values.evaluation_id
+    WHERE
+        rating_values.dimension_key = @sort_key::TEXT
+    GROUP BY
+        cem.course_number
+)
 SELECT
-    course_number,
-    AVG(recommended) AS recommended,
-    AVG(engaging) AS engaging,
-    AVG(difficulty) AS difficulty,
-    AVG(effort) AS effort,
-    AVG(resources) AS resources
+    averages.*
 FROM
-    ratings
-    JOIN course_evaluation_map ON ratings.evaluation_id = course_evaluation_map.id
-WHERE
-    recommended IS NOT NULL
-    AND engaging IS NOT NULL
-    AND difficulty IS NOT NULL
-    AND effort IS NOT NULL
-    AND resources IS NOT NULL
-GROUP BY
-    course_number
+    averages
+    LEFT JOIN sort_values ON sort_values.course_number = averages.course_number
+ORDER BY
+    -- course numbers are text, they can't share a CASE with the averages
+    CASE WHEN @sort_key::TEXT = 'courseNumber' AND NOT @descending::BOOLEAN THEN averages.course_number END ASC,
+    CASE WHEN @sort_key::TEXT = 'courseNumber' AND @descending::BOOLEAN THEN averages.course_number END DESC,
+    CASE WHEN NOT @descending::BOOLEAN THEN
+        CASE @sort_key::TEXT
+            WHEN 'count' THEN rating_count
+            ELSE sort_values.average
+        END
+    END ASC NULLS LAST,
+    CASE WHEN @descending::BOOLEAN THEN
+        CASE @sort_key::TEXT
+            WHEN 'count' THEN rating_count
+            ELSE sort_values.average
+        END
+    END DESC NULLS LAST,
+    averages.course_number
 LIMIT
     @page_limit
 OFFSET
     @page_offset;
 
+-- name: CountAllRatingsAvg :one
+SELECT
+    COUNT(*)
+FROM
+    course_stats
+WHERE
+    rating_count > 0
+    AND rating_count >= @min_count::INTEGER
+    AND course_number LIKE @department::TEXT || '%';
+
 -- name: GetLogs :many
 SELECT
     courses.course_number,
@@ -273,9 +339,10 @@ SELECT
 FROM
     event_log
     JOIN actions ON event_log.action_id = actions.id
-    JOIN course_evaluation_map ON event_log.evaluation_id = course_evaluation_map.id
-    JOIN courses ON course_evaluation_map.course_number = courses.course_number
-    JOIN users ON event_log.user_id = users.user_id
+    -- audit entries outlive their evaluation
+    LEFT JOIN course_evaluation_map ON event_log.evaluation_id = course_evaluation_map.id
+    LEFT JOIN courses ON course_evaluation_map.course_number = courses.course_number
+    LEFT JOIN users ON event_log.user_id = users.user_id
 WHERE
     DATE(date) BETWEEN @start_date
     AND @end_date;
@@ -289,7 +356,8 @@ FROM
     JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
 WHERE
     user_id = @user_id
-    AND course_number = @course_number;
+    AND course_number = @course_number
+    AND reviews.deleted_at IS NULL;
 
 -- name: GetReviewWithId :one
 SELECT
@@ -297,7 +365,8 @@ SELECT
 FROM
     reviews
 WHERE
-    evaluation_id = @evaluation_id;
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL;
 
 -- name: GetRatingWithId :one
 SELECT
@@ -305,7 +374,8 @@ SELECT
 FROM
     ratings
 WHERE
-    evaluation_id = @evaluation_id;
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL;
 
 -- name: SetCourseEvaluationMap :one
 INSERT INTO
@@ -337,7 +407,9 @@ UPDATE
 SET
     review = @review,
     old_review = CASE WHEN published = 'pending' THEN old_review ELSE review END,
-    published = 'pending'
+    published = 'pending',
+    redacted_at = NULL,
+    shadowed = FALSE
 FROM
     course_evaluation_map
 WHERE
@@ -360,10 +432,35 @@ VALUES
     (@semester) RETURNING *;
 
 -- name: DeleteReview :one
+UPDATE
+    reviews
+SET
+    deleted_at = NOW()
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: RestoreReview :one
+UPDATE
+    reviews
+SET
+    deleted_at = NULL
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at > @deleted_after RETURNING *;
+
+-- name: PurgeDeletedReview :exec
+DELETE FROM
+    reviews
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedReviews :many
 DELETE FROM
     reviews
 WHERE
-    evaluation_id = @evaluation_id RETURNING *;
+    deleted_at <= @deleted_before RETURNING evaluation_id;
 
 -- name: UpdateRating :one
 INSERT INTO ratings (
@@ -372,7 +469,9 @@ INSERT INTO ratings (
     engaging,
     difficulty,
     effort,
-    resources
+    resources,
+    hours_per_week,
+    shadowed
 )
 VALUES (
     @evaluation_id,
@@ -380,23 +479,53 @@ VALUES (
     @engaging,
     @difficulty,
     @effort,
-    @resources
+    @resources,
+    @hours_per_week,
+    @shadowed
 )
 ON CONFLICT (evaluation_id) DO UPDATE SET
     recommended = EXCLUDED.recommended,
     engaging = EXCLUDED.engaging,
     difficulty = EXCLUDED.difficulty,
     effort = EXCLUDED.effort,
-    resources = EXCLUDED.resources
+    resources = EXCLUDED.resources,
+    hours_per_week = EXCLUDED.hours_per_week,
+    shadowed = EXCLUDED.shadowed
 RETURNING *;
 
 -- name: DeleteRating :one
+UPDATE
+    ratings
+SET
+    deleted_at = NOW()
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: RestoreRating :one
+UPDATE
+    ratings
+SET
+    deleted_at = NULL
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at > @deleted_after RETURNING *;
+
+-- name: PurgeDeletedRating :exec
+DELETE FROM
+    ratings
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedRatings :many
 DELETE FROM
     ratings
 WHERE
-    evaluation_id = @evaluation_id RETURNING *;
+    deleted_at <= @deleted_before RETURNING evaluation_id;
 
 -- name: CheckRatingAndReview :one
+-- deleted ones count until they are purged, they can still be restored
 SELECT
     reviews.id,
     ratings.id
@@ -437,31 +566,71 @@ SELECT
     course_evaluation_map.course_number,
     courses.course_name,
     course_evaluation_map.user_id,
-    course_evaluation_map.id
+    course_evaluation_map.id,
+    semester_embargo.ends_on AS embargo_until
 FROM
     reviews
     JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
     JOIN courses ON course_evaluation_map.course_number = courses.course_number
+    LEFT JOIN (
+        SELECT
+            semester_calendar.semester,
+            semester_calendar.ends_on
+        FROM
+            semester_calendar
+            JOIN current_semester ON current_semester.semester = semester_calendar.semester
+        WHERE
+            semester_calendar.ends_on > CURRENT_DATE
+    ) AS semester_embargo ON semester_embargo.semester = course_evaluation_map.semester
 WHERE
-    reviews.published = 'pending';
+    reviews.published = 'pending'
+    AND reviews.deleted_at IS NULL;
 
 -- name: VerifyReview :one
+-- reviews of a current semester with a known end stay hidden until it ends
 UPDATE
     reviews
 SET
     published = 'verified',
-    requested_changes = NULL
+    requested_changes = NULL,
+    shadowed = FALSE,
+    embargoed_until = (
+        SELECT
+            semester_calendar.ends_on
+        FROM
+            course_evaluation_map
+            JOIN current_semester ON current_semester.semester = course_evaluation_map.semester
+            JOIN semester_calendar ON semester_calendar.semester = course_evaluation_map.semester
+        WHERE
+            course_evaluation_map.id = reviews.evaluation_id
+            AND semester_calendar.ends_on > CURRENT_DATE
+    )
 WHERE
-    evaluation_id = @evaluation_id RETURNING *;
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL RETURNING *;
 
 -- name: RejectReview :one
 UPDATE
     reviews
 SET
     published = 'rejected',
-    requested_changes = @requested_changes
+    requested_changes = @requested_changes,
+    shadowed = FALSE
 WHERE
-    evaluation_id = @evaluation_id RETURNING *;
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: ShadowReview :exec
+-- rejected without notifying anyone, lifting the shadow ban puts it back into the queue
+UPDATE
+    reviews
+SET
+    published = 'rejected',
+    requested_changes = NULL,
+    shadowed = TRUE
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL;
 
 -- name: AddCourse :one
 INSERT INTO
@@ -493,3 +662,1653 @@ FROM
 WHERE
     id = @evaluation_id
     AND user_id = @user_id;
+
+-- name: GetCourseStatsDrift :many
+SELECT
+    computed.course_number,
+    COALESCE(stored.rating_count, 0)::INTEGER AS stored_rating_count,
+    computed.rating_count AS computed_rating_count,
+    COALESCE(stored.recommended_sum, 0)::DOUBLE PRECISION AS stored_recommended_sum,
+    computed.recommended_sum AS computed_recommended_sum,
+    COALESCE(stored.recommended_count, 0)::INTEGER AS stored_recommended_count,
+    computed.recommended_count AS computed_recommended_count,
+    COALESCE(stored.engaging_sum, 0)::DOUBLE PRECISION AS stored_engaging_sum,
+    computed.engaging_sum AS computed_engaging_sum,
+    COALESCE(stored.engaging_count, 0)::INTEGER AS stored_engaging_count,
+    computed.engaging_count AS computed_engaging_count,
+    COALESCE(stored.difficulty_sum, 0)::DOUBLE PRECISION AS stored_difficulty_sum,
+    computed.difficulty_sum AS computed_difficulty_sum,
+    COALESCE(stored.difficulty_count, 0)::INTEGER AS stored_difficulty_count,
+    computed.difficulty_count AS computed_difficulty_count,
+    COALESCE(stored.effort_sum, 0)::DOUBLE PRECISION AS stored_effort_sum,
+    computed.effort_sum AS computed_effort_sum,
+    COALESCE(stored.effort_count, 0)::INTEGER AS stored_effort_count,
+    computed.effort_count AS computed_effort_count,
+    COALESCE(stored.resources_sum, 0)::DOUBLE PRECISION AS stored_resources_sum,
+    computed.resources_sum AS computed_resources_sum,
+    COALESCE(stored.resources_count, 0)::INTEGER AS stored_resources_count,
+    computed.resources_count AS computed_resources_count,
+    COALESCE(stored.review_count, 0)::INTEGER AS stored_review_count,
+    computed.review_count AS computed_review_count,
+    COALESCE(stored.verified_review_count, 0)::INTEGER AS stored_verified_review_count,
+    computed.verified_review_count AS computed_verified_review_count,
+    stored.latest_activity AS stored_latest_activity,
+    computed.latest_activity::DATE AS computed_latest_activity
+FROM
+    course_stats_computed AS computed
+    LEFT JOIN course_stats AS stored ON computed.course_number = stored.course_number
+WHERE
+    stored.course_number IS NULL
+    OR stored.rating_count IS DISTINCT FROM computed.rating_count
+    OR stored.recommended_sum IS DISTINCT FROM computed.recommended_sum
+    OR stored.recommended_count IS DISTINCT FROM computed.recommended_count
+    OR stored.engaging_sum IS DISTINCT FROM computed.engaging_sum
+    OR stored.engaging_count IS DISTINCT FROM computed.engaging_count
+    OR stored.difficulty_sum IS DISTINCT FROM computed.difficulty_sum
+    OR stored.difficulty_count IS DISTINCT FROM computed.difficulty_count
+    OR stored.effort_sum IS DISTINCT FROM computed.effort_sum
+    OR stored.effort_count IS DISTINCT FROM computed.effort_count
+    OR stored.resources_sum IS DISTINCT FROM computed.resources_sum
+    OR stored.resources_count IS DISTINCT FROM computed.resources_count
+    OR stored.review_count IS DISTINCT FROM computed.review_count
+    OR stored.verified_review_count IS DISTINCT FROM computed.verified_review_count
+    OR stored.latest_activity IS DISTINCT FROM computed.latest_activity
+ORDER BY
+    computed.course_number;
+
+-- name: DeleteCourseStats :exec
+DELETE FROM course_stats;
+
+-- name: RebuildCourseStats :exec
+INSERT INTO course_stats
+SELECT
+    *
+FROM
+    course_stats_computed;
+
+-- name: GetDepartments :many
+SELECT
+    departments.code,
+    departments.name,
+    ARRAY_AGG(DISTINCT department_prefixes.prefix)::TEXT[] AS prefixes,
+    COUNT(courses.course_number) AS course_count
+FROM
+    departments
+    JOIN department_prefixes ON department_prefixes.department_code = departments.code
+    LEFT JOIN courses ON LEFT(courses.course_number, 3) = department_prefixes.prefix
+GROUP BY
+    departments.code,
+    departments.name
+ORDER BY
+    departments.code;
+
+-- name: GetDepartment :one
+SELECT
+    departments.code,
+    departments.name,
+    COALESCE(ARRAY_AGG(DISTINCT department_prefixes.prefix) FILTER (WHERE department_prefixes.prefix IS NOT NULL), '{}')::TEXT[] AS prefixes,
+    COUNT(courses.course_number) AS course_count
+FROM
+    departments
+    LEFT JOIN department_prefixes ON department_prefixes.department_code = departments.code
+    LEFT JOIN courses ON LEFT(courses.course_number, 3) = department_prefixes.prefix
+WHERE
+    departments.code = @code
+GROUP BY
+    departments.code,
+    departments.name;
+
+-- name: GetDepartmentCourses :many
+SELECT
+    courses.course_number,
+    courses.course_name
+FROM
+    courses
+    JOIN department_prefixes ON LEFT(courses.course_number, 3) = department_prefixes.prefix
+WHERE
+    department_prefixes.department_code = @code
+ORDER BY
+    courses.course_number;
+
+-- name: GetDepartmentRatingsAvg :one
+SELECT
+    COUNT(*) AS course_count,
+    COALESCE(SUM(course_stats.verified_review_count), 0)::BIGINT AS review_count,
+    COALESCE(SUM(course_stats.rating_count), 0)::INTEGER AS rating_count,
+    (SUM(course_stats.recommended_sum) / NULLIF(SUM(course_stats.recommended_count), 0))::numeric AS recommended,
+    COALESCE(SUM(course_stats.recommended_count), 0)::INTEGER AS recommended_count,
+    (SUM(course_stats.engaging_sum) / NULLIF(SUM(course_stats.engaging_count), 0))::numeric AS engaging,
+    COALESCE(SUM(course_stats.engaging_count), 0)::INTEGER AS engaging_count,
+    (SUM(course_stats.difficulty_sum) / NULLIF(SUM(course_stats.difficulty_count), 0))::numeric AS difficulty,
+    COALESCE(SUM(course_stats.difficulty_count), 0)::INTEGER AS difficulty_count,
+    (SUM(course_stats.effort_sum) / NULLIF(SUM(course_stats.effort_count), 0))::numeric AS effort,
+    COALESCE(SUM(course_stats.effort_count), 0)::INTEGER AS effort_count,
+    (SUM(course_stats.resources_sum) / NULLIF(SUM(course_stats.resources_count), 0))::numeric AS resources,
+    COALESCE(SUM(course_stats.resources_count), 0)::INTEGER AS resources_count
+FROM
+    course_stats
+    JOIN department_prefixes ON LEFT(course_stats.course_number, 3) = department_prefixes.prefix
+WHERE
+    department_prefixes.department_code = @code;
+
+-- name: SetDepartment :exec
+INSERT INTO
+    departments (code, name)
+VALUES
+    (@code, @name) ON CONFLICT (code) DO
+UPDATE
+SET
+    name = EXCLUDED.name;
+
+-- name: SetDepartmentPrefixes :exec
+INSERT INTO
+    department_prefixes (prefix, department_code)
+SELECT
+    UNNEST(@prefixes::TEXT[]),
+    @code ON CONFLICT (prefix) DO
+UPDATE
+SET
+    department_code = EXCLUDED.department_code;
+
+-- name: DeleteEmptyDepartments :exec
+DELETE FROM
+    departments
+WHERE
+    code <> @keep
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            department_prefixes
+        WHERE
+            department_code = departments.code
+    );
+
+-- name: AddDepartmentPrefix :exec
+WITH department AS (
+    INSERT INTO
+        departments (code)
+    SELECT
+        @prefix::TEXT
+    WHERE
+        NOT EXISTS (
+            SELECT
+                1
+            FROM
+                department_prefixes
+            WHERE
+                prefix = @prefix::TEXT
+        ) ON CONFLICT (code) DO NOTHING
+)
+INSERT INTO
+    department_prefixes (prefix, department_code)
+VALUES
+    (@prefix::TEXT, @prefix::TEXT) ON CONFLICT (prefix) DO NOTHING;
+
+-- name: SetLecturer :one
+INSERT INTO
+    lecturers (vvz_id, name)
+VALUES
+    (@vvz_id, @name) ON CONFLICT (vvz_id) DO
+UPDATE
+SET
+    name = EXCLUDED.name RETURNING *;
+
+-- name: AddCourseLecturer :exec
+INSERT INTO
+    course_lecturers (course_number, lecturer_id, semester)
+VALUES
+    (@course_number, @lecturer_id, @semester) ON CONFLICT DO NOTHING;
+
+-- name: GetCourseLecturers :many
+SELECT
+    course_lecturers.semester,
+    lecturers.id,
+    lecturers.name
+FROM
+    course_lecturers
+    JOIN lecturers ON course_lecturers.lecturer_id = lecturers.id
+WHERE
+    course_lecturers.course_number = @course_number
+ORDER BY
+    course_lecturers.semester DESC,
+    lecturers.name;
+
+-- name: GetCourseRatingsBySemester :many
+WITH semester_lecturers AS (
+    SELECT
+        course_lecturers.semester,
+        ARRAY_AGG(lecturers.id ORDER BY lecturers.name)::INTEGER[] AS lecturer_ids,
+        ARRAY_AGG(lecturers.name ORDER BY lecturers.name)::TEXT[] AS lecturer_names
+    FROM
+        course_lecturers
+        JOIN lecturers ON course_lecturers.lecturer_id = lecturers.id
+    WHERE
+        course_lecturers.course_number = @course_number
+    GROUP BY
+        course_lecturers.semester
+)
+SELECT
+    cem.semester,
+    COALESCE(semester_lecturers.lecturer_ids, '{}')::INTEGER[] AS lecturer_ids,
+    COALESCE(semester_lecturers.lecturer_names, '{}')::TEXT[] AS lecturer_names,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    AVG(ratings.recommended)::numeric AS recommended,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    AVG(ratings.engaging)::numeric AS engaging,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    AVG(ratings.difficulty)::numeric AS difficulty,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    AVG(ratings.effort)::numeric AS effort,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    AVG(ratings.resources)::numeric AS resources,
+    COUNT(ratings.resources)::INTEGER AS resources_count
+FROM
+    counted_ratings AS ratings
+    JOIN course_evaluation_map AS cem ON ratings.evaluation_id = cem.id
+    LEFT JOIN semester_lecturers ON semester_lecturers.semester = cem.semester
+WHERE
+    cem.course_number = @course_number
+GROUP BY
+    cem.semester,
+    semester_lecturers.lecturer_ids,
+    semester_lecturers.lecturer_names
+ORDER BY
+    cem.semester DESC NULLS LAST;
+
+-- name: GetLecturer :one
+SELECT
+    *
+FROM
+    lecturers
+WHERE
+    id = @id;
+
+-- name: GetLecturerCourses :many
+SELECT
+    courses.course_number,
+    courses.course_name,
+    course_lecturers.semester
+FROM
+    course_lecturers
+    JOIN courses ON course_lecturers.course_number = courses.course_number
+WHERE
+    course_lecturers.lecturer_id = @lecturer_id
+ORDER BY
+    course_lecturers.semester DESC,
+    courses.course_number;
+
+-- name: GetLecturerRatingsAvg :one
+SELECT
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    AVG(ratings.recommended)::numeric AS recommended,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    AVG(ratings.engaging)::numeric AS engaging,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    AVG(ratings.difficulty)::numeric AS difficulty,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    AVG(ratings.effort)::numeric AS effort,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    AVG(ratings.resources)::numeric AS resources,
+    COUNT(ratings.resources)::INTEGER AS resources_count
+FROM
+    course_lecturers
+    JOIN course_evaluation_map AS cem ON cem.course_number = course_lecturers.course_number
+    AND cem.semester = course_lecturers.semester
+    JOIN counted_ratings AS ratings ON ratings.evaluation_id = cem.id
+WHERE
+    course_lecturers.lecturer_id = @lecturer_id;
+
+-- name: GetCourseRatingTrend :many
+WITH semester_lecturers AS (
+    SELECT
+        course_lecturers.semester,
+        ARRAY_AGG(lecturers.id ORDER BY lecturers.name)::INTEGER[] AS lecturer_ids,
+        ARRAY_AGG(lecturers.name ORDER BY lecturers.name)::TEXT[] AS lecturer_names
+    FROM
+        course_lecturers
+        JOIN lecturers ON course_lecturers.lecturer_id = lecturers.id
+    WHERE
+        course_lecturers.course_number = @course_number
+    GROUP BY
+        course_lecturers.semester
+)
+SELECT
+    cem.semester::TEXT AS semester,
+    COALESCE(semester_lecturers.lecturer_ids, '{}')::INTEGER[] AS lecturer_ids,
+    COALESCE(semester_lecturers.lecturer_names, '{}')::TEXT[] AS lecturer_names,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    AVG(ratings.recommended)::numeric AS recommended,
+    VAR_SAMP(ratings.recommended)::numeric AS recommended_variance,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    AVG(ratings.engaging)::numeric AS engaging,
+    VAR_SAMP(ratings.engaging)::numeric AS engaging_variance,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    AVG(ratings.difficulty)::numeric AS difficulty,
+    VAR_SAMP(ratings.difficulty)::numeric AS difficulty_variance,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    AVG(ratings.effort)::numeric AS effort,
+    VAR_SAMP(ratings.effort)::numeric AS effort_variance,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    AVG(ratings.resources)::numeric AS resources,
+    VAR_SAMP(ratings.resources)::numeric AS resources_variance,
+    COUNT(ratings.resources)::INTEGER AS resources_count
+FROM
+    counted_ratings AS ratings
+    JOIN course_evaluation_map AS cem ON ratings.evaluation_id = cem.id
+    LEFT JOIN semester_lecturers ON semester_lecturers.semester = cem.semester
+WHERE
+    cem.course_number = @course_number
+    AND cem.semester IS NOT NULL
+GROUP BY
+    cem.semester,
+    semester_lecturers.lecturer_ids,
+    semester_lecturers.lecturer_names;
+
+-- name: SetCourseOffering :exec
+INSERT INTO
+    course_offerings (course_number, semester, ects)
+VALUES
+    (@course_number, @semester, @ects) ON CONFLICT (course_number, semester) DO
+UPDATE
+SET
+    ects = COALESCE(EXCLUDED.ects, course_offerings.ects);
+
+-- name: CompareCourses :many
+WITH value_counts AS (
+    SELECT
+        cem.course_number,
+        rating_values.dimension_key,
+        rating_values.value,
+        COUNT(*) AS count
+    FROM
+        rating_values
+        JOIN counted_ratings AS ratings ON ratings.evaluation_id = rating_values.evaluation_id
+        JOIN course_evaluation_map AS cem ON cem.id = rating_values.evaluation_id
+    WHERE
+        cem.course_number = ANY(@course_numbers::TEXT[])
+    GROUP BY
+        cem.course_number,
+        rating_values.dimension_key,
+        rating_values.value
+),
+distributions AS (
+    SELECT
+        course_number,
+        JSONB_OBJECT_AGG(dimension_key, counts) AS distributions
+    FROM
+        (
+            -- a count per value of the scale of every active dimension, values of a former
+            -- scale are left out
+            SELECT
+                rated.course_number,
+                rated.dimension_key,
+                JSONB_AGG(COALESCE(value_counts.count, 0) ORDER BY scale.value) AS counts
+            FROM
+                (
+                    SELECT DISTINCT
+                        course_number,
+                        dimension_key
+                    FROM
+                        value_counts
+                ) AS rated
+                JOIN rating_dimensions ON rating_dimensions.key = rated.dimension_key
+                CROSS JOIN LATERAL GENERATE_SERIES(rating_dimensions.scale_min, rating_dimensions.scale_max) AS scale(value)
+                LEFT JOIN value_counts ON value_counts.course_number = rated.course_number
+                AND value_counts.dimension_key = rated.dimension_key
+                AND value_counts.value = scale.value
+            WHERE
+                rating_dimensions.active
+            GROUP BY
+                rated.course_number,
+                rated.dimension_key
+        ) AS dimension_counts
+    GROUP BY
+        course_number
+),
+latest_reviews AS (
+    SELECT
+        course_number,
+        JSONB_AGG(
+            JSONB_BUILD_OBJECT('review', snippet, 'semester', semester, 'date', date, 'annotation', annotation)
+            ORDER BY date DESC, id DESC
+        ) AS reviews
+    FROM
+        (
+            SELECT
+                cem.course_number,
+                cem.semester,
+                reviews.id,
+                reviews.date,
+                LEFT(reviews.review, @snippet_length::INTEGER) AS snippet,
+                CASE WHEN reviews.redacted_at IS NOT NULL THEN '[redacted by moderator]' END AS annotation,
+                ROW_NUMBER() OVER (
+                    PARTITION BY cem.course_number
+                    ORDER BY reviews.date DESC, reviews.id DESC
+                ) AS position
+            FROM
+                reviews
+                JOIN course_evaluation_map AS cem ON reviews.evaluation_id = cem.id
+            WHERE
+                cem.course_number = ANY(@course_numbers::TEXT[])
+                AND reviews.published = 'verified'
+                AND reviews.embargoed_until IS NULL
+                AND reviews.deleted_at IS NULL
+        ) AS ranked
+    WHERE
+        position <= @snippet_count::INTEGER
+    GROUP BY
+        course_number
+),
+offerings AS (
+    SELECT
+        course_number,
+        ARRAY_AGG(semester)::TEXT[] AS semesters,
+        (ARRAY_AGG(ects ORDER BY semester DESC) FILTER (WHERE ects IS NOT NULL))[1]::numeric AS ects
+    FROM
+        course_offerings
+    WHERE
+        course_number = ANY(@course_numbers::TEXT[])
+    GROUP BY
+        course_number
+),
+semester_lecturers AS (
+    SELECT
+        course_number,
+        JSONB_AGG(
+            JSONB_BUILD_OBJECT('semester', semester, 'lecturers', lecturers)
+            ORDER BY semester DESC
+        ) AS lecturers
+    FROM
+        (
+            SELECT
+                course_lecturers.course_number,
+                course_lecturers.semester,
+                JSONB_AGG(
+                    JSONB_BUILD_OBJECT('id', lecturers.id, 'name', lecturers.name)
+                    ORDER BY lecturers.name
+                ) AS lecturers
+            FROM
+                course_lecturers
+                JOIN lecturers ON course_lecturers.lecturer_id = lecturers.id
+            WHERE
+                course_lecturers.course_number = ANY(@course_numbers::TEXT[])
+            GROUP BY
+                course_lecturers.course_number,
+                course_lecturers.semester
+        ) AS per_semester
+    GROUP BY
+        course_number
+)
+SELECT
+    courses.course_number,
+    courses.course_name,
+    offerings.ects,
+    COALESCE(offerings.semesters, '{}')::TEXT[] AS semesters,
+    COALESCE(course_stats.rating_count, 0)::INTEGER AS rating_count,
+    (course_stats.recommended_sum / NULLIF(course_stats.recommended_count, 0))::numeric AS recommended,
+    COALESCE(course_stats.recommended_count, 0)::INTEGER AS recommended_count,
+    (course_stats.engaging_sum / NULLIF(course_stats.engaging_count, 0))::numeric AS engaging,
+    COALESCE(course_stats.engaging_count, 0)::INTEGER AS engaging_count,
+    (course_stats.difficulty_sum / NULLIF(course_stats.difficulty_count, 0))::numeric AS difficulty,
+    COALESCE(course_stats.difficulty_count, 0)::INTEGER AS difficulty_count,
+    (course_stats.effort_sum / NULLIF(course_stats.effort_count, 0))::numeric AS effort,
+    COALESCE(course_stats.effort_count, 0)::INTEGER AS effort_count,
+    (course_stats.resources_sum / NULLIF(course_stats.resources_count, 0))::numeric AS resources,
+    COALESCE(course_stats.resources_count, 0)::INTEGER AS resources_count,
+    COALESCE(distributions.distributions, '{}')::JSONB AS distributions,
+    COALESCE(latest_reviews.reviews, '[]')::JSONB AS latest_reviews,
+    COALESCE(semester_lecturers.lecturers, '[]')::JSONB AS lecturers
+FROM
+    courses
+    LEFT JOIN course_stats ON course_stats.course_number = courses.course_number
+    LEFT JOIN distributions ON distributions.course_number = courses.course_number
+    LEFT JOIN latest_reviews ON latest_reviews.course_number = courses.course_number
+    LEFT JOIN offerings ON offerings.course_number = courses.course_number
+    LEFT JOIN semester_lecturers ON semester_lecturers.course_number = courses.course_number
+WHERE
+    courses.course_number = ANY(@course_numbers::TEXT[]);
+
+-- name: ComputeCourseSimilarities :exec
+-- a user's score of a course is the mean of "recommended" and "engaging", centered on the
+-- user's mean so generous and strict raters compare
+WITH scores AS (
+    SELECT
+        cem.user_id,
+        cem.course_number,
+        AVG(score) AS score
+    FROM
+        counted_ratings AS ratings
+        JOIN course_evaluation_map AS cem ON ratings.evaluation_id = cem.id
+        CROSS JOIN UNNEST(ARRAY[ratings.recommended, ratings.engaging]) AS score
+    WHERE
+        score IS NOT NULL
+    GROUP BY
+        cem.user_id,
+        cem.course_number
+),
+centered AS (
+    SELECT
+        user_id,
+        course_number,
+        score - AVG(score) OVER (PARTITION BY user_id) AS score
+    FROM
+        scores
+),
+pairs AS (
+    SELECT
+        a.course_number,
+        b.course_number AS similar_course_number,
+        SUM(a.score * b.score) AS dot,
+        SQRT(SUM(a.score * a.score)) * SQRT(SUM(b.score * b.score)) AS norm,
+        COUNT(*) AS common_raters
+    FROM
+        centered AS a
+        JOIN centered AS b ON a.user_id = b.user_id
+        AND a.course_number <> b.course_number
+    GROUP BY
+        a.course_number,
+        b.course_number
+)
+INSERT INTO
+    course_similarities (course_number, similar_course_number, similarity, common_raters, computed_at)
+SELECT
+    course_number,
+    similar_course_number,
+    dot / norm,
+    common_raters,
+    @computed_at
+FROM
+    pairs
+WHERE
+    norm > 0
+    AND common_raters >= @min_common_raters::INTEGER ON CONFLICT (course_number, similar_course_number) DO
+UPDATE
+SET
+    similarity = EXCLUDED.similarity,
+    common_raters = EXCLUDED.common_raters,
+    computed_at = EXCLUDED.computed_at;
+
+-- name: DeleteCourseSimilaritiesBefore :exec
+DELETE FROM
+    course_similarities
+WHERE
+    computed_at < @computed_at;
+
+-- name: GetRecommendations :many
+WITH user_scores AS (
+    SELECT
+        cem.course_number,
+        AVG(score) AS score
+    FROM
+        ratings
+        JOIN course_evaluation_map AS cem ON ratings.evaluation_id = cem.id
+        CROSS JOIN UNNEST(ARRAY[ratings.recommended, ratings.engaging]) AS score
+    WHERE
+        cem.user_id = @user_id
+        AND score IS NOT NULL
+        AND ratings.deleted_at IS NULL
+    GROUP BY
+        cem.course_number
+),
+candidates AS (
+    SELECT
+        s.similar_course_number AS course_number,
+        SUM(s.similarity * user_scores.score) / SUM(s.similarity) AS predicted,
+        (ARRAY_AGG(s.course_number ORDER BY s.similarity * user_scores.score DESC))[1]::TEXT AS based_on,
+        (ARRAY_AGG(s.similarity ORDER BY s.similarity * user_scores.score DESC))[1]::DOUBLE PRECISION AS based_on_similarity,
+        (ARRAY_AGG(user_scores.score ORDER BY s.similarity * user_scores.score DESC))[1]::DOUBLE PRECISION AS based_on_score
+    FROM
+        course_similarities AS s
+        JOIN user_scores ON user_scores.course_number = s.course_number
+    WHERE
+        s.similarity > 0
+        AND s.similar_course_number NOT IN (
+            SELECT
+                course_number
+            FROM
+                course_evaluation_map
+            WHERE
+                user_id = @user_id
+        )
+    GROUP BY
+        s.similar_course_number
+)
+SELECT
+    candidates.course_number,
+    courses.course_name,
+    candidates.predicted::numeric AS predicted,
+    candidates.based_on,
+    based_on_course.course_name AS based_on_name,
+    candidates.based_on_similarity,
+    candidates.based_on_score
+FROM
+    candidates
+    JOIN courses ON courses.course_number = candidates.course_number
+    JOIN courses AS based_on_course ON based_on_course.course_number = candidates.based_on
+ORDER BY
+    candidates.predicted DESC,
+    candidates.based_on_similarity DESC
+LIMIT
+    @max_results;
+
+-- name: GetUserDepartments :many
+SELECT DISTINCT
+    department_prefixes.department_code
+FROM
+    course_evaluation_map
+    JOIN department_prefixes ON LEFT(course_evaluation_map.course_number, 3) = department_prefixes.prefix
+WHERE
+    course_evaluation_map.user_id = @user_id;
+
+-- name: GetPopularCourses :many
+SELECT
+    course_stats.course_number,
+    courses.course_name,
+    department_prefixes.department_code,
+    (course_stats.recommended_sum / NULLIF(course_stats.recommended_count, 0))::numeric AS recommended,
+    course_stats.rating_count
+FROM
+    course_stats
+    JOIN courses ON courses.course_number = course_stats.course_number
+    LEFT JOIN department_prefixes ON LEFT(course_stats.course_number, 3) = department_prefixes.prefix
+WHERE
+    course_stats.recommended_count >= @min_count::INTEGER
+    AND (
+        CARDINALITY(@departments::TEXT[]) = 0
+        OR department_prefixes.department_code = ANY(@departments::TEXT[])
+    )
+    AND course_stats.course_number NOT IN (
+        SELECT
+            course_number
+        FROM
+            course_evaluation_map
+        WHERE
+            user_id = @user_id
+    )
+ORDER BY
+    recommended DESC,
+    course_stats.rating_count DESC
+LIMIT
+    @max_results;
+
+-- name: GetVerifiedReviewTexts :many
+SELECT
+    courses.course_number,
+    courses.course_name,
+    reviews.review
+FROM
+    reviews
+    JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
+    JOIN courses ON course_evaluation_map.course_number = courses.course_number
+WHERE
+    reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
+ORDER BY
+    courses.course_number;
+
+-- name: SetCourseTextSimilarities :exec
+INSERT INTO
+    course_text_similarities (course_number, similar_course_number, similarity, computed_at)
+SELECT
+    UNNEST(@course_numbers::TEXT[]),
+    UNNEST(@similar_course_numbers::TEXT[]),
+    UNNEST(@similarities::DOUBLE PRECISION[]),
+    @computed_at ON CONFLICT (course_number, similar_course_number) DO
+UPDATE
+SET
+    similarity = EXCLUDED.similarity,
+    computed_at = EXCLUDED.computed_at;
+
+-- name: DeleteCourseTextSimilaritiesBefore :exec
+DELETE FROM
+    course_text_similarities
+WHERE
+    computed_at < @computed_at;
+
+-- name: GetSimilarCourses :many
+SELECT
+    courses.course_number,
+    courses.course_name,
+    course_text_similarities.similarity
+FROM
+    course_text_similarities
+    JOIN courses ON courses.course_number = course_text_similarities.similar_course_number
+WHERE
+    course_text_similarities.course_number = @course_number
+ORDER BY
+    course_text_similarities.similarity DESC;
+
+-- name: GetUnanalyzedReviews :many
+SELECT
+    reviews.evaluation_id,
+    reviews.review,
+    MD5(reviews.review) AS review_hash
+FROM
+    reviews
+    LEFT JOIN review_analyses ON review_analyses.evaluation_id = reviews.evaluation_id
+WHERE
+    reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
+    AND (
+        review_analyses.evaluation_id IS NULL
+        OR review_analyses.review_hash <> MD5(reviews.review)
+    );
+
+-- name: SetReviewAnalysis :exec
+INSERT INTO
+    review_analyses (evaluation_id, review_hash, language, sentiment, phrases, analyzed_at)
+VALUES
+    (@evaluation_id, @review_hash, @language, @sentiment, @phrases, NOW()) ON CONFLICT (evaluation_id) DO
+UPDATE
+SET
+    review_hash = EXCLUDED.review_hash,
+    language = EXCLUDED.language,
+    sentiment = EXCLUDED.sentiment,
+    phrases = EXCLUDED.phrases,
+    analyzed_at = EXCLUDED.analyzed_at;
+
+-- name: GetCourseReviewAnalyses :many
+SELECT
+    course_evaluation_map.semester,
+    review_analyses.language,
+    review_analyses.sentiment,
+    review_analyses.phrases
+FROM
+    review_analyses
+    JOIN reviews ON reviews.evaluation_id = review_analyses.evaluation_id
+    JOIN course_evaluation_map ON course_evaluation_map.id = review_analyses.evaluation_id
+WHERE
+    course_evaluation_map.course_number = @course_number
+    AND reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
+    AND review_analyses.review_hash = MD5(reviews.review);
+
+-- name: GetCourseWorkload :many
+SELECT
+    ratings.hours_per_week,
+    reviews.review
+FROM
+    course_evaluation_map
+    LEFT JOIN ratings ON ratings.evaluation_id = course_evaluation_map.id
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+    LEFT JOIN reviews ON reviews.evaluation_id = course_evaluation_map.id
+    AND reviews.published = 'verified'
+    AND reviews.embargoed_until IS NULL
+    AND reviews.deleted_at IS NULL
+WHERE
+    course_evaluation_map.course_number = @course_number
+    AND (
+        ratings.hours_per_week IS NOT NULL
+        OR reviews.review IS NOT NULL
+    );
+
+-- name: GetCourseEcts :one
+SELECT
+    (ARRAY_AGG(ects ORDER BY semester DESC) FILTER (WHERE ects IS NOT NULL))[1]::numeric AS ects
+FROM
+    course_offerings
+WHERE
+    course_number = @course_number;
+
+-- name: GetRatingDimensions :many
+SELECT
+    *
+FROM
+    rating_dimensions
+WHERE
+    active
+    OR @include_retired::BOOLEAN
+ORDER BY
+    position,
+    key;
+
+-- name: SetRatingDimension :one
+INSERT INTO
+    rating_dimensions (key, label_de, label_en, scale_min, scale_max, position)
+VALUES
+    (
+        @key,
+        @label_de,
+        @label_en,
+        @scale_min,
+        @scale_max,
+        COALESCE(
+            NULLIF(@position::INTEGER, 0),
+            (SELECT COALESCE(MAX(position), 0) + 1 FROM rating_dimensions)
+        )
+    ) ON CONFLICT (key) DO
+UPDATE
+SET
+    label_de = EXCLUDED.label_de,
+    label_en = EXCLUDED.label_en,
+    position = CASE WHEN @position::INTEGER = 0 THEN rating_dimensions.position ELSE EXCLUDED.position END,
+    active = TRUE RETURNING *;
+
+-- name: RetireRatingDimension :one
+UPDATE
+    rating_dimensions
+SET
+    active = FALSE
+WHERE
+    key = @key RETURNING *;
+
+-- name: SetRatingValues :exec
+WITH removed AS (
+    DELETE FROM
+        rating_values
+    WHERE
+        evaluation_id = @evaluation_id
+        AND dimension_key <> ALL(@dimension_keys::TEXT[])
+)
+INSERT INTO
+    rating_values (
+        evaluation_id,
+        dimension_key,
+        value,
+        comment,
+        comment_status,
+        comment_date
+    )
+SELECT
+    @evaluation_id,
+    new_values.dimension_key,
+    new_values.value,
+    NULLIF(new_values.comment, ''),
+    CASE WHEN new_values.comment = '' THEN NULL ELSE 'pending'::status END,
+    CASE WHEN new_values.comment = '' THEN NULL ELSE NOW()::DATE END
+FROM
+    (
+        SELECT
+            UNNEST(@dimension_keys::TEXT[]) AS dimension_key,
+            UNNEST(@values::INTEGER[]) AS value,
+            UNNEST(@comments::TEXT[]) AS comment
+    ) AS new_values ON CONFLICT (evaluation_id, dimension_key) DO
+UPDATE
+SET
+    value = EXCLUDED.value,
+    comment = EXCLUDED.comment,
+    -- an unchanged comment keeps its moderation state
+    comment_status = CASE WHEN EXCLUDED.comment IS NOT DISTINCT FROM rating_values.comment THEN rating_values.comment_status ELSE EXCLUDED.comment_status END,
+    comment_requested_changes = CASE WHEN EXCLUDED.comment IS NOT DISTINCT FROM rating_values.comment THEN rating_values.comment_requested_changes ELSE NULL END,
+    comment_date = CASE WHEN EXCLUDED.comment IS NOT DISTINCT FROM rating_values.comment THEN rating_values.comment_date ELSE EXCLUDED.comment_date END;
+
+-- name: GetRatingValues :many
+SELECT
+    dimension_key,
+    value
+FROM
+    rating_values
+WHERE
+    evaluation_id = @evaluation_id;
+
+-- name: GetCourseDimensionAverages :many
+SELECT
+    rating_dimensions.key,
+    rating_dimensions.label_de,
+    rating_dimensions.label_en,
+    rating_dimensions.scale_min,
+    rating_dimensions.scale_max,
+    AVG(course_values.value)::numeric AS average,
+    COUNT(course_values.value) AS count
+FROM
+    rating_dimensions
+    LEFT JOIN (
+        SELECT
+            rating_values.dimension_key,
+            rating_values.value
+        FROM
+            rating_values
+            JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+            AND ratings.deleted_at IS NULL
+            AND NOT ratings.shadowed
+            JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+        WHERE
+            course_evaluation_map.course_number = @course_number
+    ) AS course_values ON course_values.dimension_key = rating_dimensions.key
+WHERE
+    rating_dimensions.active
+GROUP BY
+    rating_dimensions.key
+ORDER BY
+    rating_dimensions.position,
+    rating_dimensions.key;
+
+-- name: GetUserRatingValues :many
+SELECT
+    rating_values.evaluation_id,
+    rating_values.dimension_key,
+    rating_values.value,
+    rating_values.comment,
+    rating_values.comment_status,
+    rating_values.comment_requested_changes
+FROM
+    rating_values
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    AND ratings.deleted_at IS NULL
+    JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id;
+
+-- name: GetCourseDimensionDistribution :many
+SELECT
+    rating_values.dimension_key,
+    rating_values.value,
+    COUNT(*) AS count
+FROM
+    rating_values
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+    JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+WHERE
+    course_evaluation_map.course_number = @course_number
+GROUP BY
+    rating_values.dimension_key,
+    rating_values.value;
+
+-- name: GetCourseDimensionComments :many
+SELECT
+    dimension_key,
+    value,
+    comment,
+    semester,
+    comment_date
+FROM
+    (
+        SELECT
+            rating_values.dimension_key,
+            rating_values.value,
+            rating_values.comment,
+            course_evaluation_map.semester,
+            rating_values.comment_date,
+            ROW_NUMBER() OVER (
+                PARTITION BY rating_values.dimension_key
+                ORDER BY
+                    rating_values.comment_date DESC,
+                    rating_values.evaluation_id DESC
+            ) AS position
+        FROM
+            rating_values
+            JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+            AND ratings.deleted_at IS NULL
+            AND NOT ratings.shadowed
+            JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+        WHERE
+            course_evaluation_map.course_number = @course_number
+            AND rating_values.comment_status = 'verified'
+    ) AS comments
+WHERE
+    position <= @per_dimension::INTEGER
+ORDER BY
+    dimension_key,
+    position;
+
+-- name: GetPendingRatingComments :many
+SELECT
+    rating_values.evaluation_id,
+    rating_values.dimension_key,
+    rating_values.value,
+    rating_values.comment,
+    course_evaluation_map.course_number,
+    courses.course_name,
+    course_evaluation_map.user_id,
+    ratings.shadowed
+FROM
+    rating_values
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    AND ratings.deleted_at IS NULL
+    JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+    JOIN courses ON courses.course_number = course_evaluation_map.course_number
+WHERE
+    rating_values.comment_status = 'pending'
+ORDER BY
+    rating_values.comment_date,
+    rating_values.evaluation_id;
+
+-- name: VerifyRatingComment :one
+UPDATE
+    rating_values
+SET
+    comment_status = 'verified',
+    comment_requested_changes = NULL
+WHERE
+    evaluation_id = @evaluation_id
+    AND dimension_key = @dimension_key
+    AND comment IS NOT NULL RETURNING *;
+
+-- name: RejectRatingComment :one
+UPDATE
+    rating_values
+SET
+    comment_status = 'rejected',
+    comment_requested_changes = @requested_changes
+WHERE
+    evaluation_id = @evaluation_id
+    AND dimension_key = @dimension_key
+    AND comment IS NOT NULL RETURNING *;
+
+-- name: CreateDataExport :one
+INSERT INTO
+    data_exports (id, user_id)
+VALUES
+    (@id, @user_id) RETURNING id, user_id, status, error, created_at, finished_at;
+
+-- name: GetPendingDataExport :one
+SELECT
+    id,
+    user_id,
+    status,
+    error,
+    created_at,
+    finished_at
+FROM
+    data_exports
+WHERE
+    user_id = @user_id
+    AND status = 'pending'
+ORDER BY
+    created_at DESC
+LIMIT
+    1;
+
+-- name: GetDataExport :one
+SELECT
+    id,
+    user_id,
+    status,
+    error,
+    created_at,
+    finished_at
+FROM
+    data_exports
+WHERE
+    id = @id
+    AND user_id = @user_id;
+
+-- name: GetDataExportArchive :one
+SELECT
+    (CASE WHEN @zip::BOOLEAN THEN zip_archive ELSE json_archive END)::BYTEA AS archive
+FROM
+    data_exports
+WHERE
+    id = @id
+    AND status = 'ready'
+    AND created_at > @created_after;
+
+-- name: FinishDataExport :exec
+UPDATE
+    data_exports
+SET
+    status = 'ready',
+    json_archive = @json_archive,
+    zip_archive = @zip_archive,
+    finished_at = NOW()
+WHERE
+    id = @id;
+
+-- name: FailDataExport :exec
+UPDATE
+    data_exports
+SET
+    status = 'failed',
+    error = @error,
+    finished_at = NOW()
+WHERE
+    id = @id;
+
+-- name: ClaimStalledDataExport :one
+UPDATE
+    data_exports
+SET
+    attempted_at = NOW()
+WHERE
+    id = (
+        SELECT
+            stalled.id
+        FROM
+            data_exports AS stalled
+        WHERE
+            stalled.status = 'pending'
+            AND stalled.attempted_at < @attempted_before
+        ORDER BY
+            stalled.attempted_at
+        LIMIT
+            1 FOR UPDATE SKIP LOCKED
+    ) RETURNING id,
+    user_id;
+
+-- name: DeleteDataExportsBefore :exec
+DELETE FROM
+    data_exports
+WHERE
+    created_at < @created_before;
+
+-- name: ExportUserEvaluations :many
+SELECT
+    id,
+    course_number,
+    semester
+FROM
+    course_evaluation_map
+WHERE
+    user_id = @user_id
+ORDER BY
+    id;
+
+-- name: ExportUserReviews :many
+SELECT
+    reviews.evaluation_id,
+    reviews.date,
+    reviews.published,
+    reviews.review,
+    reviews.requested_changes,
+    reviews.old_review,
+    reviews.shadowed
+FROM
+    reviews
+    JOIN course_evaluation_map ON course_evaluation_map.id = reviews.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id
+ORDER BY
+    reviews.evaluation_id;
+
+-- name: ExportUserRatings :many
+SELECT
+    ratings.evaluation_id,
+    ratings.date,
+    ratings.hours_per_week,
+    ratings.shadowed
+FROM
+    ratings
+    JOIN course_evaluation_map ON course_evaluation_map.id = ratings.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id
+ORDER BY
+    ratings.evaluation_id;
+
+-- name: ExportUserBans :many
+SELECT
+    kind,
+    reason,
+    created_at,
+    expires_at
+FROM
+    user_bans
+WHERE
+    user_id = @user_id
+ORDER BY
+    created_at;
+
+-- name: ExportUserAccountDeletions :many
+-- completed deletions only keep the hash of the user id
+SELECT
+    id,
+    mode,
+    requested_at,
+    scheduled_for,
+    cancelled_at,
+    completed_at,
+    evaluations
+FROM
+    account_deletions
+WHERE
+    user_id = @user_id
+    OR user_hash = @user_hash
+ORDER BY
+    requested_at;
+
+-- name: ExportUserRatingValues :many
+SELECT
+    rating_values.*
+FROM
+    rating_values
+    JOIN course_evaluation_map ON course_evaluation_map.id = rating_values.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id
+ORDER BY
+    rating_values.evaluation_id,
+    rating_values.dimension_key;
+
+-- name: ExportUserEventLog :many
+SELECT
+    event_log.id,
+    event_log.evaluation_id,
+    actions.name AS action,
+    event_log.info,
+    event_log.date
+FROM
+    event_log
+    LEFT JOIN actions ON actions.id = event_log.action_id
+WHERE
+    event_log.user_id = @user_id
+ORDER BY
+    event_log.id;
+
+-- name: RequestAccountDeletion :one
+INSERT INTO
+    account_deletions (user_id, user_hash, mode, scheduled_for)
+VALUES
+    (@user_id, @user_hash, @mode, @scheduled_for) RETURNING *;
+
+-- name: GetPendingAccountDeletion :one
+SELECT
+    *
+FROM
+    account_deletions
+WHERE
+    user_id = @user_id
+    AND cancelled_at IS NULL
+    AND completed_at IS NULL;
+
+-- name: CancelAccountDeletion :one
+UPDATE
+    account_deletions
+SET
+    cancelled_at = NOW()
+WHERE
+    user_id = @user_id
+    AND cancelled_at IS NULL
+    AND completed_at IS NULL RETURNING *;
+
+-- name: GetDueAccountDeletions :many
+SELECT
+    *
+FROM
+    account_deletions
+WHERE
+    user_id IS NOT NULL
+    AND cancelled_at IS NULL
+    AND completed_at IS NULL
+    AND scheduled_for <= NOW()
+ORDER BY
+    scheduled_for;
+
+-- name: GetAccountDeletions :many
+SELECT
+    *
+FROM
+    account_deletions
+ORDER BY
+    requested_at DESC;
+
+-- name: DetachUserEvaluations :execrows
+UPDATE
+    course_evaluation_map
+SET
+    user_id = @placeholder_id
+WHERE
+    user_id = @user_id;
+
+-- name: DetachUserEventLog :exec
+UPDATE
+    event_log
+SET
+    user_id = @placeholder_id
+WHERE
+    user_id = @user_id;
+
+-- name: DeleteUserEventLog :exec
+-- audit entries are kept, the user is cleared by the foreign key
+DELETE FROM
+    event_log
+WHERE
+    user_id = @user_id
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            actions
+        WHERE
+            actions.id = event_log.action_id
+            AND actions.name = ANY(@audit_actions::TEXT[])
+    );
+
+-- name: CountUserEvaluations :one
+SELECT
+    COUNT(*)
+FROM
+    course_evaluation_map
+WHERE
+    user_id = @user_id;
+
+-- name: CompleteAccountDeletion :exec
+UPDATE
+    account_deletions
+SET
+    user_id = NULL,
+    completed_at = NOW(),
+    evaluations = @evaluations
+WHERE
+    id = @id;
+
+-- name: DeleteUser :exec
+DELETE FROM
+    users
+WHERE
+    user_id = @user_id;
+
+-- name: GetUserIDs :many
+SELECT
+    user_id
+FROM
+    users;
+
+-- name: RenameUser :exec
+SELECT
+    rename_user(@old_id, @new_id);
+
+-- name: GetCohortSizes :many
+SELECT
+    cem.course_number,
+    COALESCE(cem.semester, '')::TEXT AS semester,
+    (COUNT(*) FILTER (WHERE reviews.id IS NOT NULL OR ratings.id IS NOT NULL))::INTEGER AS evaluations,
+    COUNT(ratings.id)::INTEGER AS ratings
+FROM
+    course_evaluation_map AS cem
+    LEFT JOIN reviews ON reviews.evaluation_id = cem.id
+    AND reviews.deleted_at IS NULL
+    LEFT JOIN ratings ON ratings.evaluation_id = cem.id
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+WHERE
+    cardinality(@course_numbers::TEXT[]) = 0
+    OR cem.course_number = ANY(@course_numbers::TEXT[])
+GROUP BY
+    cem.course_number,
+    cem.semester;
+
+-- name: ReleaseEmbargoedReviews :execrows
+UPDATE
+    reviews
+SET
+    embargoed_until = NULL
+WHERE
+    embargoed_until <= CURRENT_DATE;
+
+-- name: GetEmbargoedReviews :many
+SELECT
+    course_evaluation_map.id,
+    course_evaluation_map.course_number,
+    courses.course_name,
+    course_evaluation_map.semester,
+    reviews.review,
+    reviews.embargoed_until
+FROM
+    reviews
+    JOIN course_evaluation_map ON reviews.evaluation_id = course_evaluation_map.id
+    JOIN courses ON course_evaluation_map.course_number = courses.course_number
+WHERE
+    reviews.published = 'verified'
+    AND reviews.embargoed_until IS NOT NULL
+    AND reviews.deleted_at IS NULL
+ORDER BY
+    reviews.embargoed_until,
+    course_evaluation_map.course_number;
+
+-- name: GetSemesterCalendar :many
+SELECT
+    *
+FROM
+    semester_calendar
+ORDER BY
+    ends_on DESC;
+
+-- name: SetSemesterEnd :one
+INSERT INTO
+    semester_calendar (semester, ends_on)
+VALUES
+    (@semester, @ends_on) ON CONFLICT (semester) DO
+UPDATE
+SET
+    ends_on = EXCLUDED.ends_on RETURNING *;
+
+-- name: MoveSemesterEmbargo :exec
+UPDATE
+    reviews
+SET
+    embargoed_until = @ends_on
+FROM
+    course_evaluation_map
+WHERE
+    course_evaluation_map.id = reviews.evaluation_id
+    AND course_evaluation_map.semester = @semester
+    AND reviews.embargoed_until IS NOT NULL;
+
+-- name: AddReviewRevision :exec
+INSERT INTO
+    review_revisions (evaluation_id, review, reason)
+SELECT
+    reviews.evaluation_id,
+    reviews.review,
+    @reason
+FROM
+    reviews
+WHERE
+    reviews.evaluation_id = @evaluation_id;
+
+-- name: RedactReview :one
+UPDATE
+    reviews
+SET
+    review = @review,
+    redacted_at = NOW()
+WHERE
+    evaluation_id = @evaluation_id
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: GetReviewRevisions :many
+SELECT
+    *
+FROM
+    review_revisions
+WHERE
+    evaluation_id = @evaluation_id
+ORDER BY
+    created_at DESC,
+    id DESC;
+
+-- name: GetUserReviewRedactions :many
+-- the latest revision of every review of the user that is redacted right now
+SELECT DISTINCT ON (review_revisions.evaluation_id)
+    review_revisions.*
+FROM
+    review_revisions
+    JOIN reviews ON reviews.evaluation_id = review_revisions.evaluation_id
+    JOIN course_evaluation_map ON course_evaluation_map.id = review_revisions.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id
+    AND reviews.redacted_at IS NOT NULL
+ORDER BY
+    review_revisions.evaluation_id,
+    review_revisions.created_at DESC,
+    review_revisions.id DESC;
+
+-- name: LogEvent :exec
+INSERT INTO
+    event_log (evaluation_id, user_id, action_id, info)
+SELECT
+    @evaluation_id,
+    @user_id,
+    actions.id,
+    @info
+FROM
+    actions
+WHERE
+    actions.name = @action;
+
+-- name: ExportUserReviewRevisions :many
+SELECT
+    review_revisions.*
+FROM
+    review_revisions
+    JOIN course_evaluation_map ON course_evaluation_map.id = review_revisions.evaluation_id
+WHERE
+    course_evaluation_map.user_id = @user_id
+ORDER BY
+    review_revisions.id;
+
+-- name: SetUserBan :one
+INSERT INTO
+    user_bans (user_id, kind, reason, expires_at)
+VALUES
+    (@user_id, @kind, @reason, @expires_at)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    kind = EXCLUDED.kind,
+    reason = EXCLUDED.reason,
+    created_at = NOW(),
+    expires_at = EXCLUDED.expires_at RETURNING *;
+
+-- name: GetUserBan :one
+-- the ban of the user if it hasn't expired yet
+SELECT
+    *
+FROM
+    user_bans
+WHERE
+    user_id = @user_id
+    AND (
+        expires_at IS NULL
+        OR expires_at > NOW()
+    );
+
+-- name: GetEvaluationBan :one
+-- the kind of ban the author of an evaluation is under right now
+SELECT
+    user_bans.kind
+FROM
+    user_bans
+    JOIN course_evaluation_map ON course_evaluation_map.user_id = user_bans.user_id
+WHERE
+    course_evaluation_map.id = @evaluation_id
+    AND (
+        user_bans.expires_at IS NULL
+        OR user_bans.expires_at > NOW()
+    );
+
+-- name: DeleteUserBan :one
+DELETE FROM
+    user_bans
+WHERE
+    user_id = @user_id RETURNING *;
+
+-- name: UnshadowUserRatings :execrows
+UPDATE
+    ratings
+SET
+    shadowed = FALSE
+FROM
+    course_evaluation_map
+WHERE
+    ratings.evaluation_id = course_evaluation_map.id
+    AND course_evaluation_map.user_id = @user_id
+    AND ratings.shadowed;
+
+-- name: UnshadowUserReviews :execrows
+UPDATE
+    reviews
+SET
+    published = 'pending',
+    shadowed = FALSE
+FROM
+    course_evaluation_map
+WHERE
+    reviews.evaluation_id = course_evaluation_map.id
+    AND course_evaluation_map.user_id = @user_id
+    AND reviews.shadowed;
+
+-- name: GetUserBans :many
+SELECT
+    *
+FROM
+    user_bans
+ORDER BY
+    created_at DESC;
+
+-- name: GetDimensionAveragesByCourse :many
+-- averages of the active dimensions, rating_count is what the anonymity threshold applies to
+SELECT
+    cem.course_number,
+    rating_values.dimension_key,
+    AVG(rating_values.value)::numeric AS average,
+    COUNT(*)::INTEGER AS count,
+    COALESCE(MAX(course_stats.rating_count), 0)::INTEGER AS rating_count
+FROM
+    rating_values
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    JOIN course_evaluation_map AS cem ON cem.id = rating_values.evaluation_id
+    LEFT JOIN course_stats ON course_stats.course_number = cem.course_number
+WHERE
+    cem.course_number = ANY(@course_numbers::TEXT[])
+    AND rating_dimensions.active
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+GROUP BY
+    cem.course_number,
+    rating_values.dimension_key;
+
+-- name: GetSemesterDimensionAverages :many
+SELECT
+    COALESCE(cem.semester, '')::TEXT AS semester,
+    rating_values.dimension_key,
+    AVG(rating_values.value)::numeric AS average,
+    VAR_SAMP(rating_values.value)::numeric AS variance,
+    COUNT(*)::INTEGER AS count
+FROM
+    rating_values
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    JOIN course_evaluation_map AS cem ON cem.id = rating_values.evaluation_id
+WHERE
+    cem.course_number = @course_number
+    AND rating_dimensions.active
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+GROUP BY
+    cem.semester,
+    rating_values.dimension_key;
+
+-- name: GetLecturerDimensionAverages :many
+SELECT
+    rating_values.dimension_key,
+    AVG(rating_values.value)::numeric AS average,
+    COUNT(*)::INTEGER AS count
+FROM
+    course_lecturers
+    JOIN course_evaluation_map AS cem ON cem.course_number = course_lecturers.course_number
+    AND cem.semester = course_lecturers.semester
+    JOIN ratings ON ratings.evaluation_id = cem.id
+    JOIN rating_values ON rating_values.evaluation_id = ratings.evaluation_id
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+WHERE
+    course_lecturers.lecturer_id = @lecturer_id
+    AND rating_dimensions.active
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+GROUP BY
+    rating_values.dimension_key;
+
+-- name: GetDepartmentDimensionAverages :many
+SELECT
+    rating_values.dimension_key,
+    AVG(rating_values.value)::numeric AS average,
+    COUNT(*)::INTEGER AS count
+FROM
+    rating_values
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+    JOIN ratings ON ratings.evaluation_id = rating_values.evaluation_id
+    JOIN course_evaluation_map AS cem ON cem.id = rating_values.evaluation_id
+    JOIN department_prefixes ON LEFT(cem.course_number, 3) = department_prefixes.prefix
+WHERE
+    department_prefixes.department_code = @code
+    AND rating_dimensions.active
+    AND ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed
+GROUP BY
+    rating_values.dimension_key;
@@ -14,7 +14,7 @@ CREATE TABLE course_evaluation_map (
     user_id VARCHAR(128) NOT NULL, -- User associated with the evaluation
     course_number VARCHAR(12) NOT NULL, -- Course associated with the evaluation
     semester VARCHAR(4) DEFAULT NULL, -- Semester in which the evaluation was performed
-    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
     FOREIGN KEY (course_number) REFERENCES courses(course_number)
 );
 
@@ -28,7 +28,11 @@ CREATE TABLE reviews (
     review TEXT NOT NULL, -- Content of the review
     requested_changes TEXT DEFAULT NULL, -- Changes requested for the review
     old_review TEXT DEFAULT NULL, -- old version of the review after edit
-    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id),
+    embargoed_until DATE DEFAULT NULL, -- Verified but hidden until the end of its semester
+    deleted_at TIMESTAMPTZ DEFAULT NULL, -- Deleted by its author, purged after the undo window
+    redacted_at TIMESTAMPTZ DEFAULT NULL, -- Changed by a moderator, until the author edits it again
+    shadowed BOOLEAN NOT NULL DEFAULT FALSE, -- Rejected under a shadow ban, back to pending when it is lifted
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE,
     UNIQUE (evaluation_id)
 );
 
@@ -41,10 +45,41 @@ CREATE TABLE ratings (
     difficulty INTEGER DEFAULT NULL CHECK (difficulty BETWEEN 1 AND 5),
     effort INTEGER DEFAULT NULL CHECK (effort BETWEEN 1 AND 5),
     resources INTEGER DEFAULT NULL CHECK (resources BETWEEN 1 AND 5),
-    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id),
+    hours_per_week DOUBLE PRECISION DEFAULT NULL CHECK (hours_per_week BETWEEN 0.5 AND 60), -- Reported workload
+    deleted_at TIMESTAMPTZ DEFAULT NULL, -- Deleted by its author, purged after the undo window
+    shadowed BOOLEAN NOT NULL DEFAULT FALSE, -- Given under a shadow ban, only shown to its author
+    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id) ON DELETE CASCADE,
     UNIQUE (evaluation_id) -- Ensures one rating per evaluation
 );
 
+CREATE TABLE rating_dimensions (
+    key VARCHAR(32) PRIMARY KEY, -- Name used by the API, like "recommended"
+    label_de TEXT NOT NULL, -- Label shown to German speaking users
+    label_en TEXT NOT NULL, -- Label shown to English speaking users
+    scale_min INTEGER NOT NULL DEFAULT 1, -- Lowest value that can be given
+    scale_max INTEGER NOT NULL DEFAULT 5, -- Highest value that can be given
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- Retired dimensions keep their values but can't be rated anymore
+    position INTEGER NOT NULL DEFAULT 0, -- Display order
+    CHECK (scale_min < scale_max)
+);
+
+CREATE TABLE rating_values (
+    evaluation_id INTEGER NOT NULL, -- Rating the value belongs to
+    dimension_key VARCHAR(32) NOT NULL, -- Dimension that was rated
+    value INTEGER NOT NULL, -- Within the scale of the dimension
+    comment TEXT DEFAULT NULL CHECK (char_length(comment) <= 280), -- Short explanation of the value
+    comment_status status DEFAULT NULL, -- Moderation state of the comment, NULL without one
+    comment_requested_changes TEXT DEFAULT NULL, -- Changes requested for the comment
+    comment_date DATE DEFAULT NULL, -- Date the comment was last changed
+    PRIMARY KEY (evaluation_id, dimension_key),
+    FOREIGN KEY (evaluation_id) REFERENCES ratings(evaluation_id) ON DELETE CASCADE,
+    FOREIGN KEY (dimension_key) REFERENCES rating_dimensions(key) ON UPDATE CASCADE
+);
+
+CREATE INDEX rating_values_dimension_key_idx ON rating_values (dimension_key);
+
+CREATE INDEX rating_values_pending_comments_idx ON rating_values (comment_status) WHERE comment_status = 'pending';
+
 CREATE TABLE actions (
     id SERIAL PRIMARY KEY, -- Unique identifier for the action
     name TEXT NOT NULL -- Name of the action
@@ -52,13 +87,12 @@ CREATE TABLE actions (
 
 CREATE TABLE event_log (
     id SERIAL PRIMARY KEY, -- Unique identifier for the log entry
-    evaluation_id INTEGER, -- Reference to the evaluation
-    user_id VARCHAR(128), -- User associated with the log entry
+    evaluation_id INTEGER, -- Evaluation the entry is about, kept after it is deleted for the audit log
+    user_id VARCHAR(128), -- User associated with the log entry, cleared when an audit entry outlives them
     action_id INTEGER, -- Action performed
     info TEXT, -- Additional information
     date DATE DEFAULT NOW(), -- Date of the event
-    FOREIGN KEY (evaluation_id) REFERENCES course_evaluation_map(id),
-    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL,
     FOREIGN KEY (action_id) REFERENCES actions(id)
 );
 
@@ -73,3 +107,330 @@ CREATE TABLE course_number_alias (
 CREATE TABLE current_semester (
     semester VARCHAR(4) PRIMARY KEY -- Current semester
 );
+
+CREATE TABLE course_stats (
+    course_number VARCHAR(12) PRIMARY KEY, -- Course the aggregates belong to
+    rating_count INTEGER NOT NULL DEFAULT 0, -- Ratings of the course
+    recommended_sum DOUBLE PRECISION NOT NULL DEFAULT 0, -- Sum and amount of set values per dimension
+    recommended_count INTEGER NOT NULL DEFAULT 0,
+    engaging_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    engaging_count INTEGER NOT NULL DEFAULT 0,
+    difficulty_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    difficulty_count INTEGER NOT NULL DEFAULT 0,
+    effort_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    effort_count INTEGER NOT NULL DEFAULT 0,
+    resources_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    resources_count INTEGER NOT NULL DEFAULT 0,
+    review_count INTEGER NOT NULL DEFAULT 0, -- Reviews in any moderation state
+    verified_review_count INTEGER NOT NULL DEFAULT 0, -- Published reviews
+    latest_activity DATE DEFAULT NULL, -- Latest verified review or rating
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+-- the values of the active built-in dimensions as columns, the legacy projection of
+-- rating_values for the aggregates and routes with a field per built-in dimension
+CREATE VIEW built_in_rating_values AS
+SELECT
+    rating_values.evaluation_id,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'recommended'))::INTEGER AS recommended,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'engaging'))::INTEGER AS engaging,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'difficulty'))::INTEGER AS difficulty,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'effort'))::INTEGER AS effort,
+    (MAX(rating_values.value) FILTER (WHERE rating_values.dimension_key = 'resources'))::INTEGER AS resources
+FROM
+    rating_values
+    JOIN rating_dimensions ON rating_dimensions.key = rating_values.dimension_key
+WHERE
+    rating_dimensions.active
+GROUP BY
+    rating_values.evaluation_id;
+
+-- the reviews and ratings that count towards the aggregates, a change of what counts
+-- only needs to replace these
+CREATE VIEW counted_reviews AS
+SELECT * FROM reviews WHERE deleted_at IS NULL;
+
+-- the built-in columns of a rating come from rating_values, a retired dimension reads as NULL
+CREATE VIEW counted_ratings AS
+SELECT
+    ratings.id,
+    ratings.evaluation_id,
+    ratings.date,
+    built_in.recommended,
+    built_in.engaging,
+    built_in.difficulty,
+    built_in.effort,
+    built_in.resources,
+    ratings.hours_per_week,
+    ratings.deleted_at,
+    ratings.shadowed
+FROM
+    ratings
+    LEFT JOIN built_in_rating_values AS built_in ON built_in.evaluation_id = ratings.evaluation_id
+WHERE
+    ratings.deleted_at IS NULL
+    AND NOT ratings.shadowed;
+
+-- single source of truth for the aggregates, used by the triggers and the reconcile command
+CREATE VIEW course_stats_computed AS
+SELECT
+    courses.course_number,
+    COUNT(ratings.id)::INTEGER AS rating_count,
+    COALESCE(SUM(ratings.recommended), 0)::DOUBLE PRECISION AS recommended_sum,
+    COUNT(ratings.recommended)::INTEGER AS recommended_count,
+    COALESCE(SUM(ratings.engaging), 0)::DOUBLE PRECISION AS engaging_sum,
+    COUNT(ratings.engaging)::INTEGER AS engaging_count,
+    COALESCE(SUM(ratings.difficulty), 0)::DOUBLE PRECISION AS difficulty_sum,
+    COUNT(ratings.difficulty)::INTEGER AS difficulty_count,
+    COALESCE(SUM(ratings.effort), 0)::DOUBLE PRECISION AS effort_sum,
+    COUNT(ratings.effort)::INTEGER AS effort_count,
+    COALESCE(SUM(ratings.resources), 0)::DOUBLE PRECISION AS resources_sum,
+    COUNT(ratings.resources)::INTEGER AS resources_count,
+    COUNT(reviews.id)::INTEGER AS review_count,
+    (COUNT(reviews.id) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL))::INTEGER AS verified_review_count,
+    GREATEST(MAX(reviews.date) FILTER (WHERE reviews.published = 'verified' AND reviews.embargoed_until IS NULL), MAX(ratings.date)) AS latest_activity
+FROM
+    courses
+    LEFT JOIN course_evaluation_map AS cem ON cem.course_number = courses.course_number
+    LEFT JOIN counted_reviews AS reviews ON reviews.evaluation_id = cem.id
+    LEFT JOIN counted_ratings AS ratings ON ratings.evaluation_id = cem.id
+GROUP BY
+    courses.course_number;
+
+-- concurrent writes to the same course wait for the row lock, so each computes the row with
+-- the changes committed before it instead of the last upsert overwriting the others
+CREATE OR REPLACE FUNCTION refresh_course_stats(course VARCHAR) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO course_stats (course_number)
+    SELECT course_number FROM courses WHERE course_number = course
+    ON CONFLICT (course_number) DO NOTHING;
+    PERFORM 1 FROM course_stats WHERE course_number = course FOR UPDATE;
+
+    INSERT INTO course_stats
+    SELECT * FROM course_stats_computed WHERE course_number = course
+    ON CONFLICT (course_number) DO UPDATE SET
+        rating_count = EXCLUDED.rating_count,
+        recommended_sum = EXCLUDED.recommended_sum,
+        recommended_count = EXCLUDED.recommended_count,
+        engaging_sum = EXCLUDED.engaging_sum,
+        engaging_count = EXCLUDED.engaging_count,
+        difficulty_sum = EXCLUDED.difficulty_sum,
+        difficulty_count = EXCLUDED.difficulty_count,
+        effort_sum = EXCLUDED.effort_sum,
+        effort_count = EXCLUDED.effort_count,
+        resources_sum = EXCLUDED.resources_sum,
+        resources_count = EXCLUDED.resources_count,
+        review_count = EXCLUDED.review_count,
+        verified_review_count = EXCLUDED.verified_review_count,
+        latest_activity = EXCLUDED.latest_activity;
+END;
+$$ LANGUAGE plpgsql;
+
+-- ratings, their values and reviews reference the course through their evaluation
+CREATE OR REPLACE FUNCTION course_stats_evaluation_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM refresh_course_stats(course_number) FROM course_evaluation_map WHERE id = OLD.evaluation_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM refresh_course_stats(course_number) FROM course_evaluation_map WHERE id = NEW.evaluation_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION course_stats_course_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM refresh_course_stats(OLD.course_number);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM refresh_course_stats(NEW.course_number);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- retiring or bringing back a dimension changes the aggregates of every course
+CREATE OR REPLACE FUNCTION course_stats_dimension_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_course_stats(course_number) FROM courses;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ratings_course_stats AFTER INSERT OR UPDATE OR DELETE ON ratings
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+CREATE TRIGGER reviews_course_stats AFTER INSERT OR UPDATE OR DELETE ON reviews
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+CREATE TRIGGER course_evaluation_map_course_stats AFTER UPDATE OF course_number OR DELETE ON course_evaluation_map
+    FOR EACH ROW EXECUTE FUNCTION course_stats_course_trigger();
+
+CREATE TRIGGER courses_course_stats AFTER INSERT ON courses
+    FOR EACH ROW EXECUTE FUNCTION course_stats_course_trigger();
+
+CREATE TRIGGER rating_values_course_stats AFTER INSERT OR UPDATE OR DELETE ON rating_values
+    FOR EACH ROW EXECUTE FUNCTION course_stats_evaluation_trigger();
+
+CREATE TRIGGER rating_dimensions_course_stats AFTER UPDATE OF active ON rating_dimensions
+    FOR EACH ROW WHEN (OLD.active IS DISTINCT FROM NEW.active) EXECUTE FUNCTION course_stats_dimension_trigger();
+
+CREATE TABLE departments (
+    code VARCHAR(16) PRIMARY KEY, -- Short name like D-INFK, the prefix itself for unknown units
+    name TEXT DEFAULT NULL -- Full name of the department
+);
+
+CREATE TABLE department_prefixes (
+    prefix VARCHAR(3) PRIMARY KEY, -- First three digits of a course number
+    department_code VARCHAR(16) NOT NULL, -- Department the unit belongs to
+    FOREIGN KEY (department_code) REFERENCES departments(code) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE lecturers (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the lecturer
+    vvz_id VARCHAR(16) NOT NULL UNIQUE, -- dozide of the lecturer in the VVZ
+    name TEXT NOT NULL -- Name as listed in the VVZ
+);
+
+CREATE TABLE course_lecturers (
+    course_number VARCHAR(12) NOT NULL, -- Course that was taught
+    lecturer_id INTEGER NOT NULL, -- Lecturer teaching it
+    semester VARCHAR(4) NOT NULL, -- Semester in the format of course_evaluation_map, e.g. 23HS
+    PRIMARY KEY (course_number, semester, lecturer_id),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (lecturer_id) REFERENCES lecturers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX course_lecturers_lecturer_idx ON course_lecturers (lecturer_id);
+
+CREATE TABLE course_offerings (
+    course_number VARCHAR(12) NOT NULL, -- Course that was offered
+    semester VARCHAR(4) NOT NULL, -- Semester in the format of course_evaluation_map, e.g. 23HS
+    ects NUMERIC(4, 1) DEFAULT NULL, -- Credits as listed in the VVZ, if the scraper found them
+    PRIMARY KEY (course_number, semester),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+CREATE TABLE course_similarities (
+    course_number VARCHAR(12) NOT NULL, -- Course the similarity is computed for
+    similar_course_number VARCHAR(12) NOT NULL, -- Other course
+    similarity DOUBLE PRECISION NOT NULL, -- Adjusted cosine similarity, -1 to 1
+    common_raters INTEGER NOT NULL, -- Users that rated both courses
+    computed_at TIMESTAMPTZ NOT NULL, -- Run of the job that computed the row
+    PRIMARY KEY (course_number, similar_course_number),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (similar_course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+CREATE TABLE course_text_similarities (
+    course_number VARCHAR(12) NOT NULL, -- Course the similarity is computed for
+    similar_course_number VARCHAR(12) NOT NULL, -- One of its most similar courses
+    similarity DOUBLE PRECISION NOT NULL, -- Cosine similarity of the TF-IDF vectors, 0 to 1
+    computed_at TIMESTAMPTZ NOT NULL, -- Run of the job that computed the row
+    PRIMARY KEY (course_number, similar_course_number),
+    FOREIGN KEY (course_number) REFERENCES courses(course_number) ON DELETE CASCADE,
+    FOREIGN KEY (similar_course_number) REFERENCES courses(course_number) ON DELETE CASCADE
+);
+
+CREATE TABLE review_analyses (
+    evaluation_id INTEGER PRIMARY KEY, -- Review the analysis belongs to
+    review_hash TEXT NOT NULL, -- MD5 of the analyzed text, a changed review is analyzed again
+    language VARCHAR(2) NOT NULL, -- Detected language, de or en
+    sentiment DOUBLE PRECISION NOT NULL, -- Lexicon based sentiment, -1 to 1
+    phrases JSONB NOT NULL, -- Top keyphrases with the sentiment of their sentence
+    analyzed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE
+);
+
+CREATE TABLE data_exports (
+    id VARCHAR(32) PRIMARY KEY, -- Random hex id, also part of the signed download link
+    user_id VARCHAR(128) NOT NULL, -- User the data belongs to
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    json_archive BYTEA DEFAULT NULL, -- Everything as one JSON document
+    zip_archive BYTEA DEFAULT NULL, -- The same data as one CSV per table
+    error TEXT DEFAULT NULL, -- Why building the archive failed
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- Last time building the archive was started
+    finished_at TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+
+CREATE TABLE account_deletions (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the deletion
+    user_id VARCHAR(128) DEFAULT NULL, -- User to delete, cleared once the deletion ran
+    user_hash VARCHAR(64) NOT NULL, -- SHA-256 of the user id, kept as tombstone
+    mode VARCHAR(6) NOT NULL CHECK (mode IN ('delete', 'detach')), -- Delete contributions or keep them without the user
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    scheduled_for TIMESTAMPTZ NOT NULL, -- End of the grace period
+    cancelled_at TIMESTAMPTZ DEFAULT NULL,
+    completed_at TIMESTAMPTZ DEFAULT NULL,
+    evaluations INTEGER DEFAULT NULL, -- Evaluations deleted or detached
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+-- at most one deletion waiting per user
+CREATE UNIQUE INDEX account_deletions_pending_idx ON account_deletions (user_id)
+    WHERE cancelled_at IS NULL AND completed_at IS NULL;
+
+CREATE OR REPLACE FUNCTION rename_user(old_id VARCHAR, new_id VARCHAR) RETURNS VOID AS $$
+BEGIN
+    IF old_id = new_id THEN
+        RETURN;
+    END IF;
+    -- the new id may exist already if the user logged in before the rename, both are merged
+    INSERT INTO users (user_id, admin, moderator)
+    SELECT new_id, admin, moderator FROM users WHERE user_id = old_id
+    ON CONFLICT (user_id) DO UPDATE SET
+        admin = COALESCE(users.admin, FALSE) OR COALESCE(EXCLUDED.admin, FALSE),
+        moderator = COALESCE(users.moderator, FALSE) OR COALESCE(EXCLUDED.moderator, FALSE);
+
+    -- every table referencing users(user_id) must be listed here
+    UPDATE course_evaluation_map SET user_id = new_id WHERE user_id = old_id;
+    UPDATE event_log SET user_id = new_id WHERE user_id = old_id;
+    UPDATE data_exports SET user_id = new_id WHERE user_id = old_id;
+    UPDATE account_deletions SET cancelled_at = NOW()
+    WHERE user_id = old_id AND cancelled_at IS NULL AND completed_at IS NULL
+        AND EXISTS (SELECT 1 FROM account_deletions WHERE user_id = new_id AND cancelled_at IS NULL AND completed_at IS NULL);
+    UPDATE account_deletions SET user_id = new_id WHERE user_id = old_id;
+    -- a ban of the new id wins, the one of the old id goes with it
+    UPDATE user_bans SET user_id = new_id
+    WHERE user_id = old_id AND NOT EXISTS (SELECT 1 FROM user_bans WHERE user_id = new_id);
+
+    DELETE FROM users WHERE user_id = old_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE semester_calendar (
+    semester VARCHAR(4) PRIMARY KEY, -- Semester like 24HS
+    ends_on DATE NOT NULL -- Reviews of the semester are embargoed until this day
+);
+
+CREATE INDEX reviews_embargoed_until_idx ON reviews (embargoed_until) WHERE embargoed_until IS NOT NULL;
+
+CREATE INDEX reviews_deleted_at_idx ON reviews (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX ratings_deleted_at_idx ON ratings (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE review_revisions (
+    id SERIAL PRIMARY KEY, -- Unique identifier for the revision
+    evaluation_id INTEGER NOT NULL, -- Review the text belonged to
+    review TEXT NOT NULL, -- Text before the moderator changed it
+    reason TEXT DEFAULT NULL, -- Why the moderator changed it, shown to the author
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (evaluation_id) REFERENCES reviews(evaluation_id) ON DELETE CASCADE
+);
+
+CREATE INDEX review_revisions_evaluation_id_idx ON review_revisions (evaluation_id);
+
+CREATE TABLE user_bans (
+    user_id VARCHAR(128) PRIMARY KEY, -- Banned user, at most one ban at a time
+    kind VARCHAR(6) NOT NULL CHECK (kind IN ('full', 'write', 'shadow')), -- No access, read only, or submissions rejected silently
+    reason TEXT NOT NULL, -- Why the user was banned
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ DEFAULT NULL, -- NULL for a permanent ban
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);